@@ -0,0 +1,91 @@
+package ci
+
+import (
+	"os"
+	"strings"
+)
+
+const (
+	ProviderGitHubActions = "github-actions"
+	ProviderGitLabCI      = "gitlab-ci"
+	ProviderJenkins       = "jenkins"
+	ProviderBuildkite     = "buildkite"
+)
+
+// Context is the continuous integration pipeline the deployment is executed from
+type Context struct {
+	Provider    string `json:"provider,omitempty"`
+	Actor       string `json:"actor,omitempty"`
+	PipelineURL string `json:"pipelineURL,omitempty"`
+	Repository  string `json:"repository,omitempty"`
+	Revision    string `json:"revision,omitempty"`
+
+	// RevisionTime is the commit time of the revision in RFC 3339 format, only GitLab CI exposes it
+	RevisionTime string `json:"revisionTime,omitempty"`
+}
+
+// Detect returns the context of the pipeline from its environment variables,
+// it is empty when the deployment isn't executed from any supported pipeline.
+func Detect() Context {
+	return DetectFrom(os.Getenv)
+}
+
+// DetectFrom is Detect with a custom lookup of the environment variables
+func DetectFrom(getenv func(string) string) Context {
+	switch {
+	case getenv("GITHUB_ACTIONS") == "true":
+		url := ""
+		if server, repo, runID := getenv("GITHUB_SERVER_URL"), getenv("GITHUB_REPOSITORY"), getenv("GITHUB_RUN_ID"); server != "" && repo != "" && runID != "" {
+			url = server + "/" + repo + "/actions/runs/" + runID
+			if attempt := getenv("GITHUB_RUN_ATTEMPT"); attempt != "" && attempt != "1" {
+				url += "/attempts/" + attempt
+			}
+		}
+
+		return Context{
+			Provider:    ProviderGitHubActions,
+			Actor:       getenv("GITHUB_ACTOR"),
+			PipelineURL: url,
+			Repository:  getenv("GITHUB_REPOSITORY"),
+			Revision:    getenv("GITHUB_SHA"),
+		}
+	case getenv("GITLAB_CI") == "true":
+		return Context{
+			Provider:     ProviderGitLabCI,
+			Actor:        getenv("GITLAB_USER_LOGIN"),
+			PipelineURL:  firstOf(getenv("CI_PIPELINE_URL"), getenv("CI_JOB_URL")),
+			Repository:   getenv("CI_PROJECT_PATH"),
+			Revision:     getenv("CI_COMMIT_SHA"),
+			RevisionTime: getenv("CI_COMMIT_TIMESTAMP"),
+		}
+	case getenv("BUILDKITE") == "true":
+		return Context{
+			Provider:    ProviderBuildkite,
+			Actor:       firstOf(getenv("BUILDKITE_BUILD_CREATOR_EMAIL"), getenv("BUILDKITE_BUILD_CREATOR")),
+			PipelineURL: getenv("BUILDKITE_BUILD_URL"),
+			Repository:  getenv("BUILDKITE_REPO"),
+			Revision:    getenv("BUILDKITE_COMMIT"),
+		}
+	case getenv("JENKINS_URL") != "":
+		// the build user is only available along with the build user vars plugin
+		return Context{
+			Provider:    ProviderJenkins,
+			Actor:       firstOf(getenv("BUILD_USER_ID"), getenv("BUILD_USER")),
+			PipelineURL: getenv("BUILD_URL"),
+			Repository:  getenv("GIT_URL"),
+			Revision:    getenv("GIT_COMMIT"),
+		}
+	}
+
+	return Context{}
+}
+
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,88 @@
+package ci_test
+
+import (
+	"testing"
+
+	"github.com/ardikabs/dpl/internal/ci"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectFrom(t *testing.T) {
+	tests := map[string]struct {
+		env  map[string]string
+		want ci.Context
+	}{
+		"github actions": {
+			env: map[string]string{
+				"GITHUB_ACTIONS":     "true",
+				"GITHUB_ACTOR":       "ardikabs",
+				"GITHUB_SERVER_URL":  "https://github.com",
+				"GITHUB_REPOSITORY":  "ardikabs/myapp",
+				"GITHUB_RUN_ID":      "1234",
+				"GITHUB_RUN_ATTEMPT": "2",
+				"GITHUB_SHA":         "b6d7153",
+			},
+			want: ci.Context{
+				Provider:    ci.ProviderGitHubActions,
+				Actor:       "ardikabs",
+				PipelineURL: "https://github.com/ardikabs/myapp/actions/runs/1234/attempts/2",
+				Repository:  "ardikabs/myapp",
+				Revision:    "b6d7153",
+			},
+		},
+		"gitlab ci": {
+			env: map[string]string{
+				"GITLAB_CI":           "true",
+				"GITLAB_USER_LOGIN":   "ardikabs",
+				"CI_PIPELINE_URL":     "https://gitlab.com/ardikabs/myapp/-/pipelines/1234",
+				"CI_PROJECT_PATH":     "ardikabs/myapp",
+				"CI_COMMIT_SHA":       "b6d7153",
+				"CI_COMMIT_TIMESTAMP": "2024-10-01T08:00:00+00:00",
+			},
+			want: ci.Context{
+				Provider:     ci.ProviderGitLabCI,
+				Actor:        "ardikabs",
+				PipelineURL:  "https://gitlab.com/ardikabs/myapp/-/pipelines/1234",
+				Repository:   "ardikabs/myapp",
+				Revision:     "b6d7153",
+				RevisionTime: "2024-10-01T08:00:00+00:00",
+			},
+		},
+		"jenkins": {
+			env: map[string]string{
+				"JENKINS_URL": "https://jenkins.ardikabs.com/",
+				"BUILD_URL":   "https://jenkins.ardikabs.com/job/myapp/42/",
+				"GIT_COMMIT":  "b6d7153",
+			},
+			want: ci.Context{
+				Provider:    ci.ProviderJenkins,
+				PipelineURL: "https://jenkins.ardikabs.com/job/myapp/42/",
+				Revision:    "b6d7153",
+			},
+		},
+		"buildkite": {
+			env: map[string]string{
+				"BUILDKITE":                     "true",
+				"BUILDKITE_BUILD_CREATOR":       "Ardika Bagus",
+				"BUILDKITE_BUILD_CREATOR_EMAIL": "me@ardikabs.com",
+				"BUILDKITE_BUILD_URL":           "https://buildkite.com/ardikabs/myapp/builds/42",
+			},
+			want: ci.Context{
+				Provider:    ci.ProviderBuildkite,
+				Actor:       "me@ardikabs.com",
+				PipelineURL: "https://buildkite.com/ardikabs/myapp/builds/42",
+			},
+		},
+		"none": {
+			env:  map[string]string{"USER": "ardikabs"},
+			want: ci.Context{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := ci.DetectFrom(func(key string) string { return tt.env[key] })
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package argoconfig
+
+import (
+	"errors"
+
+	"github.com/ardikabs/dpl/internal/manager"
+	"github.com/ardikabs/dpl/internal/manager/argocd"
+	"github.com/ardikabs/dpl/internal/types"
+	flag "github.com/spf13/pflag"
+)
+
+// Parameters are the connection and the credentials of Argo CD, shared by the commands talking to Argo CD.
+// The credentials are only set through the environment variables.
+type Parameters struct {
+	InstancesFile string `env:"ARGOCD_INSTANCES_FILE"`
+	Host          string `env:"ARGOCD_HOST"`
+	AuthToken     string `env:"ARGOCD_AUTH_TOKEN"`
+	AuthTokenFile string `env:"ARGOCD_AUTH_TOKEN_FILE"`
+	Username      string `env:"ARGOCD_USERNAME"`
+	Password      string `env:"ARGOCD_PASSWORD"`
+	CACert        string `env:"ARGOCD_CA_CERT"`
+	ClientCert    string `env:"ARGOCD_CLIENT_CERT"`
+	ClientKey     string `env:"ARGOCD_CLIENT_KEY"`
+	GRPCWeb       bool   `env:"ARGOCD_GRPC_WEB,default=true"`
+	PlainText     bool   `env:"ARGOCD_PLAINTEXT"`
+	Insecure      bool   `env:"ARGOCD_INSECURE"`
+}
+
+func (p *Parameters) AttachFlags(flagset *flag.FlagSet) {
+	flagset.StringVar(&p.InstancesFile, "argocd-instances-file", p.InstancesFile, "Configuration file of the named ArgoCD instances, instead of the single ArgoCD instance")
+	flagset.StringVar(&p.AuthTokenFile, "argocd-auth-token-file", p.AuthTokenFile, "File containing the ArgoCD token, it is read again on reconnection so the rotated token is picked up")
+	flagset.StringVar(&p.CACert, "argocd-ca-cert", p.CACert, "CA bundle file to verify the ArgoCD server certificate with")
+	flagset.StringVar(&p.ClientCert, "argocd-client-cert", p.ClientCert, "Client certificate file to authenticate to ArgoCD with, along with --argocd-client-key")
+	flagset.StringVar(&p.ClientKey, "argocd-client-key", p.ClientKey, "Client certificate key file to authenticate to ArgoCD with, along with --argocd-client-cert")
+	flagset.BoolVar(&p.GRPCWeb, "argocd-grpc-web", p.GRPCWeb, "Use the gRPC-Web protocol to connect to ArgoCD, such as behind a proxy without HTTP/2 support")
+	flagset.BoolVar(&p.PlainText, "argocd-plaintext", p.PlainText, "Connect to ArgoCD without TLS")
+	flagset.BoolVar(&p.Insecure, "argocd-insecure", p.Insecure, "Skip the ArgoCD server certificate verification")
+}
+
+// Validate returns an error when the host or the credentials are missing, the instances file is validated once it is loaded
+func (p *Parameters) Validate() error {
+	if p.InstancesFile != "" {
+		return nil
+	}
+
+	if p.Host == "" {
+		return errors.New("ArgoCD Host is required. Please set ARGOCD_HOST environment variable")
+	}
+
+	switch {
+	case p.AuthToken != "", p.AuthTokenFile != "":
+	case p.Username != "":
+		if p.Password == "" {
+			return errors.New("ArgoCD Password is required along with the username. Please set ARGOCD_PASSWORD environment variable")
+		}
+	default:
+		return errors.New("ArgoCD credentials are required. Please set either ARGOCD_AUTH_TOKEN, ARGOCD_AUTH_TOKEN_FILE, or ARGOCD_USERNAME and ARGOCD_PASSWORD environment variables")
+	}
+
+	if (p.ClientCert == "") != (p.ClientKey == "") {
+		return errors.New("ArgoCD client certificate and key must be set together")
+	}
+
+	return nil
+}
+
+// NewManager returns the manager of the instances from the instances file, otherwise of the single instance
+func (p *Parameters) NewManager() (manager.Interface, error) {
+	if p.InstancesFile != "" {
+		instances, err := argocd.LoadInstances(p.InstancesFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return instances, nil
+	}
+
+	client, err := argocd.NewClient(p.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func (p *Parameters) GetConfig() types.ArgoConfig {
+	return types.ArgoConfig{
+		Host:           p.Host,
+		Insecure:       p.Insecure,
+		PlainText:      p.PlainText,
+		GRPCWeb:        p.GRPCWeb,
+		CACertFile:     p.CACert,
+		ClientCertFile: p.ClientCert,
+		ClientKeyFile:  p.ClientKey,
+		Secret: types.ArgoSecret{
+			Token:     p.AuthToken,
+			TokenFile: p.AuthTokenFile,
+			Username:  p.Username,
+			Password:  p.Password,
+		},
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"path/filepath"
 
 	"github.com/ardikabs/dpl/internal/cli/commands/exec"
+	"github.com/ardikabs/dpl/internal/cli/commands/history"
+	"github.com/ardikabs/dpl/internal/cli/commands/preview"
 	"github.com/ardikabs/dpl/internal/cli/commands/version"
 	"github.com/ardikabs/dpl/internal/cli/global"
+	"github.com/ardikabs/dpl/internal/log"
 	"github.com/spf13/cobra"
 )
 
@@ -25,7 +28,15 @@ func New() *cobra.Command {
 
 	global.AttachFlags(cmd.PersistentFlags())
 
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return log.Configure(global.GetLogFormat(), global.GetLogFile())
+	}
+
 	cmd.AddCommand(version.NewCommand())
 	cmd.AddCommand(exec.NewCommand())
+	cmd.AddCommand(exec.NewPromoteCommand())
+	cmd.AddCommand(exec.NewServeCommand())
+	cmd.AddCommand(preview.NewCommand())
+	cmd.AddCommand(history.NewCommand())
 	return cmd
 }
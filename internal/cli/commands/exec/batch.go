@@ -0,0 +1,101 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ardikabs/dpl/internal/tools/ioutils"
+	goyaml "gopkg.in/yaml.v3"
+)
+
+// batchFile is the manifest file format for deploying many releases at once,
+// the top-level environment and image are used as defaults for every deployment entry.
+//
+//	environment: staging
+//	image: ghcr.io/ardikabs/app/monorepo:b6d7153
+//	deployments:
+//	  - release: myapp
+//	  - release: myworker
+//	    image: ghcr.io/ardikabs/app/worker:b6d7153
+//	  - release: mycron
+//	    environment: production
+//	    cluster: k8s-prod-1
+type batchFile struct {
+	Environment string       `yaml:"environment"`
+	Image       string       `yaml:"image"`
+	Deployments []batchEntry `yaml:"deployments"`
+}
+
+type batchEntry struct {
+	Release     string `yaml:"release"`
+	Environment string `yaml:"environment"`
+	Cluster     string `yaml:"cluster"`
+	Image       string `yaml:"image"`
+}
+
+func loadBatchFile(filename string) ([]target, error) {
+	content, err := ioutils.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var f batchFile
+	if err := goyaml.Unmarshal(content, &f); err != nil {
+		return nil, err
+	}
+
+	return f.targets()
+}
+
+// targets resolves every deployment entry into a target, filling in the defaults
+func (f batchFile) targets() ([]target, error) {
+	if len(f.Deployments) == 0 {
+		return nil, errors.New("batch file has no deployments")
+	}
+
+	type entryKey struct{ release, environment, cluster string }
+	seen := make(map[entryKey]int)
+
+	targets := make([]target, 0, len(f.Deployments))
+	for idx, entry := range f.Deployments {
+		if entry.Release == "" {
+			return nil, fmt.Errorf("deployments[%d]: release is required", idx)
+		}
+
+		if entry.Environment == "" {
+			entry.Environment = f.Environment
+		}
+
+		if entry.Environment == "" {
+			return nil, fmt.Errorf("deployments[%d]: environment is required", idx)
+		}
+
+		if entry.Image == "" {
+			entry.Image = f.Image
+		}
+
+		if entry.Image == "" {
+			return nil, fmt.Errorf("deployments[%d]: image is required", idx)
+		}
+
+		image, err := parseImageDefinition(entry.Image)
+		if err != nil {
+			return nil, fmt.Errorf("deployments[%d]: %w", idx, err)
+		}
+
+		key := entryKey{entry.Release, entry.Environment, entry.Cluster}
+		if prev, ok := seen[key]; ok {
+			return nil, fmt.Errorf("deployments[%d]: %w, as deployments[%d]", idx, ErrDuplicateRelease, prev)
+		}
+		seen[key] = idx
+
+		targets = append(targets, target{
+			ReleaseName: entry.Release,
+			Environment: entry.Environment,
+			Cluster:     entry.Cluster,
+			Image:       image,
+		})
+	}
+
+	return targets, nil
+}
@@ -0,0 +1,149 @@
+package exec
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/ardikabs/dpl/internal/manager"
+	"github.com/ardikabs/dpl/internal/types"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchFile_Targets(t *testing.T) {
+	t.Run("defaults are inherited", func(t *testing.T) {
+		f := batchFile{
+			Environment: "staging",
+			Image:       "ghcr.io/ardikabs/app/monorepo:b6d7153",
+			Deployments: []batchEntry{
+				{Release: "myapp"},
+				{Release: "myworker", Image: "ghcr.io/ardikabs/app/worker:b6d7153"},
+				{Release: "mycron", Environment: "production", Cluster: "k8s-prod-1"},
+			},
+		}
+
+		targets, err := f.targets()
+		require.NoError(t, err)
+		require.Equal(t, []target{
+			{ReleaseName: "myapp", Environment: "staging", Image: types.ImageDefinition{Name: "ghcr.io/ardikabs/app/monorepo", Tag: "b6d7153"}},
+			{ReleaseName: "myworker", Environment: "staging", Image: types.ImageDefinition{Name: "ghcr.io/ardikabs/app/worker", Tag: "b6d7153"}},
+			{ReleaseName: "mycron", Environment: "production", Cluster: "k8s-prod-1", Image: types.ImageDefinition{Name: "ghcr.io/ardikabs/app/monorepo", Tag: "b6d7153"}},
+		}, targets)
+	})
+
+	tests := map[string]struct {
+		file    batchFile
+		wantErr string
+	}{
+		"no deployments": {
+			file:    batchFile{Environment: "staging", Image: "myapp:v1"},
+			wantErr: "batch file has no deployments",
+		},
+		"release is missing": {
+			file:    batchFile{Environment: "staging", Image: "myapp:v1", Deployments: []batchEntry{{Release: "myapp"}, {}}},
+			wantErr: "deployments[1]: release is required",
+		},
+		"environment is missing": {
+			file:    batchFile{Image: "myapp:v1", Deployments: []batchEntry{{Release: "myapp"}}},
+			wantErr: "deployments[0]: environment is required",
+		},
+		"image is missing": {
+			file:    batchFile{Environment: "staging", Deployments: []batchEntry{{Release: "myapp"}}},
+			wantErr: "deployments[0]: image is required",
+		},
+		"image is invalid": {
+			file:    batchFile{Environment: "staging", Deployments: []batchEntry{{Release: "myapp", Image: "myapp:v1:v2"}}},
+			wantErr: "deployments[0]: invalid image format",
+		},
+		"duplicated entry": {
+			file: batchFile{Environment: "staging", Image: "myapp:v1", Deployments: []batchEntry{
+				{Release: "myapp"},
+				{Release: "myworker"},
+				{Release: "myapp", Environment: "staging", Image: "myapp:v2"},
+			}},
+			wantErr: "deployments[2]: release is targeted more than once, as deployments[0]",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := tt.file.targets()
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+// fakeManager lists the releases of the environment, narrowed down to the cluster when it is requested,
+// the releases are synced successfully unless the sync func tells otherwise.
+type fakeManager struct {
+	manager.Interface
+
+	releases types.ListReleases
+	sync     func(ctx context.Context, rels types.ListReleases) error
+
+	mu    sync.Mutex
+	syncs int
+}
+
+func (m *fakeManager) ListReleases(_ context.Context, req *manager.ListReleaseRequest, _ ...manager.Option) (types.ListReleases, error) {
+	var rels types.ListReleases
+	for _, rel := range m.releases {
+		if rel.Environment == req.Environment && (req.Cluster == "" || rel.Cluster == req.Cluster) {
+			r := *rel
+			rels = append(rels, &r)
+		}
+	}
+
+	return rels, nil
+}
+
+func (m *fakeManager) SyncReleases(ctx context.Context, rels types.ListReleases, _ ...manager.Option) error {
+	m.mu.Lock()
+	m.syncs++
+	m.mu.Unlock()
+
+	if m.sync != nil {
+		return m.sync(ctx, rels)
+	}
+
+	for _, rel := range rels {
+		rel.Status = types.ReleaseStatus{Sync: types.SyncStatusSynced, Health: types.HealthStatusHealthy, Result: types.ReleaseResultSucceeded}
+	}
+
+	return nil
+}
+
+func (m *fakeManager) AnnotateRelease(context.Context, *types.Release, map[string]string, ...manager.Option) error {
+	return nil
+}
+
+func TestExecInstance_ListReleases(t *testing.T) {
+	ins := &execInstance{
+		Manager: &fakeManager{releases: types.ListReleases{
+			{ID: "myapp-prod-1", Name: "myapp", Environment: "production", Cluster: "prod-1"},
+			{ID: "myapp-prod-2", Name: "myapp", Environment: "production", Cluster: "prod-2"},
+		}},
+		Params: &parameters{},
+	}
+
+	image := types.ImageDefinition{Name: "myapp", Tag: "v1"}
+
+	t.Run("distinct targets", func(t *testing.T) {
+		rels, err := ins.listReleases(context.Background(), logr.Discard(), []target{
+			{ReleaseName: "myapp", Environment: "production", Cluster: "prod-1", Image: image},
+			{ReleaseName: "myapp", Environment: "production", Cluster: "prod-2", Image: image},
+		})
+		require.NoError(t, err)
+		require.Len(t, rels, 2)
+	})
+
+	t.Run("overlapping targets", func(t *testing.T) {
+		_, err := ins.listReleases(context.Background(), logr.Discard(), []target{
+			{ReleaseName: "myapp", Environment: "production", Image: image},
+			{ReleaseName: "myapp", Environment: "production", Cluster: "prod-1", Image: image},
+		})
+		require.ErrorIs(t, err, ErrDuplicateRelease)
+		require.ErrorContains(t, err, "application myapp-prod-1 is targeted by both myapp on production and myapp on production/prod-1")
+	})
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/ardikabs/dpl/internal/cli/global"
 	"github.com/ardikabs/dpl/internal/log"
+	"github.com/ardikabs/dpl/internal/result"
+	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 )
 
@@ -73,7 +75,17 @@ and trigger a sync to the ArgoCD Application.
 `,
 		Example: `
 # execute a deployment runner for deploying release named myapp
-$ dpl exec --environment staging --image ghcr.io/ardikabs/app/myapp:latest myapp`,
+$ dpl exec --environment staging --image ghcr.io/ardikabs/app/myapp:latest myapp
+
+# execute a deployment runner for deploying many releases at once, with a single commit and push
+$ cat <<EOF > deploy.yaml
+environment: staging
+image: ghcr.io/ardikabs/app/monorepo:b6d7153
+deployments:
+  - release: myapp
+  - release: myworker
+EOF
+$ dpl exec --from-file deploy.yaml`,
 	}
 
 	cmd.SilenceErrors = true
@@ -92,19 +104,48 @@ func runner(params *parameters) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
 		log.SetLevel(global.GetLogLevel())
 
+		if err := configureOutput(params); err != nil {
+			return reportFailure(params, err)
+		}
+
 		if err := params.ParseArgs(args); err != nil {
-			return err
+			return reportFailure(params, result.WithCategory(result.CategoryValidation, err))
 		}
 
 		if err := params.Validate(); err != nil {
-			return err
+			return reportFailure(params, result.WithCategory(result.CategoryValidation, err))
 		}
 
 		instance, err := newExecInstance(log.Logger, params)
 		if err != nil {
-			return err
+			return reportFailure(params, err)
+		}
+		defer instance.Manager.Close()
+
+		if params.FromFile != "" {
+			return instance.ExecBatch(cmd.Context())
 		}
 
 		return instance.Exec(cmd.Context())
 	}
 }
+
+// configureOutput moves the logs to stderr when the result document owns the stdout
+func configureOutput(params *parameters) error {
+	if params.Output != OutputJSON {
+		return nil
+	}
+
+	log.Output = os.Stderr
+	return log.Configure(global.GetLogFormat(), global.GetLogFile())
+}
+
+// reportFailure writes the result document of the deployment failing before it is executed,
+// such as the invalid parameters or the hooks file failing to load, then returns the error.
+func reportFailure(params *parameters, err error) error {
+	res := result.New(uuid.New().String())
+	res.Finish(err)
+
+	writeResult(log.Logger, params, res)
+	return err
+}
@@ -0,0 +1,58 @@
+package exec
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ardikabs/dpl/internal/result"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_ReportFailure(t *testing.T) {
+	t.Setenv("GITHUB_OUTPUT", "")
+	t.Setenv("GIT_SECRET", "autobot:s3cr3t")
+
+	tests := []struct {
+		name     string
+		args     []string
+		category result.Category
+	}{
+		{
+			name:     "invalid arguments",
+			args:     []string{"--environment", "staging", "--image", "ghcr.io/ardikabs/app/myapp:b6d7153"},
+			category: result.CategoryValidation,
+		},
+		{
+			name:     "invalid parameters",
+			args:     []string{"--image", "ghcr.io/ardikabs/app/myapp:b6d7153", "myapp"},
+			category: result.CategoryValidation,
+		},
+		{
+			name:     "setup failure",
+			args:     []string{"--environment", "staging", "--image", "ghcr.io/ardikabs/app/myapp:b6d7153", "--argocd-instances-file", "missing-instances.yaml", "myapp"},
+			category: result.CategoryUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputFile := filepath.Join(t.TempDir(), "result.json")
+
+			cmd := NewCommand()
+			cmd.SetArgs(append(tt.args, "--output-file", outputFile))
+			err := cmd.Execute()
+			require.Error(t, err)
+
+			content, rerr := os.ReadFile(outputFile)
+			require.NoError(t, rerr)
+
+			var res result.Result
+			require.NoError(t, json.Unmarshal(content, &res))
+			require.Equal(t, result.StatusFailed, res.Status)
+			require.NotEmpty(t, res.RequestID)
+			require.Equal(t, &result.ErrorResult{Category: tt.category, Message: err.Error()}, res.Error)
+		})
+	}
+}
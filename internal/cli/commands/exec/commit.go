@@ -0,0 +1,222 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/ardikabs/dpl/internal/ci"
+	"github.com/ardikabs/dpl/internal/errs"
+	"github.com/ardikabs/dpl/internal/git"
+	"github.com/ardikabs/dpl/internal/tools/ioutils"
+	"github.com/ardikabs/dpl/internal/types"
+	"github.com/go-logr/logr"
+)
+
+// DefaultCommitTemplate is the deployment commit message used when no template is given
+const DefaultCommitTemplate = `dpl({{ .RequestID }}): update deployment manifest{{ with .Path }} of {{ . }}{{ end }}{{ with .Trailers }}
+
+{{ . }}{{ end }}`
+
+var ErrInvalidCommitTemplate = errors.New("invalid commit message template")
+
+// commitContext is the deployment context the commit message template is rendered with
+type commitContext struct {
+	RequestID string
+	Actor     string
+	Reason    string
+	CI        ci.Context
+
+	// Release and Environment are the unique release names and environments of the commit, separated by comma
+	Release     string
+	Environment string
+
+	// Path is the path the commit is limited to, it is empty unless a commit is made per path
+	Path string
+
+	// Provenance is where the deployment comes from, such as the promotion source
+	Provenance string
+
+	// Trailers are the git trailers of the commit, along with the provenance
+	Trailers string
+
+	Releases []commitRelease
+
+	deployer deployer
+}
+
+type commitRelease struct {
+	ID            string
+	Name          string
+	Environment   string
+	Cluster       string
+	Path          string
+	Image         string
+	Tag           string
+	PreviousImage string
+	PreviousTag   string
+	ChangelogURL  string
+}
+
+// newCommitTemplates parses the commit message template and the changelog URL template
+func newCommitTemplates(params *parameters) (message, changelog *template.Template, err error) {
+	text := params.CommitTemplate
+	if params.CommitTemplateFile != "" {
+		if text != "" {
+			return nil, nil, errs.Wrap(errors.New("--commit-template is not allowed along with --commit-template-file"), ErrInvalidCommitTemplate)
+		}
+
+		content, err := ioutils.ReadFile(params.CommitTemplateFile)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		text = string(content)
+	}
+
+	if text == "" {
+		text = DefaultCommitTemplate
+	}
+
+	if message, err = template.New("commit").Parse(text); err != nil {
+		return nil, nil, errs.Wrap(err, ErrInvalidCommitTemplate)
+	}
+
+	if params.ChangelogURL != "" {
+		if changelog, err = template.New("changelog").Parse(params.ChangelogURL); err != nil {
+			return nil, nil, errs.Wrap(err, ErrInvalidCommitTemplate)
+		}
+	}
+
+	return message, changelog, nil
+}
+
+func newCommitContext(reqID, provenance string, dep deployer) commitContext {
+	return commitContext{
+		RequestID:  reqID,
+		Actor:      dep.Actor,
+		Reason:     dep.Reason,
+		CI:         dep.CI,
+		Provenance: provenance,
+		deployer:   dep,
+	}
+}
+
+// commit commits the rendered manifests, either at once, or once per release path when it is configured
+func (ins *execInstance) commit(ctx context.Context, log logr.Logger, repo git.Repository, cctx commitContext, releases types.ListReleases, previousImages map[string]types.ImageDefinition) error {
+	if !ins.Params.CommitPerPath {
+		return ins.commitPath(ctx, log, repo, cctx, "", releases, previousImages)
+	}
+
+	var paths []string
+	groups := make(map[string]types.ListReleases)
+	for _, rel := range releases {
+		path := releasePath(rel)
+		if _, ok := groups[path]; !ok {
+			paths = append(paths, path)
+		}
+
+		groups[path] = append(groups[path], rel)
+	}
+
+	for _, path := range paths {
+		if err := ins.commitPath(ctx, log, repo, cctx, path, groups[path], previousImages); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ins *execInstance) commitPath(ctx context.Context, log logr.Logger, repo git.Repository, cctx commitContext, path string, releases types.ListReleases, previousImages map[string]types.ImageDefinition) error {
+	message, err := ins.newCommitMessage(cctx, path, releases, previousImages)
+	if err != nil {
+		return err
+	}
+
+	opts := []git.CommitOption{
+		ins.Params.Identity.CommitOption(),
+		git.WithCommitMessage(message),
+		git.WithCommitLogger(log),
+	}
+
+	if path != "" {
+		opts = append(opts, git.WithCommitPath(path))
+	}
+
+	return repo.Commit(ctx, opts...)
+}
+
+// newCommitMessage renders the commit message of the releases, the trailers are scoped to them
+func (ins *execInstance) newCommitMessage(cctx commitContext, path string, releases types.ListReleases, previousImages map[string]types.ImageDefinition) (string, error) {
+	cctx.Path = path
+
+	var names, environments []string
+	for _, rel := range releases {
+		names = appendUnique(names, rel.Name)
+		environments = appendUnique(environments, rel.Environment)
+
+		r := commitRelease{
+			ID:          rel.ID,
+			Name:        rel.Name,
+			Environment: rel.Environment,
+			Cluster:     rel.Cluster,
+			Path:        releasePath(rel),
+			Image:       rel.Image.String(),
+			Tag:         rel.Image.Tag,
+		}
+
+		if previous, ok := previousImages[rel.ID]; ok {
+			r.PreviousImage, r.PreviousTag = previous.String(), previous.Tag
+		}
+
+		if ins.ChangelogTemplate != nil && r.PreviousTag != "" && r.PreviousTag != r.Tag {
+			var buf bytes.Buffer
+			if err := ins.ChangelogTemplate.Execute(&buf, r); err != nil {
+				return "", errs.Wrap(err, ErrInvalidCommitTemplate)
+			}
+
+			r.ChangelogURL = buf.String()
+		}
+
+		cctx.Releases = append(cctx.Releases, r)
+	}
+
+	cctx.Release, cctx.Environment = strings.Join(names, ", "), strings.Join(environments, ", ")
+
+	var trailers []string
+	if cctx.Provenance != "" {
+		trailers = append(trailers, cctx.Provenance)
+	}
+	trailers = append(trailers, cctx.deployer.trailers(releases)...)
+	cctx.Trailers = strings.Join(trailers, "\n")
+
+	var buf bytes.Buffer
+	if err := ins.CommitTemplate.Execute(&buf, cctx); err != nil {
+		return "", errs.Wrap(err, ErrInvalidCommitTemplate)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// releasePath returns the path changed by the release, which is the generator file when the release is rendered to it
+func releasePath(rel *types.Release) string {
+	if rel.GeneratorFile != "" {
+		return filepath.Clean(rel.GeneratorFile)
+	}
+
+	return filepath.Clean(rel.GitPath)
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+
+	return append(values, value)
+}
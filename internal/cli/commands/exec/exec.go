@@ -2,18 +2,46 @@ package exec
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"text/template"
 	"time"
 
+	"github.com/ardikabs/dpl/internal/errs"
 	"github.com/ardikabs/dpl/internal/git"
+	"github.com/ardikabs/dpl/internal/history"
+	"github.com/ardikabs/dpl/internal/hooks"
 	"github.com/ardikabs/dpl/internal/manager"
 	"github.com/ardikabs/dpl/internal/manager/argocd"
+	"github.com/ardikabs/dpl/internal/metrics"
+	"github.com/ardikabs/dpl/internal/notifier"
 	"github.com/ardikabs/dpl/internal/renderer"
+	"github.com/ardikabs/dpl/internal/result"
+	"github.com/ardikabs/dpl/internal/smoke"
+	"github.com/ardikabs/dpl/internal/tracing"
 	"github.com/ardikabs/dpl/internal/types"
 	"github.com/go-logr/logr"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	metricsPushTimeout = 10 * time.Second
+
+	// onFailureHookTimeout bounds the on-failure hooks, which are run even when the deployment is cancelled
+	onFailureHookTimeout = 5 * time.Minute
+)
+
+var (
+	ErrRolledBack        = errors.New("deployment is rolled back to the previous image")
+	ErrRollbackFailed    = errors.New("failed to roll back deployment")
+	ErrNothingToRollback = errors.New("no previous image found for any release")
+	ErrDuplicateRelease  = errors.New("release is targeted more than once")
 )
 
 type execInstance struct {
@@ -21,7 +49,58 @@ type execInstance struct {
 	Git      git.Interface
 	Manager  manager.Interface
 	Renderer renderer.Interface
+	Hooks    *hooks.Runner
+	Smoke    *smoke.Checker
+	Notifier *notifier.Notifier
 	Logger   logr.Logger
+
+	// History is the store the deployments are recorded to, such as the server local store,
+	// otherwise they are recorded to the manifest repository when it is enabled.
+	History history.Store
+
+	// Provenance is appended to the commit message body when it is set,
+	// for example to record where a promoted image comes from.
+	Provenance string
+
+	// RequestID identifies the execution, it is generated when it is empty.
+	RequestID string
+
+	// ObserveMetrics records the metrics of every execution, even without the Pushgateway or the textfile,
+	// for example to be exposed by the server.
+	ObserveMetrics bool
+
+	// IgnoreCI ignores the CI context and the local user of the process,
+	// for example the server whose deployments are requested through the API instead.
+	IgnoreCI bool
+
+	// Result is the machine-readable result of the last execution
+	Result *result.Result
+
+	// Summary is where the summary table of the releases is written to, when there are many releases
+	Summary io.Writer
+
+	// CommitTemplate renders the deployment commit message,
+	// while ChangelogTemplate renders the changelog URL of a release whose tag is changed.
+	CommitTemplate    *template.Template
+	ChangelogTemplate *template.Template
+}
+
+// target is a single release deployment request,
+// a plain exec has exactly one target, while a batch exec could have many.
+type target struct {
+	ReleaseName string
+	Environment string
+	Cluster     string
+	Image       types.ImageDefinition
+}
+
+func (t target) String() string {
+	s := t.ReleaseName + " on " + t.Environment
+	if t.Cluster != "" {
+		s += "/" + t.Cluster
+	}
+
+	return s
 }
 
 func newExecInstance(log logr.Logger, params *parameters) (*execInstance, error) {
@@ -30,30 +109,65 @@ func newExecInstance(log logr.Logger, params *parameters) (*execInstance, error)
 		return nil, err
 	}
 
-	argo, err := argocd.NewClient(types.ArgoConfig{
-		Host:    params.ArgoCDHost,
-		GRPCWeb: true,
-		Secret: types.ArgoSecret{
-			Token: params.ArgoCDAuthToken,
-		},
-	})
+	argo, err := params.ArgoCD.NewManager()
+	if err != nil {
+		return nil, err
+	}
+
+	var hookRunner *hooks.Runner
+	if params.HooksFile != "" {
+		if hookRunner, err = hooks.Load(params.HooksFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var smokeChecker *smoke.Checker
+	if params.SmokeChecksFile != "" {
+		if smokeChecker, err = smoke.Load(params.SmokeChecksFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var n *notifier.Notifier
+	if params.NotifiersFile != "" {
+		if n, err = notifier.Load(params.NotifiersFile); err != nil {
+			return nil, err
+		}
+	}
+
+	commitTmpl, changelogTmpl, err := newCommitTemplates(params)
 	if err != nil {
 		return nil, err
 	}
 
 	return &execInstance{
-		Git:      g,
-		Manager:  argo,
-		Renderer: renderer.New(params.Profile),
-		Logger:   log,
-		Params:   params,
+		CommitTemplate:    commitTmpl,
+		ChangelogTemplate: changelogTmpl,
+		Git:               g,
+		Manager:           argo,
+		Renderer:          renderer.New(params.Profile),
+		Hooks:             hookRunner,
+		Smoke:             smokeChecker,
+		Notifier:          n,
+		Summary:           summaryOutput(params),
+		Logger:            log,
+		Params:            params,
 	}, nil
 }
 
+// summaryOutput returns where the summary table is written to, it is stderr when the result document owns the stdout
+func summaryOutput(params *parameters) io.Writer {
+	if params.Output == OutputJSON {
+		return os.Stderr
+	}
+
+	return os.Stdout
+}
+
 func (ins *execInstance) Exec(ctx context.Context) error {
 	imageDefinition := ins.Params.GetImageDefinition()
 
-	reqID := uuid.New().String()
+	reqID := ins.newRequestID()
 	log := ins.Logger.
 		WithName("exec").
 		WithValues(
@@ -63,22 +177,105 @@ func (ins *execInstance) Exec(ctx context.Context) error {
 			"requestID", reqID,
 		)
 
-	req, err := manager.NewListReleaseRequestBuilder().
-		SetReleaseSelector(ins.Params.SelectorForRelease, ins.Params.ReleaseName).
-		SetEnvironmentSelector(ins.Params.SelectorForEnvironment, ins.Params.Environment).
-		SetClusterSelector(ins.Params.SelectorForCluster, ins.Params.Cluster).
-		Build()
-	if err != nil {
-		return err
+	return ins.execute(ctx, log, reqID, []target{{
+		ReleaseName: ins.Params.ReleaseName,
+		Environment: ins.Params.Environment,
+		Cluster:     ins.Params.Cluster,
+		Image:       imageDefinition,
+	}})
+}
+
+// ExecBatch deploys every target listed in the batch file using a single clone, commit, and push.
+func (ins *execInstance) ExecBatch(ctx context.Context) error {
+	targets := ins.Params.GetBatchTargets()
+
+	reqID := ins.newRequestID()
+	log := ins.Logger.
+		WithName("exec.batch").
+		WithValues(
+			"file", ins.Params.FromFile,
+			"targets", len(targets),
+			"requestID", reqID,
+		)
+
+	return ins.execute(ctx, log, reqID, targets)
+}
+
+func (ins *execInstance) newRequestID() string {
+	if ins.RequestID != "" {
+		return ins.RequestID
+	}
+
+	return uuid.New().String()
+}
+
+func (ins *execInstance) execute(ctx context.Context, log logr.Logger, reqID string, targets []target) (err error) {
+	ctx = tracing.WithRequestID(ctx, reqID)
+	ctx, span := tracing.Start(ctx, "Exec", attribute.Int("dpl.targets", len(targets)))
+	defer tracing.End(span, &err)
+
+	if len(targets) == 1 {
+		span.SetAttributes(
+			attribute.String("dpl.release", targets[0].ReleaseName),
+			attribute.String("dpl.environment", targets[0].Environment),
+			attribute.String("dpl.image", targets[0].Image.String()),
+		)
 	}
 
-	releases, err := ins.Manager.ListReleases(ctx, req, manager.WithLogger(log))
+	var releases types.ListReleases
+
+	res := result.New(reqID)
+	res.RevisionTime = ins.revisionTime()
+	ins.Result = res
+	defer func() {
+		ins.report(log, res, releases, err)
+	}()
+
+	ev := newNotifierEvent(reqID, targets)
+	defer func() {
+		ev.Type = notifier.EventSucceeded
+		if err != nil {
+			ev.Type = notifier.EventFailed
+			ev.Error = err.Error()
+		}
+
+		ev.CommitSHA, ev.CommitURL = res.CommitSHA, notifier.CommitURL(res.GitURL, res.CommitSHA)
+		ev.Releases = newNotifierReleasesStatus(releases)
+		ins.notify(ctx, log, ev)
+	}()
+
+	hctx := newHookContext(reqID, targets)
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		hctx.Error = err.Error()
+		ins.runOnFailureHooks(ctx, log, hctx)
+	}()
+
+	done := res.Track("list-releases")
+	releases, err = ins.listReleases(ctx, log, targets)
+	done()
 	if err != nil {
+		if errs.IsAny(err, argocd.ErrArgoCDApplicationNotExists, argocd.ErrGitRepoAndRevisionMismatch) {
+			return result.WithCategory(result.CategoryValidation, err)
+		}
+
 		return err
 	}
 
+	hctx.Releases = newHookReleasesContext(releases)
+
+	dep := ins.newDeployer()
+	log = log.WithValues("actor", dep.Actor)
+
+	ev.Type, ev.Releases = notifier.EventStarted, newNotifierReleasesStatus(releases)
+	ins.notify(ctx, log, ev)
+
 	gitURL := releases.GetGitURL()
 	gitRevision := releases.GetGitRevision()
+	res.GitURL, res.GitRevision = gitURL, gitRevision
 
 	log = log.WithValues("gitURL", gitURL, "gitRevision", gitRevision)
 
@@ -88,11 +285,328 @@ func (ins *execInstance) Exec(ctx context.Context) error {
 	}
 	defer os.RemoveAll(workspace)
 
+	done = res.Track("clone")
 	repo, err := ins.Git.Clone(ctx, gitURL, workspace, git.WithCloneBranch(gitRevision), git.WithCloneLogger(log))
+	done()
+	if err != nil {
+		return result.WithCategory(result.CategoryGit, err)
+	}
+
+	if err := ins.Params.resolveGeneratorFiles(repo.Root(), log, releases); err != nil {
+		return result.WithCategory(result.CategoryRender, err)
+	}
+
+	hookOpts := append(ins.hookOptions(log), hooks.WithWorkdir(repo.Root()))
+
+	if err := ins.runHooks(ctx, hooks.StagePreRender, hctx, hookOpts...); err != nil {
+		return err
+	}
+
+	previousImages := ins.inspect(repo, log, releases)
+
+	done = res.Track("render")
+	err = ins.render(ctx, repo, log, releases)
+	done()
+	if err != nil {
+		return result.WithCategory(result.CategoryRender, err)
+	}
+
+	if err := ins.runHooks(ctx, hooks.StagePostRender, hctx, hookOpts...); err != nil {
+		return err
+	}
+
+	done = res.Track("commit")
+	err = ins.commit(ctx, log, repo, newCommitContext(reqID, ins.Provenance, dep), releases, previousImages)
+	done()
+	if err != nil {
+		return result.WithCategory(result.CategoryGit, err)
+	}
+
+	done = res.Track("push")
+	err = repo.Push(ctx, git.WithPushLogger(log))
+	done()
+	if err != nil {
+		return result.WithCategory(result.CategoryGit, err)
+	}
+
+	if hctx.CommitSHA, err = repo.Head(); err != nil {
+		return result.WithCategory(result.CategoryGit, err)
+	}
+	res.CommitSHA = hctx.CommitSHA
+
+	// the deployment is recorded from here onwards, as the manifests are changed regardless the outcome
+	defer func() {
+		ins.recordHistory(ctx, log, res, repo, releases, previousImages, dep, err)
+	}()
+
+	ins.annotate(ctx, log, reqID, res.CommitSHA, dep, releases)
+
+	if err := ins.runHooks(ctx, hooks.StagePostPush, hctx, hookOpts...); err != nil {
+		return err
+	}
+
+	done = res.Track("sync")
+	syncErr := ins.Manager.SyncReleases(ctx, releases, append(ins.syncOptions(),
+		manager.WithLogger(log),
+		manager.WithKeepGoing(ins.Params.KeepGoing),
+		manager.WithTerminateOnCancel(ins.Params.TerminateOnCancel),
+	)...)
+	done()
+
+	if len(targets) > 1 {
+		for _, rel := range releases {
+			log.Info("deployment result",
+				"id", rel.ID,
+				"release", rel.Name,
+				"environment", rel.Environment,
+				"cluster", rel.Cluster,
+				"image", rel.Image.String(),
+				"sync.status", rel.Status.Sync,
+				"health.status", rel.Status.Health,
+				"result", rel.Status.Result,
+			)
+		}
+	}
+
+	// the rest of the deployment goes on with the synced releases only, when the partial success is allowed
+	synced := releases
+	if syncErr != nil {
+		synced = releases.Succeeded()
+		if !ins.Params.AllowPartialSuccess || len(synced) == 0 {
+			return classifySyncError(syncErr)
+		}
+
+		log.Error(syncErr, "deployment is partially succeeded, continuing with the synced releases",
+			"synced", len(synced),
+			"failed", len(releases)-len(synced),
+		)
+	}
+
+	done = res.Track("smoke-check")
+	err = ins.smokeCheck(ctx, log, synced)
+	done()
 	if err != nil {
+		err = result.WithCategory(result.CategorySmokeCheck, err)
+		if !ins.Params.RollbackOnSmokeFailure {
+			return err
+		}
+
+		if rollbackErr := ins.rollback(ctx, log, reqID, repo, synced, previousImages); rollbackErr != nil {
+			return result.WithCategory(result.CategorySmokeCheck, errs.Wrap(err, errs.Wrap(rollbackErr, ErrRollbackFailed)))
+		}
+
+		return result.WithCategory(result.CategorySmokeCheck, errs.Wrap(err, ErrRolledBack))
+	}
+
+	if err := ins.runHooks(ctx, hooks.StagePostSync, hctx, hookOpts...); err != nil {
 		return err
 	}
 
+	log.Info("deployment executed successfully")
+	return nil
+}
+
+// hookOptions returns the options of running the hooks, the hooks write to stderr
+// when the result document owns the stdout, so their output doesn't corrupt it.
+func (ins *execInstance) hookOptions(log logr.Logger) []hooks.RunOption {
+	opts := []hooks.RunOption{hooks.WithLogger(log)}
+	if ins.Params.Output == OutputJSON {
+		opts = append(opts, hooks.WithOutput(os.Stderr, os.Stderr))
+	}
+
+	return opts
+}
+
+// runOnFailureHooks runs the on-failure hooks regardless the deployment is cancelled,
+// as the cancelled deployment is one of the failures they are meant for.
+func (ins *execInstance) runOnFailureHooks(ctx context.Context, log logr.Logger, hctx hooks.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), onFailureHookTimeout)
+	defer cancel()
+
+	if err := ins.Hooks.Run(ctx, hooks.StageOnFailure, hctx, ins.hookOptions(log)...); err != nil {
+		log.Error(err, "on-failure hook failed")
+	}
+}
+
+func (ins *execInstance) runHooks(ctx context.Context, stage hooks.Stage, hctx hooks.Context, opts ...hooks.RunOption) error {
+	return result.WithCategory(result.CategoryHook, ins.Hooks.Run(ctx, stage, hctx, opts...))
+}
+
+// report writes the deployment result to the configured outputs, failing to write the result doesn't fail the deployment.
+func (ins *execInstance) report(log logr.Logger, res *result.Result, releases types.ListReleases, err error) {
+	res.SetReleases(releases)
+	res.Finish(err)
+
+	if ins.Summary != nil && isSynced(releases) && (len(releases) > 1 || res.HasDiagnostics()) {
+		if err := res.WriteSummary(ins.Summary); err != nil {
+			log.Error(err, "failed to write deployment summary")
+		}
+	}
+
+	ins.exportMetrics(log, res)
+	writeResult(log, ins.Params, res)
+}
+
+// writeResult writes the result document to the output file, the stdout, and the GitHub Actions step outputs when configured.
+func writeResult(log logr.Logger, params *parameters, res *result.Result) {
+	if params.OutputFile != "" {
+		if err := res.WriteFile(params.OutputFile); err != nil {
+			log.Error(err, "failed to write result file", "file", params.OutputFile)
+		}
+	}
+
+	if params.Output == OutputJSON {
+		if err := res.Write(os.Stdout); err != nil {
+			log.Error(err, "failed to write result to stdout")
+		}
+	}
+
+	if githubOutput := os.Getenv("GITHUB_OUTPUT"); githubOutput != "" {
+		if err := res.WriteGitHubOutput(githubOutput); err != nil {
+			log.Error(err, "failed to write GitHub Actions step outputs", "file", githubOutput)
+		}
+	}
+}
+
+// exportMetrics observes the deployment result and exports the metrics to the Pushgateway and the textfile when configured.
+func (ins *execInstance) exportMetrics(log logr.Logger, res *result.Result) {
+	if !ins.ObserveMetrics && ins.Params.MetricsPushgatewayURL == "" && ins.Params.MetricsTextfile == "" {
+		return
+	}
+
+	metrics.Observe(res)
+
+	if ins.Params.MetricsTextfile != "" {
+		if err := metrics.WriteTextfile(ins.Params.MetricsTextfile); err != nil {
+			log.Error(err, "failed to write metrics textfile", "file", ins.Params.MetricsTextfile)
+		}
+	}
+
+	if ins.Params.MetricsPushgatewayURL != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), metricsPushTimeout)
+		defer cancel()
+
+		if err := metrics.Push(ctx, ins.Params.MetricsPushgatewayURL, ins.metricsGrouping()); err != nil {
+			log.Error(err, "failed to push metrics to the Pushgateway", "url", ins.Params.MetricsPushgatewayURL)
+		}
+	}
+}
+
+// metricsGrouping returns the Pushgateway grouping key, so the deployments to different environments or clusters don't overwrite each other,
+// the grouping labels must not collide with the metric labels, hence they are named after the deployment instead.
+func (ins *execInstance) metricsGrouping() map[string]string {
+	targets := []target{{ReleaseName: ins.Params.ReleaseName, Environment: ins.Params.Environment, Cluster: ins.Params.Cluster}}
+
+	deployment := ins.Params.ReleaseName
+	if ins.Params.FromFile != "" {
+		deployment = strings.TrimSuffix(filepath.Base(ins.Params.FromFile), filepath.Ext(ins.Params.FromFile))
+		targets = ins.Params.GetBatchTargets()
+	}
+
+	var environments, clusters []string
+	for _, t := range targets {
+		environments = appendUnique(environments, t.Environment)
+		clusters = appendUnique(clusters, t.Cluster)
+	}
+
+	grouping := map[string]string{"deployment": deployment}
+	if len(environments) == 1 && environments[0] != "" {
+		grouping["deployment_environment"] = environments[0]
+	}
+
+	if len(clusters) == 1 && clusters[0] != "" {
+		grouping["deployment_cluster"] = clusters[0]
+	}
+
+	return grouping
+}
+
+// syncOptions returns the options controlling the pace and the order of the release syncs
+func (ins *execInstance) syncOptions() []manager.Option {
+	label, values := ins.Params.GetSyncOrder()
+
+	return []manager.Option{
+		manager.WithMaxConcurrency(ins.Params.MaxConcurrency),
+		manager.WithSyncDelay(ins.Params.SyncDelay),
+		manager.WithSyncOrder(label, values...),
+		manager.WithPrune(ins.Params.SyncPrune),
+		manager.WithDryRun(ins.Params.SyncDryRun),
+		manager.WithForce(ins.Params.SyncForce),
+		manager.WithSyncStrategy(ins.Params.SyncStrategy),
+		manager.WithSyncOptions(ins.Params.GetSyncOptions()...),
+		manager.WithSyncResources(ins.Params.GetSyncResources()...),
+		manager.WithSyncRetry(ins.Params.GetSyncRetry()),
+		manager.WithRolloutPaused(ins.Params.OnRolloutPaused),
+	}
+}
+
+// isSynced returns true when the sync of the releases is attempted
+func isSynced(releases types.ListReleases) bool {
+	for _, rel := range releases {
+		if rel.Status.Result != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func classifySyncError(err error) error {
+	switch {
+	case errs.IsAny(err, argocd.ErrSyncCancelled, context.Canceled):
+		return result.WithCategory(result.CategoryCancelled, err)
+	case errs.IsAny(err, argocd.ErrSyncOnWatchTimeout, argocd.ErrSyncOperationTimeout):
+		return result.WithCategory(result.CategorySyncTimeout, err)
+	case errs.IsAny(err, argocd.ErrStatusHealthDegraded):
+		return result.WithCategory(result.CategoryDegraded, err)
+	default:
+		return result.WithCategory(result.CategorySync, err)
+	}
+}
+
+// listReleases looks up the releases for every target, all of them must share the same git repository and revision,
+// as they are rendered and committed within a single workspace.
+func (ins *execInstance) listReleases(ctx context.Context, log logr.Logger, targets []target) (types.ListReleases, error) {
+	var releases types.ListReleases
+
+	// the overlapping targets would render and sync the same Application twice
+	targeted := make(map[string]target)
+
+	for _, t := range targets {
+		req, err := manager.NewListReleaseRequestBuilder().
+			SetReleaseSelector(ins.Params.SelectorForRelease, t.ReleaseName).
+			SetEnvironmentSelector(ins.Params.SelectorForEnvironment, t.Environment).
+			SetClusterSelector(ins.Params.SelectorForCluster, t.Cluster).
+			Build()
+		if err != nil {
+			return nil, err
+		}
+
+		rels, err := ins.Manager.ListReleases(ctx, req, ins.Params.listOptions(log)...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rel := range rels {
+			if len(releases) > 0 && (rel.GitURL != releases.GetGitURL() || rel.GitRevision != releases.GetGitRevision()) {
+				return nil, errs.Wrapf(argocd.ErrGitRepoAndRevisionMismatch, "release %s on %s", t.ReleaseName, t.Environment)
+			}
+
+			key := rel.Instance + "/" + rel.ID
+			if prev, ok := targeted[key]; ok {
+				return nil, errs.Wrapf(ErrDuplicateRelease, "application %s is targeted by both %s and %s", rel.ID, prev, t)
+			}
+			targeted[key] = t
+
+			rel.Image = t.Image
+			releases = append(releases, rel)
+		}
+	}
+
+	return releases, nil
+}
+
+func (ins *execInstance) render(ctx context.Context, repo git.Repository, log logr.Logger, releases types.ListReleases) error {
 	for _, rel := range releases {
 		log := log.WithValues("id", rel.ID, "cluster", rel.Cluster, "gitPath", rel.GitPath)
 		rendererOpts := []renderer.RenderOption{
@@ -105,21 +619,253 @@ func (ins *execInstance) Exec(ctx context.Context) error {
 			}))
 		}
 
-		workdir := filepath.Join(repo.Root(), rel.GitPath)
-		if err := ins.Renderer.Render(workdir, ins.Params.ReleaseName, &renderer.KustomizeParams{
-			KustomizationRef:   ins.Params.KustomizationFileRef,
-			ImageReferenceName: ins.Params.KustomizationImageRef,
-			ImageName:          imageDefinition.Name,
-			ImageTag:           imageDefinition.Tag,
-		}, rendererOpts...); err != nil {
+		_, span := tracing.Start(ctx, "Renderer.Render",
+			attribute.String("argocd.application", rel.ID),
+			attribute.String("dpl.cluster", rel.Cluster),
+			attribute.String("dpl.image", rel.Image.String()),
+		)
+
+		r, workdir, params := ins.Params.releaseRenderer(ins.Renderer, repo.Root(), rel)
+		err := r.Render(workdir, rel.Name, params, rendererOpts...)
+
+		tracing.End(span, &err)
+		if err != nil {
 			return err
 		}
 	}
 
+	return nil
+}
+
+func newHookContext(reqID string, targets []target) hooks.Context {
+	hctx := hooks.Context{RequestID: reqID}
+
+	if len(targets) == 1 {
+		hctx.Release = targets[0].ReleaseName
+		hctx.Environment = targets[0].Environment
+		hctx.Image = targets[0].Image.String()
+	}
+
+	return hctx
+}
+
+func newHookReleasesContext(releases types.ListReleases) []hooks.ReleaseContext {
+	out := make([]hooks.ReleaseContext, 0, len(releases))
+	for _, rel := range releases {
+		out = append(out, hooks.ReleaseContext{
+			ID:          rel.ID,
+			Name:        rel.Name,
+			Environment: rel.Environment,
+			Cluster:     rel.Cluster,
+			Image:       rel.Image.String(),
+			GitPath:     rel.GitPath,
+		})
+	}
+
+	return out
+}
+
+func (ins *execInstance) isHistoryEnabled() bool {
+	return ins.History != nil || ins.Params.RecordHistory
+}
+
+// recordHistory appends the deployment to the history ledger, either to the store, or to the manifest repository as a separate commit.
+// Failing to record doesn't fail the deployment.
+func (ins *execInstance) recordHistory(ctx context.Context, log logr.Logger, res *result.Result, repo git.Repository, releases types.ListReleases, previousImages map[string]types.ImageDefinition, dep deployer, deployErr error) {
+	if !ins.isHistoryEnabled() {
+		return
+	}
+
+	entries := ins.newHistoryEntries(res, releases, previousImages, dep, deployErr)
+
+	if ins.History != nil {
+		if err := ins.History.Append(entries...); err != nil {
+			log.Error(err, "failed to record deployment history")
+		}
+
+		return
+	}
+
+	// the ledger is recorded even when the deployment is cancelled
+	ctx = context.WithoutCancel(ctx)
+
+	if err := history.NewFileStore(repo.Root()).Append(entries...); err != nil {
+		log.Error(err, "failed to record deployment history")
+		return
+	}
+
+	if err := repo.Commit(ctx,
+		ins.Params.Identity.CommitOption(),
+		git.WithCommitMessage(fmt.Sprintf("dpl(%s): record deployment history", res.RequestID)),
+		git.WithCommitPath(history.Dir),
+		git.WithCommitLogger(log),
+	); err != nil {
+		log.Error(err, "failed to commit deployment history")
+		return
+	}
+
+	if err := repo.Push(ctx, git.WithPushLogger(log)); err != nil {
+		log.Error(err, "failed to push deployment history")
+	}
+}
+
+// newHistoryEntries returns an entry per release and environment, along with the clusters it is deployed to
+func (ins *execInstance) newHistoryEntries(res *result.Result, releases types.ListReleases, previousImages map[string]types.ImageDefinition, dep deployer, deployErr error) []history.Entry {
+	status, category := result.StatusSucceeded, ""
+	if deployErr != nil {
+		status, category = result.StatusFailed, string(result.CategoryOf(deployErr))
+	}
+
+	var entries []history.Entry
+	index := make(map[string]int)
+
+	for _, rel := range releases {
+		key := rel.Name + "/" + rel.Environment
+
+		idx, ok := index[key]
+		if !ok {
+			idx = len(entries)
+			index[key] = idx
+			entries = append(entries, history.Entry{
+				RequestID:       res.RequestID,
+				Actor:           dep.Actor,
+				Release:         rel.Name,
+				Environment:     rel.Environment,
+				Image:           rel.Image.String(),
+				CommitSHA:       res.CommitSHA,
+				Result:          status,
+				ErrorCategory:   category,
+				StartedAt:       res.StartedAt,
+				DurationSeconds: time.Since(res.StartedAt).Seconds(),
+			})
+		}
+
+		e := &entries[idx]
+		e.Clusters = append(e.Clusters, rel.Cluster)
+		if previous, ok := previousImages[rel.ID]; ok && e.PreviousImage == "" {
+			e.PreviousImage = previous.String()
+		}
+	}
+
+	return entries
+}
+
+// notify sends the event without failing the deployment, it outlives the context cancellation,
+// so the failure caused by the cancellation is still notified.
+func (ins *execInstance) notify(ctx context.Context, log logr.Logger, ev notifier.Event) {
+	if err := ins.Notifier.Notify(context.WithoutCancel(ctx), ev, notifier.WithLogger(log)); err != nil {
+		log.Error(err, "failed to send deployment notification", "event", ev.Type)
+	}
+}
+
+func newNotifierEvent(reqID string, targets []target) notifier.Event {
+	ev := notifier.Event{RequestID: reqID}
+
+	if len(targets) == 1 {
+		ev.Release = targets[0].ReleaseName
+		ev.Environment = targets[0].Environment
+		ev.Image = targets[0].Image.String()
+	}
+
+	return ev
+}
+
+func newNotifierReleasesStatus(releases types.ListReleases) []notifier.ReleaseStatus {
+	out := make([]notifier.ReleaseStatus, 0, len(releases))
+	for _, rel := range releases {
+		out = append(out, notifier.ReleaseStatus{
+			ID:           rel.ID,
+			Name:         rel.Name,
+			Environment:  rel.Environment,
+			Cluster:      rel.Cluster,
+			Image:        rel.Image.String(),
+			SyncStatus:   rel.Status.Sync,
+			HealthStatus: rel.Status.Health,
+			Result:       rel.Status.Result,
+			Message:      rel.Status.Message,
+		})
+	}
+
+	return out
+}
+
+// inspect reads the image currently rendered for every release, releases without image reference are left out.
+func (ins *execInstance) inspect(repo git.Repository, log logr.Logger, releases types.ListReleases) map[string]types.ImageDefinition {
+	images := make(map[string]types.ImageDefinition, len(releases))
+
+	for _, rel := range releases {
+		log := log.WithValues("id", rel.ID, "cluster", rel.Cluster, "gitPath", rel.GitPath)
+
+		r, workdir, params := ins.Params.releaseRenderer(ins.Renderer, repo.Root(), rel)
+		image, err := r.Inspect(workdir, rel.Name, params, renderer.WithLogger(log))
+		if err != nil {
+			log.Info("unable to inspect the current image, the previous image is unknown", "err", err)
+			continue
+		}
+
+		images[rel.ID] = image
+	}
+
+	return images
+}
+
+func (ins *execInstance) smokeCheck(ctx context.Context, log logr.Logger, releases types.ListReleases) error {
+	if ins.Smoke == nil {
+		return nil
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	if ins.Params.MaxConcurrency > 0 {
+		g.SetLimit(ins.Params.MaxConcurrency)
+	}
+
+	for _, rel := range releases {
+		rel := rel
+
+		g.Go(func() error {
+			return ins.Smoke.Run(ctx, smoke.Target{
+				Release:     rel.Name,
+				Environment: rel.Environment,
+				Cluster:     rel.Cluster,
+				Namespace:   rel.Namespace,
+				Image:       rel.Image.String(),
+			}, smoke.WithLogger(log.WithValues("id", rel.ID)))
+		})
+	}
+
+	return g.Wait()
+}
+
+// rollback renders the previous image back for every release, then commits, pushes, and syncs the releases again.
+// The releases are reported with the status of the rollback sync, while keeping the image of the deployment.
+func (ins *execInstance) rollback(ctx context.Context, log logr.Logger, reqID string, repo git.Repository, releases types.ListReleases, previousImages map[string]types.ImageDefinition) error {
+	log = log.WithName("rollback")
+
+	var rolledBack, rollbackReleases types.ListReleases
+	for _, rel := range releases {
+		image, ok := previousImages[rel.ID]
+		if !ok {
+			log.Info("no previous image found, skipping release", "id", rel.ID, "cluster", rel.Cluster)
+			continue
+		}
+
+		r := *rel
+		r.Image = image
+		rolledBack = append(rolledBack, rel)
+		rollbackReleases = append(rollbackReleases, &r)
+	}
+
+	if len(rollbackReleases) == 0 {
+		return ErrNothingToRollback
+	}
+
+	if err := ins.render(ctx, repo, log, rollbackReleases); err != nil {
+		return err
+	}
+
 	if err := repo.Commit(ctx,
-		git.WithCommitMessage(fmt.Sprintf("dpl(%s): update deployment manifest", reqID)),
-		git.WithCommitter("autobot", "me@ardikabs"),
-		git.WithCommitPath("."),
+		ins.Params.Identity.CommitOption(),
+		git.WithCommitMessage(fmt.Sprintf("dpl(%s): rollback deployment manifest", reqID)),
 		git.WithCommitLogger(log),
 	); err != nil {
 		return err
@@ -129,10 +875,18 @@ func (ins *execInstance) Exec(ctx context.Context) error {
 		return err
 	}
 
-	if err := ins.Manager.SyncReleases(ctx, releases, manager.WithLogger(log)); err != nil {
+	err := ins.Manager.SyncReleases(ctx, rollbackReleases, append(ins.syncOptions(),
+		manager.WithLogger(log),
+	)...)
+
+	for i, rel := range rollbackReleases {
+		rolledBack[i].Status = rel.Status
+	}
+
+	if err != nil {
 		return err
 	}
 
-	log.Info("deployment executed successfully")
+	log.Info("deployment rolled back successfully")
 	return nil
 }
@@ -0,0 +1,171 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ardikabs/dpl/internal/cli/gitconfig"
+	"github.com/ardikabs/dpl/internal/hooks"
+	"github.com/ardikabs/dpl/internal/result"
+	"github.com/ardikabs/dpl/internal/smoke"
+	"github.com/ardikabs/dpl/internal/types"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestInstance(t *testing.T, m *fakeManager) *execInstance {
+	t.Helper()
+
+	params := &parameters{
+		Identity:    gitconfig.Identity{CommitterName: "autobot", CommitterEmail: "me@ardikabs"},
+		ReleaseName: "myapp",
+		Environment: "staging",
+		Image:       "ghcr.io/ardikabs/app/myapp:b6d7153",
+	}
+	require.NoError(t, params.validateAndSetImageDefinition())
+
+	commitTmpl, changelogTmpl, err := newCommitTemplates(params)
+	require.NoError(t, err)
+
+	return &execInstance{
+		CommitTemplate:    commitTmpl,
+		ChangelogTemplate: changelogTmpl,
+		Git:               &fakeGit{},
+		Manager:           m,
+		Renderer:          &fakeRenderer{},
+		Logger:            logr.Discard(),
+		Params:            params,
+	}
+}
+
+func TestExecInstance_Exec_OnFailureHook(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the deployment is cancelled while the releases are being synced
+	m := &fakeManager{
+		releases: types.ListReleases{{ID: "myapp-staging", Name: "myapp", Environment: "staging", GitPath: "staging"}},
+		sync: func(ctx context.Context, _ types.ListReleases) error {
+			cancel()
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+
+	payload := filepath.Join(t.TempDir(), "payload.json")
+	runner, err := hooks.New(hooks.Hook{Name: "report", Stage: hooks.StageOnFailure, Command: "cat > " + payload})
+	require.NoError(t, err)
+
+	ins := newTestInstance(t, m)
+	ins.Hooks = runner
+
+	require.ErrorIs(t, ins.Exec(ctx), context.Canceled)
+
+	content, err := os.ReadFile(payload)
+	require.NoError(t, err)
+	require.Contains(t, string(content), `"stage":"on-failure"`)
+	require.Contains(t, string(content), "context canceled")
+}
+
+func TestExecInstance_ExportMetrics(t *testing.T) {
+	// the grouping labels are parsed from the path, as they are pushed in no particular order
+	var groupings []map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		components := strings.Split(strings.TrimPrefix(r.URL.Path, "/metrics/"), "/")
+		grouping := make(map[string]string)
+		for i := 0; i+1 < len(components); i += 2 {
+			grouping[components[i]] = components[i+1]
+		}
+
+		groupings = append(groupings, grouping)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	push := func(params *parameters) {
+		params.MetricsPushgatewayURL = srv.URL
+
+		ins := &execInstance{Params: params}
+		res := result.New("b6d7153")
+		res.Finish(nil)
+		ins.exportMetrics(logr.Discard(), res)
+	}
+
+	push(&parameters{ReleaseName: "myapp", Environment: "staging"})
+	push(&parameters{ReleaseName: "myapp", Environment: "production", Cluster: "prod-1"})
+	push(&parameters{FromFile: "deploy/monorepo.yaml", batchTargets: []target{
+		{ReleaseName: "myapp", Environment: "production", Cluster: "prod-1"},
+		{ReleaseName: "myapp", Environment: "production", Cluster: "prod-2"},
+	}})
+
+	require.Equal(t, []map[string]string{
+		{"job": "dpl", "deployment": "myapp", "deployment_environment": "staging"},
+		{"job": "dpl", "deployment": "myapp", "deployment_environment": "production", "deployment_cluster": "prod-1"},
+		{"job": "dpl", "deployment": "monorepo", "deployment_environment": "production"},
+	}, groupings)
+}
+
+func TestExecInstance_Exec_RollbackOnSmokeFailure(t *testing.T) {
+	previous := types.ImageDefinition{Name: "ghcr.io/ardikabs/app/myapp", Tag: "a1c2e3f"}
+
+	for _, restart := range []bool{false, true} {
+		t.Run(fmt.Sprintf("restart=%t", restart), func(t *testing.T) {
+			// the rollback sync is told apart by the image it syncs
+			m := &fakeManager{
+				releases: types.ListReleases{{ID: "myapp-staging", Name: "myapp", Environment: "staging", GitPath: "staging"}},
+				sync: func(_ context.Context, rels types.ListReleases) error {
+					for _, rel := range rels {
+						rel.Status = types.ReleaseStatus{
+							Sync:    types.SyncStatusSynced,
+							Health:  types.HealthStatusHealthy,
+							Result:  types.ReleaseResultSucceeded,
+							Message: "synced " + rel.Image.Tag,
+						}
+					}
+
+					return nil
+				},
+			}
+
+			checker, err := smoke.New(smoke.Config{TimeoutSec: 1, RetryIntervalSec: 1, Checks: []smoke.Check{{Name: "e2e", Command: "exit 1"}}})
+			require.NoError(t, err)
+
+			ins := newTestInstance(t, m)
+			ins.Smoke = checker
+			ins.Params.RollbackOnSmokeFailure = true
+			ins.Params.IsTriggerRestart = restart
+
+			r := ins.Renderer.(*fakeRenderer)
+			r.images = map[string]types.ImageDefinition{"staging": previous}
+
+			err = ins.Exec(context.Background())
+			require.ErrorIs(t, err, ErrRolledBack)
+			require.ErrorIs(t, err, smoke.ErrSmokeCheckFailed)
+			require.Equal(t, result.CategorySmokeCheck, result.CategoryOf(err))
+
+			require.Equal(t, []string{"staging=ghcr.io/ardikabs/app/myapp:b6d7153", "staging=" + previous.String()}, r.rendered)
+			if restart {
+				require.Equal(t, []string{"staging", "staging"}, r.restarted)
+			} else {
+				require.Empty(t, r.restarted)
+			}
+
+			repo := ins.Git.(*fakeGit).repo
+			require.Len(t, repo.commits, 2)
+			require.Contains(t, repo.commits[1].Message, "rollback deployment manifest")
+			require.Equal(t, "autobot", repo.commits[1].Committer.Name)
+			require.Equal(t, 2, repo.pushes)
+			require.Equal(t, 2, m.syncs)
+
+			require.Len(t, ins.Result.Releases, 1)
+			require.Equal(t, "synced a1c2e3f", ins.Result.Releases[0].Message)
+			require.Equal(t, "ghcr.io/ardikabs/app/myapp:b6d7153", ins.Result.Releases[0].Image)
+		})
+	}
+}
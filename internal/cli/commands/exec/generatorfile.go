@@ -0,0 +1,82 @@
+package exec
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/ardikabs/dpl/internal/errs"
+	"github.com/ardikabs/dpl/internal/renderer"
+	"github.com/ardikabs/dpl/internal/types"
+	"github.com/go-logr/logr"
+)
+
+var ErrGeneratorSourceMismatch = errors.New("git files generator reads another repository or revision than the release")
+
+// resolveGeneratorFiles resolves the generator file of every release generated by a git files generator,
+// so the image is rendered to the values the generator consumes instead of the release path.
+func (p *parameters) resolveGeneratorFiles(root string, log logr.Logger, releases types.ListReleases) error {
+	if !p.EditGeneratorFiles {
+		return nil
+	}
+
+	for _, rel := range releases {
+		if rel.Generator == nil {
+			continue
+		}
+
+		// the edited file is only read by the generator when it lives in the cloned repository and revision
+		if !sameRepoURL(rel.Generator.RepoURL, rel.GitURL) || !sameRevision(rel.Generator.Revision, rel.GitRevision) {
+			return errs.Wrapf(ErrGeneratorSourceMismatch, "applicationset %s reads %s at %s", rel.ApplicationSet, rel.Generator.RepoURL, rel.Generator.Revision)
+		}
+
+		file, err := renderer.ResolveGeneratorFile(root, rel.Generator, rel.ID)
+		if err != nil {
+			return errs.Wrapf(err, "applicationset %s", rel.ApplicationSet)
+		}
+
+		log.V(1).Info("generator file is resolved", "id", rel.ID, "applicationset", rel.ApplicationSet, "file", file)
+		rel.GeneratorFile = file
+	}
+
+	return nil
+}
+
+// releaseRenderer returns the renderer along with its working directory and params for the release,
+// the release with the generator file is rendered to the generator file from the repository root.
+func (p *parameters) releaseRenderer(r renderer.Interface, root string, rel *types.Release) (renderer.Interface, string, interface{}) {
+	if rel.GeneratorFile != "" {
+		return &renderer.GeneratorFile{}, root, &renderer.GeneratorFileParams{
+			File:        rel.GeneratorFile,
+			ImageKey:    p.GeneratorImageKey,
+			ImageName:   rel.Image.Name,
+			ImageTag:    rel.Image.Tag,
+			ImageDigest: rel.Image.Digest,
+		}
+	}
+
+	return r, filepath.Join(root, rel.GitPath), &renderer.KustomizeParams{
+		KustomizationRef:   p.KustomizationFileRef,
+		ImageReferenceName: p.KustomizationImageRef,
+		ImageName:          rel.Image.Name,
+		ImageTag:           rel.Image.Tag,
+		ImageDigest:        rel.Image.Digest,
+	}
+}
+
+func sameRepoURL(a, b string) bool {
+	normalize := func(url string) string {
+		return strings.TrimSuffix(strings.TrimSuffix(strings.ToLower(url), "/"), ".git")
+	}
+
+	return normalize(a) == normalize(b)
+}
+
+// sameRevision returns true when both are the same revision, the empty and HEAD revisions are the default branch
+func sameRevision(a, b string) bool {
+	isDefault := func(revision string) bool {
+		return revision == "" || revision == "HEAD"
+	}
+
+	return a == b || (isDefault(a) && isDefault(b))
+}
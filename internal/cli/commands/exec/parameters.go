@@ -4,31 +4,84 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/ardikabs/dpl/internal/cli/argoconfig"
+	"github.com/ardikabs/dpl/internal/cli/gitconfig"
+	"github.com/ardikabs/dpl/internal/manager"
 	"github.com/ardikabs/dpl/internal/types"
+	"github.com/go-logr/logr"
 	"github.com/joeshaw/envdecode"
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
 )
 
+const (
+	OutputJSON = "json"
+)
+
 type parameters struct {
 	ReleaseName            string
 	Image                  string
 	Environment            string
 	Cluster                string
-	Profile                string `env:"DPL_PROFILE,default=kustomize"`
-	SelectorForRelease     string `env:"DPL_SELECTOR_FOR_RELEASE,default=platform.ardikabs.com/release"`
-	SelectorForEnvironment string `env:"DPL_SELECTOR_FOR_ENVIRONMENT,default=platform.ardikabs.com/environment"`
-	SelectorForCluster     string `env:"DPL_SELECTOR_FOR_CLUSTER,default=platform.ardikabs.com/cluster"`
-	KustomizationFileRef   string `env:"KUSTOMIZE_FILE_REF,default=kustomization.yaml"`
-	KustomizationImageRef  string `env:"KUSTOMIZE_IMAGE_REF,default=img"`
-	ArgoCDAuthToken        string `env:"ARGOCD_AUTH_TOKEN"`
-	ArgoCDHost             string `env:"ARGOCD_HOST"`
-	GitSecret              string `env:"GIT_SECRET"`
+	Profile                string        `env:"DPL_PROFILE,default=kustomize"`
+	SelectorForRelease     string        `env:"DPL_SELECTOR_FOR_RELEASE,default=platform.ardikabs.com/release"`
+	SelectorForEnvironment string        `env:"DPL_SELECTOR_FOR_ENVIRONMENT,default=platform.ardikabs.com/environment"`
+	SelectorForCluster     string        `env:"DPL_SELECTOR_FOR_CLUSTER,default=platform.ardikabs.com/cluster"`
+	KustomizationFileRef   string        `env:"KUSTOMIZE_FILE_REF,default=kustomization.yaml"`
+	KustomizationImageRef  string        `env:"KUSTOMIZE_IMAGE_REF,default=img"`
+	GitSecret              string        `env:"GIT_SECRET"`
+	MaxConcurrency         int           `env:"DPL_MAX_CONCURRENCY,default=10"`
+	HooksFile              string        `env:"DPL_HOOKS_FILE"`
+	SmokeChecksFile        string        `env:"DPL_SMOKE_CHECKS_FILE"`
+	NotifiersFile          string        `env:"DPL_NOTIFIERS_FILE"`
+	OutputFile             string        `env:"DPL_OUTPUT_FILE"`
+	MetricsPushgatewayURL  string        `env:"DPL_METRICS_PUSHGATEWAY_URL"`
+	MetricsTextfile        string        `env:"DPL_METRICS_TEXTFILE"`
+	RevisionTime           string        `env:"DPL_REVISION_TIME"`
+	RecordHistory          bool          `env:"DPL_RECORD_HISTORY"`
+	Actor                  string        `env:"DPL_ACTOR"`
+	Reason                 string        `env:"DPL_REASON"`
+	CommitTemplate         string        `env:"DPL_COMMIT_TEMPLATE"`
+	CommitTemplateFile     string        `env:"DPL_COMMIT_TEMPLATE_FILE"`
+	ChangelogURL           string        `env:"DPL_CHANGELOG_URL"`
+	CommitPerPath          bool          `env:"DPL_COMMIT_PER_PATH"`
+	KeepGoing              bool          `env:"DPL_KEEP_GOING"`
+	AllowPartialSuccess    bool          `env:"DPL_ALLOW_PARTIAL_SUCCESS"`
+	SyncDelay              time.Duration `env:"DPL_SYNC_DELAY"`
+	SyncOrder              string        `env:"DPL_SYNC_ORDER"`
+	TerminateOnCancel      bool          `env:"DPL_TERMINATE_ON_CANCEL"`
+	SyncPrune              bool          `env:"DPL_SYNC_PRUNE"`
+	SyncDryRun             bool          `env:"DPL_SYNC_DRY_RUN"`
+	SyncForce              bool          `env:"DPL_SYNC_FORCE"`
+	SyncStrategy           string        `env:"DPL_SYNC_STRATEGY"`
+	SyncOptions            string        `env:"DPL_SYNC_OPTIONS"`
+	SyncResources          string        `env:"DPL_SYNC_RESOURCES"`
+	SyncRetryLimit         int64         `env:"DPL_SYNC_RETRY_LIMIT"`
+	SyncRetryBackoff       time.Duration `env:"DPL_SYNC_RETRY_BACKOFF,default=5s"`
+	SyncRetryFactor        int64         `env:"DPL_SYNC_RETRY_FACTOR,default=2"`
+	SyncRetryMaxBackoff    time.Duration `env:"DPL_SYNC_RETRY_MAX_BACKOFF,default=3m"`
+	OnRolloutPaused        string        `env:"DPL_ON_ROLLOUT_PAUSED,default=wait"`
+	DiscoverAppSets        bool          `env:"DPL_DISCOVER_APPLICATIONSETS"`
+	EditGeneratorFiles     bool          `env:"DPL_EDIT_GENERATOR_FILES"`
+	GeneratorImageKey      string        `env:"DPL_GENERATOR_IMAGE_KEY,default=image"`
+	Output                 string
+	FromFile               string
 	IsTriggerRestart       bool
+	RollbackOnSmokeFailure bool
+
+	Identity gitconfig.Identity
+	ArgoCD   argoconfig.Parameters
 
 	gitSecret       types.GitSecret
 	imageDefinition types.ImageDefinition
+	batchTargets    []target
+	syncOrderLabel  string
+	syncOrderValues []string
+	syncOptions     []string
+	syncResources   []manager.SyncResource
+	revisionTime    time.Time
 }
 
 func (p *parameters) Attach(flagset *flag.FlagSet) error {
@@ -39,6 +92,15 @@ func (p *parameters) Attach(flagset *flag.FlagSet) error {
 	flagset.StringVarP(&p.Image, "image", "i", p.Image, "Container image to be deployed for the release")
 	flagset.StringVarP(&p.Environment, "environment", "e", p.Environment, "Environment to deploy the release")
 	flagset.StringVarP(&p.Cluster, "cluster", "c", p.Cluster, "Cluster to deploy the release")
+	flagset.StringVarP(&p.FromFile, "from-file", "f", p.FromFile, "Manifest file listing the releases to be deployed at once")
+	p.attachDeploymentFlags(flagset)
+
+	return nil
+}
+
+// attachDeploymentFlags attaches the flags of the deployment flow, shared by the commands running it,
+// the release and its target are attached by the command itself.
+func (p *parameters) attachDeploymentFlags(flagset *flag.FlagSet) {
 	flagset.StringVar(&p.Profile, "profile", p.Profile, "Selected profile for deployment")
 	flagset.StringVar(&p.KustomizationFileRef, "kustomize-file-ref", p.KustomizationFileRef, "Kustomization file reference")
 	flagset.StringVar(&p.KustomizationImageRef, "kustomize-image-ref", p.KustomizationImageRef, "Kustomization image reference name")
@@ -46,11 +108,65 @@ func (p *parameters) Attach(flagset *flag.FlagSet) error {
 	flagset.StringVar(&p.SelectorForEnvironment, "selector-for-environment", p.SelectorForEnvironment, "Selector for 'environment' attribute")
 	flagset.StringVar(&p.SelectorForCluster, "selector-for-cluster", p.SelectorForCluster, "Selector for 'cluster' attribute")
 	flagset.BoolVar(&p.IsTriggerRestart, "restart", p.IsTriggerRestart, "Restart the release")
+	flagset.StringVar(&p.HooksFile, "hooks-file", p.HooksFile, "Hooks configuration file executed at the deployment lifecycle stages")
+	flagset.StringVar(&p.SmokeChecksFile, "smoke-checks-file", p.SmokeChecksFile, "Smoke checks configuration file executed after the releases are synced")
+	flagset.StringVar(&p.NotifiersFile, "notifiers-file", p.NotifiersFile, "Notifiers configuration file to send the deployment lifecycle events to")
+	flagset.BoolVar(&p.RollbackOnSmokeFailure, "rollback-on-smoke-failure", p.RollbackOnSmokeFailure, "Roll back to the previous image when the smoke checks failed")
+	flagset.BoolVar(&p.RecordHistory, "record-history", p.RecordHistory, "Record the deployment to the history ledger committed within the manifest repository")
+	flagset.StringVar(&p.Actor, "actor", p.Actor, "Actor of the deployment, defaults to the CI actor or the local user")
+	flagset.StringVar(&p.Reason, "reason", p.Reason, "Reason of the deployment, recorded to the commit and the Argo CD Application")
+	flagset.StringVar(&p.CommitTemplate, "commit-template", p.CommitTemplate, "Go template of the deployment commit message, rendered with the deployment context")
+	flagset.StringVar(&p.CommitTemplateFile, "commit-template-file", p.CommitTemplateFile, "File containing the Go template of the deployment commit message")
+	flagset.StringVar(&p.ChangelogURL, "changelog-url", p.ChangelogURL, "Go template of the release changelog URL, rendered with the release of the commit context")
+	p.Identity.AttachFlags(flagset)
+	flagset.BoolVar(&p.CommitPerPath, "commit-per-path", p.CommitPerPath, "Commit the changes once per release path, such as per cluster, instead of once for all paths")
+	flagset.StringVar(&p.OutputFile, "output-file", p.OutputFile, "File to write the machine-readable deployment result to")
+	flagset.StringVarP(&p.Output, "output", "o", p.Output, "Write the machine-readable deployment result to stdout, the only supported format is 'json'")
+	flagset.StringVar(&p.MetricsPushgatewayURL, "metrics-pushgateway-url", p.MetricsPushgatewayURL, "Prometheus Pushgateway URL to push the deployment metrics to")
+	flagset.StringVar(&p.MetricsTextfile, "metrics-textfile", p.MetricsTextfile, "File to write the deployment metrics to, in the node exporter textfile collector format")
+	flagset.StringVar(&p.RevisionTime, "revision-time", p.RevisionTime, "Commit time of the deployed revision in RFC 3339 format to measure the lead time with, defaults to the CI commit timestamp")
+	flagset.IntVar(&p.MaxConcurrency, "max-concurrency", p.MaxConcurrency, "Maximum number of releases to be synced concurrently, 0 means unlimited")
+	p.attachSyncFlags(flagset)
+	p.attachApplicationSetFlags(flagset)
+	p.ArgoCD.AttachFlags(flagset)
+}
 
-	return nil
+// attachSyncFlags attaches the flags of the release syncs, shared by the commands deploying the releases
+func (p *parameters) attachSyncFlags(flagset *flag.FlagSet) {
+	flagset.BoolVar(&p.KeepGoing, "keep-going", p.KeepGoing, "Let every release sync run to completion regardless the other failures, instead of cancelling the rest on the first failure")
+	flagset.BoolVar(&p.AllowPartialSuccess, "allow-partial-success", p.AllowPartialSuccess, "Count the deployment as succeeded when some of the releases are synced, along with --keep-going")
+	flagset.DurationVar(&p.SyncDelay, "sync-delay", p.SyncDelay, "Delay between starting the release syncs, to spread the load on Argo CD")
+	flagset.StringVar(&p.SyncOrder, "sync-order", p.SyncOrder, "Sync the releases in waves by the label values in order, in format <label>=<value>[,<value>...], e.g. tier=canary")
+	flagset.BoolVar(&p.TerminateOnCancel, "terminate-on-cancel", p.TerminateOnCancel, "Terminate the running Argo CD sync operation when the deployment is cancelled or the sync times out")
+	flagset.BoolVar(&p.SyncPrune, "sync-prune", p.SyncPrune, "Delete the resources which are no longer part of the release manifests on sync")
+	flagset.BoolVar(&p.SyncDryRun, "sync-dry-run", p.SyncDryRun, "Sync the releases without applying the changes, the sync completes once the Argo CD operation succeeded")
+	flagset.BoolVar(&p.SyncForce, "sync-force", p.SyncForce, "Replace the resources which cannot be patched, by deleting and recreating them")
+	flagset.StringVar(&p.SyncStrategy, "sync-strategy", p.SyncStrategy, "Sync strategy, either 'apply' or 'hook', defaults to the Argo CD default")
+	flagset.StringVar(&p.SyncOptions, "sync-options", p.SyncOptions, "Comma-separated Argo CD sync options, e.g. ServerSideApply=true,ApplyOutOfSyncOnly=true")
+	flagset.StringVar(&p.SyncResources, "sync-resources", p.SyncResources, "Comma-separated resources to be synced only, in format [<group>]:<kind>:[<namespace>/]<name>, e.g. apps:Deployment:myapp")
+	flagset.Int64Var(&p.SyncRetryLimit, "sync-retry-limit", p.SyncRetryLimit, "Maximum number of retries of the failed Argo CD sync operation, 0 disables it and a negative value retries indefinitely")
+	flagset.DurationVar(&p.SyncRetryBackoff, "sync-retry-backoff", p.SyncRetryBackoff, "Backoff before retrying the failed Argo CD sync operation")
+	flagset.Int64Var(&p.SyncRetryFactor, "sync-retry-factor", p.SyncRetryFactor, "Factor to multiply the backoff by after every failed retry")
+	flagset.DurationVar(&p.SyncRetryMaxBackoff, "sync-retry-max-backoff", p.SyncRetryMaxBackoff, "Maximum backoff between the retries of the failed Argo CD sync operation")
+	flagset.StringVar(&p.OnRolloutPaused, "on-rollout-paused", p.OnRolloutPaused, "What to do once an Argo Rollout of the release is paused, either 'wait', 'accept', 'promote', or 'abort'")
+}
+
+// attachApplicationSetFlags attaches the flags of the releases generated by the ApplicationSets
+func (p *parameters) attachApplicationSetFlags(flagset *flag.FlagSet) {
+	flagset.BoolVar(&p.DiscoverAppSets, "discover-applicationsets", p.DiscoverAppSets, "Discover the releases generated by the ApplicationSets matching the selectors, along with the labeled Applications")
+	flagset.BoolVar(&p.EditGeneratorFiles, "edit-generator-files", p.EditGeneratorFiles, "Render the image to the file consumed by the git files generator of the ApplicationSet, it implies --discover-applicationsets")
+	flagset.StringVar(&p.GeneratorImageKey, "generator-image-key", p.GeneratorImageKey, "Dot-separated key of the image within the generator file, either an image reference or a mapping of name, tag, and digest")
 }
 
 func (p *parameters) ParseArgs(args []string) error {
+	if p.FromFile != "" {
+		if len(args) != 0 {
+			return errors.New("RELEASE_NAME argument is not allowed along with --from-file flag")
+		}
+
+		return nil
+	}
+
 	if len(args) != 1 {
 		return errors.New("either RELEASE_NAME argument is not provided or too many arguments")
 	}
@@ -60,6 +176,22 @@ func (p *parameters) ParseArgs(args []string) error {
 }
 
 func (p *parameters) Validate() error {
+	if err := p.validateOutput(); err != nil {
+		return err
+	}
+
+	if err := p.validateAndSetSyncFlags(); err != nil {
+		return err
+	}
+
+	if err := p.validateAndSetRevisionTime(); err != nil {
+		return err
+	}
+
+	if p.FromFile != "" {
+		return p.validateBatch()
+	}
+
 	if err := p.validateRequiredFlags(); err != nil {
 		return err
 	}
@@ -75,6 +207,14 @@ func (p *parameters) Validate() error {
 	return nil
 }
 
+func (p *parameters) validateOutput() error {
+	if p.Output != "" && p.Output != OutputJSON {
+		return fmt.Errorf("unsupported output format '%s', it should be '%s'", p.Output, OutputJSON)
+	}
+
+	return nil
+}
+
 func (p *parameters) validateRequiredFlags() error {
 	if p.Image == "" {
 		return errors.New("image is required. Please set --image flag")
@@ -84,12 +224,12 @@ func (p *parameters) validateRequiredFlags() error {
 		return errors.New("environment is required. Please set --environment flag")
 	}
 
-	if p.ArgoCDHost == "" {
-		return errors.New("ArgoCD Host is required. Please set ARGOCD_HOST environment variable")
-	}
+	return p.validateRequiredSecrets()
+}
 
-	if p.ArgoCDAuthToken == "" {
-		return errors.New("ArgoCD Auth Token is required. Please set ARGOCD_AUTH_TOKEN environment variable")
+func (p *parameters) validateRequiredSecrets() error {
+	if err := p.ArgoCD.Validate(); err != nil {
+		return err
 	}
 
 	if p.GitSecret == "" {
@@ -99,19 +239,149 @@ func (p *parameters) validateRequiredFlags() error {
 	return nil
 }
 
+func (p *parameters) validateBatch() error {
+	if p.Image != "" || p.Environment != "" || p.Cluster != "" {
+		return errors.New("--image, --environment, and --cluster flags are not allowed along with --from-file flag")
+	}
+
+	if err := p.validateRequiredSecrets(); err != nil {
+		return err
+	}
+
+	if err := p.validateAndSetGitSecret(); err != nil {
+		return err
+	}
+
+	targets, err := loadBatchFile(p.FromFile)
+	if err != nil {
+		return err
+	}
+
+	p.batchTargets = targets
+	return nil
+}
+
 func (p *parameters) validateAndSetImageDefinition() error {
-	parts := strings.Split(p.Image, ":")
+	image, err := parseImageDefinition(p.Image)
+	if err != nil {
+		return err
+	}
+
+	p.imageDefinition = image
+	return nil
+}
+
+func parseImageDefinition(s string) (types.ImageDefinition, error) {
+	image, digest, _ := strings.Cut(s, "@")
+
+	parts := strings.Split(image, ":")
 	if len(parts) == 1 {
-		p.imageDefinition = types.ImageDefinition{Name: parts[0], Tag: "latest"}
-		return nil
+		return types.ImageDefinition{Name: parts[0], Tag: "latest", Digest: digest}, nil
 	}
 
 	if len(parts) == 2 {
-		p.imageDefinition = types.ImageDefinition{Name: parts[0], Tag: parts[1]}
+		return types.ImageDefinition{Name: parts[0], Tag: parts[1], Digest: digest}, nil
+	}
+
+	return types.ImageDefinition{}, errors.New("invalid image format, it should be in format <image-name>:<tag>[@<digest>]")
+}
+
+func (p *parameters) validateAndSetRevisionTime() error {
+	if p.RevisionTime == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, p.RevisionTime)
+	if err != nil {
+		return fmt.Errorf("invalid --revision-time, it should be in RFC 3339 format: %w", err)
+	}
+
+	p.revisionTime = t
+	return nil
+}
+
+func (p *parameters) validateAndSetSyncFlags() error {
+	if p.AllowPartialSuccess && !p.KeepGoing {
+		return errors.New("--allow-partial-success is only allowed along with --keep-going flag")
+	}
+
+	switch p.SyncStrategy {
+	case "", manager.SyncStrategyApply, manager.SyncStrategyHook:
+	default:
+		return fmt.Errorf("invalid sync strategy '%s', it should be either '%s' or '%s'", p.SyncStrategy, manager.SyncStrategyApply, manager.SyncStrategyHook)
+	}
+
+	p.syncOptions = nil
+	for _, option := range splitList(p.SyncOptions) {
+		if key, value, ok := strings.Cut(option, "="); !ok || key == "" || value == "" {
+			return fmt.Errorf("invalid sync option '%s', it should be in format <key>=<value>", option)
+		}
+
+		p.syncOptions = append(p.syncOptions, option)
+	}
+
+	p.syncResources = nil
+	for _, value := range splitList(p.SyncResources) {
+		res, err := parseSyncResource(value)
+		if err != nil {
+			return err
+		}
+
+		p.syncResources = append(p.syncResources, res)
+	}
+
+	switch p.OnRolloutPaused {
+	case manager.RolloutPausedWait, manager.RolloutPausedAccept, manager.RolloutPausedPromote, manager.RolloutPausedAbort:
+	default:
+		return fmt.Errorf("invalid rollout paused policy '%s', it should be either 'wait', 'accept', 'promote', or 'abort'", p.OnRolloutPaused)
+	}
+
+	if p.SyncRetryBackoff < 0 || p.SyncRetryMaxBackoff < 0 || p.SyncRetryFactor < 0 {
+		return errors.New("sync retry backoff and factor must not be negative")
+	}
+
+	if p.SyncOrder == "" {
 		return nil
 	}
 
-	return errors.New("invalid image format, it should be in format <image-name>:<tag>")
+	label, values, ok := strings.Cut(p.SyncOrder, "=")
+	if !ok || label == "" || values == "" {
+		return errors.New("invalid sync order format, it should be in format <label>=<value>[,<value>...]")
+	}
+
+	p.syncOrderLabel, p.syncOrderValues = label, strings.Split(values, ",")
+	return nil
+}
+
+// parseSyncResource parses the resource in format [<group>]:<kind>:[<namespace>/]<name>, as the Argo CD CLI does
+func parseSyncResource(value string) (manager.SyncResource, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+		return manager.SyncResource{}, fmt.Errorf("invalid sync resource '%s', it should be in format [<group>]:<kind>:[<namespace>/]<name>", value)
+	}
+
+	res := manager.SyncResource{Group: parts[0], Kind: parts[1], Name: parts[2]}
+	if namespace, name, ok := strings.Cut(parts[2], "/"); ok {
+		if name == "" {
+			return manager.SyncResource{}, fmt.Errorf("invalid sync resource '%s', the name is missing", value)
+		}
+
+		res.Namespace, res.Name = namespace, name
+	}
+
+	return res, nil
+}
+
+// splitList splits the comma-separated list, the empty items are left out
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
 }
 
 func (p *parameters) validateAndSetGitSecret() error {
@@ -131,6 +401,10 @@ func (p *parameters) GetImageDefinition() types.ImageDefinition {
 	return p.imageDefinition
 }
 
+func (p *parameters) GetBatchTargets() []target {
+	return p.batchTargets
+}
+
 func markFlagsAsRequired(flagset *flag.FlagSet, flags ...string) error {
 	for _, name := range flags {
 		if err := cobra.MarkFlagRequired(flagset, name); err != nil {
@@ -140,3 +414,37 @@ func markFlagsAsRequired(flagset *flag.FlagSet, flags ...string) error {
 
 	return nil
 }
+
+func (p *parameters) GetSyncOrder() (string, []string) {
+	return p.syncOrderLabel, p.syncOrderValues
+}
+
+func (p *parameters) GetSyncOptions() []string {
+	return p.syncOptions
+}
+
+func (p *parameters) GetSyncResources() []manager.SyncResource {
+	return p.syncResources
+}
+
+// listOptions returns the options of looking up the releases
+func (p *parameters) listOptions(log logr.Logger) []manager.Option {
+	return []manager.Option{
+		manager.WithLogger(log),
+		manager.WithApplicationSets(p.DiscoverAppSets || p.EditGeneratorFiles),
+	}
+}
+
+// GetRevisionTime returns the commit time of the deployed revision, zero when it isn't set
+func (p *parameters) GetRevisionTime() time.Time {
+	return p.revisionTime
+}
+
+func (p *parameters) GetSyncRetry() manager.SyncRetry {
+	return manager.SyncRetry{
+		Limit:         p.SyncRetryLimit,
+		Backoff:       p.SyncRetryBackoff,
+		BackoffFactor: p.SyncRetryFactor,
+		MaxBackoff:    p.SyncRetryMaxBackoff,
+	}
+}
@@ -0,0 +1,224 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/ardikabs/dpl/internal/cli/global"
+	"github.com/ardikabs/dpl/internal/errs"
+	"github.com/ardikabs/dpl/internal/git"
+	"github.com/ardikabs/dpl/internal/log"
+	"github.com/ardikabs/dpl/internal/manager"
+	"github.com/ardikabs/dpl/internal/renderer"
+	"github.com/ardikabs/dpl/internal/result"
+	"github.com/ardikabs/dpl/internal/types"
+	"github.com/joeshaw/envdecode"
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+)
+
+var (
+	ErrPromoteSourceNotReady      = errors.New("source environment is not synced and healthy")
+	ErrPromoteSourceImageMismatch = errors.New("source environment releases are rendered with different images")
+)
+
+type promoteParameters struct {
+	*parameters
+
+	FromEnvironment string
+	FromCluster     string
+}
+
+func (p *promoteParameters) Attach(flagset *flag.FlagSet) error {
+	if err := envdecode.Decode(p.parameters); err != nil {
+		return err
+	}
+
+	flagset.StringVar(&p.FromEnvironment, "from", p.FromEnvironment, "Source environment to promote the release from")
+	flagset.StringVar(&p.Environment, "to", p.Environment, "Target environment to promote the release to")
+	flagset.StringVar(&p.FromCluster, "from-cluster", p.FromCluster, "Source cluster to promote the release from")
+	flagset.StringVarP(&p.Cluster, "cluster", "c", p.Cluster, "Target cluster to promote the release to")
+	p.attachDeploymentFlags(flagset)
+
+	return nil
+}
+
+func (p *promoteParameters) Validate() error {
+	if err := p.validateOutput(); err != nil {
+		return err
+	}
+
+	if p.FromEnvironment == "" {
+		return errors.New("source environment is required. Please set --from flag")
+	}
+
+	if p.Environment == "" {
+		return errors.New("target environment is required. Please set --to flag")
+	}
+
+	if p.FromEnvironment == p.Environment && p.FromCluster == p.Cluster {
+		return errors.New("source and target environment must be different")
+	}
+
+	if err := p.validateRequiredSecrets(); err != nil {
+		return err
+	}
+
+	if err := p.validateAndSetSyncFlags(); err != nil {
+		return err
+	}
+
+	if err := p.validateAndSetRevisionTime(); err != nil {
+		return err
+	}
+
+	return p.validateAndSetGitSecret()
+}
+
+func NewPromoteCommand() *cobra.Command {
+	params := &promoteParameters{parameters: new(parameters)}
+
+	cmd := &cobra.Command{
+		Use:   "promote --from <SOURCE_ENVIRONMENT> --to <TARGET_ENVIRONMENT> RELEASE_NAME",
+		Short: "promote the release image from an environment to another environment",
+		Long: `Promote the release image from an environment to another environment.
+
+This command reads the image (and digest) currently rendered for the release on the source environment,
+using the same selectors as the 'exec' command, then executes the deployment runner against the target environment.
+It refuses to promote when any release on the source environment is not Synced and Healthy,
+or when the releases on the source environment are rendered with different images.
+`,
+		Example: `
+# promote release named myapp from staging to production
+$ dpl promote --from staging --to production myapp`,
+	}
+
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		log.SetLevel(global.GetLogLevel())
+
+		if err := configureOutput(params.parameters); err != nil {
+			return reportFailure(params.parameters, err)
+		}
+
+		if err := params.ParseArgs(args); err != nil {
+			return reportFailure(params.parameters, result.WithCategory(result.CategoryValidation, err))
+		}
+
+		if err := params.Validate(); err != nil {
+			return reportFailure(params.parameters, result.WithCategory(result.CategoryValidation, err))
+		}
+
+		instance, err := newExecInstance(log.Logger, params.parameters)
+		if err != nil {
+			return reportFailure(params.parameters, err)
+		}
+		defer instance.Manager.Close()
+
+		return promote(cmd.Context(), instance, params)
+	}
+
+	if err := params.Attach(cmd.Flags()); err != nil {
+		log.Error(err, "failed to attach command flags")
+		os.Exit(1)
+	}
+
+	return cmd
+}
+
+func promote(ctx context.Context, ins *execInstance, params *promoteParameters) error {
+	log := ins.Logger.
+		WithName("promote").
+		WithValues(
+			"release", params.ReleaseName,
+			"from", params.FromEnvironment,
+			"to", params.Environment,
+		)
+
+	image, err := resolveSourceImage(ctx, ins, params)
+	if err != nil {
+		return reportFailure(params.parameters, result.WithCategory(result.CategoryValidation, err))
+	}
+
+	log.Info("promoting image from source environment", "image", image.String())
+
+	params.Image = image.String()
+	params.imageDefinition = image
+
+	ins.Provenance = "Promoted-From: " + params.FromEnvironment
+	if params.FromCluster != "" {
+		ins.Provenance += "/" + params.FromCluster
+	}
+
+	return ins.Exec(ctx)
+}
+
+// resolveSourceImage reads the image rendered for every release on the source environment,
+// and ensures they are ready and rendered with the same image.
+func resolveSourceImage(ctx context.Context, ins *execInstance, params *promoteParameters) (types.ImageDefinition, error) {
+	log := ins.Logger.WithName("promote.resolveSourceImage").WithValues(
+		"release", params.ReleaseName,
+		"environment", params.FromEnvironment,
+	)
+
+	req, err := manager.NewListReleaseRequestBuilder().
+		SetReleaseSelector(params.SelectorForRelease, params.ReleaseName).
+		SetEnvironmentSelector(params.SelectorForEnvironment, params.FromEnvironment).
+		SetClusterSelector(params.SelectorForCluster, params.FromCluster).
+		Build()
+	if err != nil {
+		return types.ImageDefinition{}, err
+	}
+
+	releases, err := ins.Manager.ListReleases(ctx, req, params.listOptions(log)...)
+	if err != nil {
+		return types.ImageDefinition{}, err
+	}
+
+	for _, rel := range releases {
+		if !rel.Status.IsReady() {
+			return types.ImageDefinition{}, errs.Wrapf(ErrPromoteSourceNotReady,
+				"application %s is %s and %s", rel.ID, rel.Status.Sync, rel.Status.Health)
+		}
+	}
+
+	workspace, err := os.MkdirTemp("/tmp", "dpl-*")
+	if err != nil {
+		return types.ImageDefinition{}, err
+	}
+	defer os.RemoveAll(workspace)
+
+	repo, err := ins.Git.Clone(ctx, releases.GetGitURL(), workspace,
+		git.WithCloneBranch(releases.GetGitRevision()),
+		git.WithCloneLogger(log),
+	)
+	if err != nil {
+		return types.ImageDefinition{}, err
+	}
+
+	if err := params.resolveGeneratorFiles(repo.Root(), log, releases); err != nil {
+		return types.ImageDefinition{}, err
+	}
+
+	var image types.ImageDefinition
+	for _, rel := range releases {
+		log := log.WithValues("id", rel.ID, "cluster", rel.Cluster, "gitPath", rel.GitPath)
+
+		r, workdir, rendererParams := params.releaseRenderer(ins.Renderer, repo.Root(), rel)
+		current, err := r.Inspect(workdir, params.ReleaseName, rendererParams, renderer.WithLogger(log))
+		if err != nil {
+			return types.ImageDefinition{}, err
+		}
+
+		if image != (types.ImageDefinition{}) && image != current {
+			return types.ImageDefinition{}, errs.Wrapf(ErrPromoteSourceImageMismatch,
+				"found %s and %s", image.String(), current.String())
+		}
+
+		image = current
+	}
+
+	return image, nil
+}
@@ -0,0 +1,160 @@
+package exec
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ardikabs/dpl/internal/git"
+	"github.com/ardikabs/dpl/internal/renderer"
+	"github.com/ardikabs/dpl/internal/types"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGit clones an empty repository to the destination, whose commits and pushes are recorded
+type fakeGit struct {
+	repo *fakeRepository
+}
+
+func (g *fakeGit) Clone(_ context.Context, _, dest string, _ ...git.CloneOption) (git.Repository, error) {
+	if g.repo == nil {
+		g.repo = &fakeRepository{}
+	}
+
+	g.repo.root = dest
+	return g.repo, nil
+}
+
+type fakeRepository struct {
+	root string
+
+	mu      sync.Mutex
+	commits []*git.CommitOptions
+	pushes  int
+}
+
+func (r *fakeRepository) Root() string { return r.root }
+
+func (r *fakeRepository) Head() (string, error) { return "b6d7153", nil }
+
+func (r *fakeRepository) Pull(context.Context, ...git.PullOption) error { return nil }
+
+func (r *fakeRepository) Commit(_ context.Context, opts ...git.CommitOption) error {
+	o := git.NewDefaultCommitOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.commits = append(r.commits, o)
+	return nil
+}
+
+func (r *fakeRepository) Push(context.Context, ...git.PushOption) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pushes++
+	return nil
+}
+
+// fakeRenderer keeps the image rendered per release path, the base name of the working directory,
+// along with the paths rendered with the restart annotation.
+type fakeRenderer struct {
+	mu        sync.Mutex
+	images    map[string]types.ImageDefinition
+	rendered  []string
+	restarted []string
+}
+
+func (r *fakeRenderer) Render(workdir string, _ string, params interface{}, opts ...renderer.RenderOption) error {
+	p := params.(*renderer.KustomizeParams)
+	image := types.ImageDefinition{Name: p.ImageName, Tag: p.ImageTag, Digest: p.ImageDigest}
+
+	o := new(renderer.RenderOptions)
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := o.ExternalAnnotations["dpl/restartedAt"]; ok {
+		r.restarted = append(r.restarted, filepath.Base(workdir))
+	}
+
+	if r.images == nil {
+		r.images = make(map[string]types.ImageDefinition)
+	}
+
+	r.images[filepath.Base(workdir)] = image
+	r.rendered = append(r.rendered, filepath.Base(workdir)+"="+image.String())
+	return nil
+}
+
+func (r *fakeRenderer) Inspect(workdir string, _ string, _ interface{}, _ ...renderer.RenderOption) (types.ImageDefinition, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.images[filepath.Base(workdir)], nil
+}
+
+func TestResolveSourceImage(t *testing.T) {
+	ready := types.ReleaseStatus{Sync: types.SyncStatusSynced, Health: types.HealthStatusHealthy}
+	image := types.ImageDefinition{Name: "ghcr.io/ardikabs/app/myapp", Tag: "b6d7153"}
+
+	newInstance := func(images map[string]types.ImageDefinition, releases ...*types.Release) *execInstance {
+		return &execInstance{
+			Git:      &fakeGit{},
+			Manager:  &fakeManager{releases: releases},
+			Renderer: &fakeRenderer{images: images},
+			Logger:   logr.Discard(),
+			Params:   &parameters{},
+		}
+	}
+
+	params := func(ins *execInstance) *promoteParameters {
+		return &promoteParameters{parameters: ins.Params, FromEnvironment: "staging"}
+	}
+
+	t.Run("source is not ready", func(t *testing.T) {
+		ins := newInstance(map[string]types.ImageDefinition{"staging-1": image},
+			&types.Release{ID: "myapp-staging-1", Environment: "staging", GitPath: "staging-1", Status: types.ReleaseStatus{Sync: "OutOfSync", Health: types.HealthStatusHealthy}},
+		)
+
+		_, err := resolveSourceImage(context.Background(), ins, params(ins))
+		require.ErrorIs(t, err, ErrPromoteSourceNotReady)
+		require.ErrorContains(t, err, "application myapp-staging-1 is OutOfSync and Healthy")
+	})
+
+	t.Run("source images are mismatched", func(t *testing.T) {
+		ins := newInstance(map[string]types.ImageDefinition{
+			"staging-1": image,
+			"staging-2": {Name: "ghcr.io/ardikabs/app/myapp", Tag: "a1c2e3f"},
+		},
+			&types.Release{ID: "myapp-staging-1", Environment: "staging", GitPath: "staging-1", Status: ready},
+			&types.Release{ID: "myapp-staging-2", Environment: "staging", GitPath: "staging-2", Status: ready},
+		)
+
+		_, err := resolveSourceImage(context.Background(), ins, params(ins))
+		require.ErrorIs(t, err, ErrPromoteSourceImageMismatch)
+		require.ErrorContains(t, err, "found ghcr.io/ardikabs/app/myapp:b6d7153 and ghcr.io/ardikabs/app/myapp:a1c2e3f")
+	})
+
+	t.Run("digest is kept", func(t *testing.T) {
+		pinned := types.ImageDefinition{Name: image.Name, Tag: image.Tag, Digest: "sha256:7d2c1e9f"}
+		ins := newInstance(map[string]types.ImageDefinition{"staging-1": pinned, "staging-2": pinned},
+			&types.Release{ID: "myapp-staging-1", Environment: "staging", GitPath: "staging-1", Status: ready},
+			&types.Release{ID: "myapp-staging-2", Environment: "staging", GitPath: "staging-2", Status: ready},
+			&types.Release{ID: "myapp-production", Environment: "production", GitPath: "production"},
+		)
+
+		got, err := resolveSourceImage(context.Background(), ins, params(ins))
+		require.NoError(t, err)
+		require.Equal(t, pinned, got)
+	})
+}
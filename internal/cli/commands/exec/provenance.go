@@ -0,0 +1,134 @@
+package exec
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/ci"
+	"github.com/ardikabs/dpl/internal/manager"
+	"github.com/ardikabs/dpl/internal/types"
+	"github.com/go-logr/logr"
+)
+
+// the annotations set on the Argo CD Application describing the last deployment
+const (
+	AnnotationDeployedBy  = "platform.ardikabs.com/deployed-by"
+	AnnotationPipelineURL = "platform.ardikabs.com/pipeline-url"
+	AnnotationReason      = "platform.ardikabs.com/deploy-reason"
+	AnnotationRequestID   = "platform.ardikabs.com/request-id"
+	AnnotationImage       = "platform.ardikabs.com/image"
+	AnnotationCommitSHA   = "platform.ardikabs.com/commit-sha"
+	AnnotationDeployedAt  = "platform.ardikabs.com/deployed-at"
+)
+
+// deployer describes who executes the deployment, where and why
+type deployer struct {
+	Actor  string
+	Reason string
+	CI     ci.Context
+}
+
+// newDeployer resolves the actor from the explicit actor, then the CI context, then the local user
+func (ins *execInstance) newDeployer() deployer {
+	d := deployer{
+		Actor:  ins.Params.Actor,
+		Reason: ins.Params.Reason,
+	}
+
+	if ins.IgnoreCI {
+		return d
+	}
+
+	d.CI = ci.Detect()
+	if d.Actor == "" {
+		d.Actor = d.CI.Actor
+	}
+
+	if d.Actor == "" {
+		d.Actor = os.Getenv("USER")
+	}
+
+	return d
+}
+
+// trailers returns the git trailers of the deployment commit,
+// the release, environment and image are listed once per unique value.
+func (d deployer) trailers(releases types.ListReleases) []string {
+	var trailers []string
+	if d.Actor != "" {
+		trailers = append(trailers, "Deployed-By: "+d.Actor)
+	}
+
+	if d.CI.PipelineURL != "" {
+		trailers = append(trailers, "Pipeline-URL: "+d.CI.PipelineURL)
+	}
+
+	if d.Reason != "" {
+		trailers = append(trailers, "Reason: "+oneLine(d.Reason))
+	}
+
+	seen := make(map[string]bool)
+	for _, rel := range releases {
+		for _, trailer := range []string{
+			"Release: " + rel.Name,
+			"Environment: " + rel.Environment,
+			"Image: " + rel.Image.String(),
+		} {
+			if strings.HasSuffix(trailer, ": ") || seen[trailer] {
+				continue
+			}
+
+			seen[trailer] = true
+			trailers = append(trailers, trailer)
+		}
+	}
+
+	return trailers
+}
+
+// annotations returns the Argo CD Application annotations of the release,
+// the empty value removes the annotation left by the previous deployment.
+func (d deployer) annotations(reqID, commitSHA string, rel *types.Release) map[string]string {
+	return map[string]string{
+		AnnotationDeployedBy:  d.Actor,
+		AnnotationPipelineURL: d.CI.PipelineURL,
+		AnnotationReason:      oneLine(d.Reason),
+		AnnotationRequestID:   reqID,
+		AnnotationImage:       rel.Image.String(),
+		AnnotationCommitSHA:   commitSHA,
+		AnnotationDeployedAt:  time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// annotate records the deployer on the releases without failing the deployment
+func (ins *execInstance) annotate(ctx context.Context, log logr.Logger, reqID, commitSHA string, d deployer, releases types.ListReleases) {
+	for _, rel := range releases {
+		if err := ins.Manager.AnnotateRelease(ctx, rel, d.annotations(reqID, commitSHA, rel), manager.WithLogger(log)); err != nil {
+			log.Error(err, "failed to annotate release", "id", rel.ID, "cluster", rel.Cluster)
+		}
+	}
+}
+
+// revisionTime returns the commit time of the deployed revision, from either the parameter or the CI context,
+// nil when it is unknown.
+func (ins *execInstance) revisionTime() *time.Time {
+	if t := ins.Params.GetRevisionTime(); !t.IsZero() {
+		return &t
+	}
+
+	if ins.IgnoreCI {
+		return nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, ci.Detect().RevisionTime); err == nil {
+		return &t
+	}
+
+	return nil
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,283 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/cli/global"
+	"github.com/ardikabs/dpl/internal/history"
+	"github.com/ardikabs/dpl/internal/log"
+	"github.com/ardikabs/dpl/internal/result"
+	"github.com/ardikabs/dpl/internal/server"
+	"github.com/ardikabs/dpl/internal/webhook"
+	"github.com/joeshaw/envdecode"
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+)
+
+// DefaultServerActor is the actor of the deployments requested to the server without any actor
+const DefaultServerActor = "api"
+
+type serveParameters struct {
+	*parameters
+
+	Port      uint   `env:"DPL_RUNNER_PORT,default=10080"`
+	Token     string `env:"DPL_SERVER_TOKEN"`
+	Workers   uint   `env:"DPL_SERVER_WORKERS,default=4"`
+	QueueSize uint   `env:"DPL_SERVER_QUEUE_SIZE,default=100"`
+
+	WebhooksFile string `env:"DPL_WEBHOOKS_FILE"`
+	HistoryDB    string `env:"DPL_HISTORY_DB"`
+}
+
+func (p *serveParameters) Attach(flagset *flag.FlagSet) error {
+	if err := envdecode.Decode(p.parameters); err != nil {
+		return err
+	}
+
+	if err := envdecode.Decode(p); err != nil {
+		return err
+	}
+
+	flagset.UintVar(&p.Port, "runner-port", p.Port, "Port the server listens on")
+	flagset.UintVar(&p.Workers, "workers", p.Workers, "Maximum number of deployments executed concurrently")
+	flagset.UintVar(&p.QueueSize, "queue-size", p.QueueSize, "Maximum number of deployments waiting to be executed, beyond it the deployments are rejected")
+	flagset.StringVar(&p.WebhooksFile, "webhooks-file", p.WebhooksFile, "Registry webhooks configuration file to deploy the pushed images automatically")
+	flagset.StringVar(&p.HistoryDB, "history-db", p.HistoryDB, "Local database file to record the deployment history to, it is served through 'GET /v1/history'")
+	flagset.StringVar(&p.Profile, "profile", p.Profile, "Selected profile for deployment")
+	flagset.StringVar(&p.KustomizationFileRef, "kustomize-file-ref", p.KustomizationFileRef, "Kustomization file reference")
+	flagset.StringVar(&p.KustomizationImageRef, "kustomize-image-ref", p.KustomizationImageRef, "Kustomization image reference name")
+	flagset.StringVar(&p.SelectorForRelease, "selector-for-release", p.SelectorForRelease, "Selector for 'release' attribute")
+	flagset.StringVar(&p.SelectorForEnvironment, "selector-for-environment", p.SelectorForEnvironment, "Selector for 'environment' attribute")
+	flagset.StringVar(&p.SelectorForCluster, "selector-for-cluster", p.SelectorForCluster, "Selector for 'cluster' attribute")
+	flagset.StringVar(&p.HooksFile, "hooks-file", p.HooksFile, "Hooks configuration file executed at the deployment lifecycle stages")
+	flagset.StringVar(&p.SmokeChecksFile, "smoke-checks-file", p.SmokeChecksFile, "Smoke checks configuration file executed after the releases are synced")
+	flagset.StringVar(&p.NotifiersFile, "notifiers-file", p.NotifiersFile, "Notifiers configuration file to send the deployment lifecycle events to")
+	flagset.BoolVar(&p.RollbackOnSmokeFailure, "rollback-on-smoke-failure", p.RollbackOnSmokeFailure, "Roll back to the previous image when the smoke checks failed, for every deployment")
+	flagset.IntVar(&p.MaxConcurrency, "max-concurrency", p.MaxConcurrency, "Maximum number of releases to be synced concurrently per deployment, 0 means unlimited")
+	p.attachSyncFlags(flagset)
+	p.attachApplicationSetFlags(flagset)
+	p.ArgoCD.AttachFlags(flagset)
+
+	return nil
+}
+
+func (p *serveParameters) Validate() error {
+	if p.Token == "" {
+		return errors.New("server token is required. Please set DPL_SERVER_TOKEN environment variable")
+	}
+
+	if err := p.validateRequiredSecrets(); err != nil {
+		return err
+	}
+
+	if err := p.validateAndSetSyncFlags(); err != nil {
+		return err
+	}
+
+	return p.validateAndSetGitSecret()
+}
+
+func NewServeCommand() *cobra.Command {
+	params := &serveParameters{parameters: new(parameters)}
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "run a long-running deployment server with an HTTP API",
+		Long: `Run a long-running deployment server with an HTTP API.
+
+The server accepts the same parameters as the exec command through 'POST /v1/deployments',
+then the deployments are queued and executed by a bounded pool of workers.
+Each deployment is identified by its request ID, its status, result, and logs are available through 'GET /v1/deployments/{id}',
+while the progress is streamed as Server-Sent Events through 'GET /v1/deployments/{id}/events'.
+
+The deployment endpoints require the bearer token set on the DPL_SERVER_TOKEN environment variable.
+
+When the '--webhooks-file' flag is set, the registry webhooks are accepted through 'POST /v1/webhooks/{source}',
+where the source is either 'dockerhub', 'github', 'harbor', or 'cloudevents',
+then every rule matching the pushed image submits a deployment as if it is requested through the API.
+`,
+		Example: `
+# run the server
+$ DPL_SERVER_TOKEN=s3cr3t dpl serve --runner-port 10080
+
+# deploy the release named myapp to the staging environment
+$ curl -H "Authorization: Bearer s3cr3t" -d '{"release":"myapp","environment":"staging","image":"ghcr.io/ardikabs/app/myapp:b6d7153"}' http://localhost:10080/v1/deployments
+
+# follow the deployment progress
+$ curl -N -H "Authorization: Bearer s3cr3t" http://localhost:10080/v1/deployments/<ID>/events`,
+	}
+
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		log.SetLevel(global.GetLogLevel())
+
+		if err := params.Validate(); err != nil {
+			return result.WithCategory(result.CategoryValidation, err)
+		}
+
+		runner := &serveRunner{params: params.parameters}
+		opts := []server.Option{
+			server.WithLogger(log.Logger),
+			server.WithPort(params.Port),
+			server.WithToken(params.Token),
+			server.WithWorkers(params.Workers),
+			server.WithQueueSize(params.QueueSize),
+		}
+
+		if params.HistoryDB != "" {
+			store, err := history.OpenBoltStore(params.HistoryDB)
+			if err != nil {
+				return err
+			}
+			defer store.Close()
+
+			runner.history = store
+			opts = append(opts, server.WithHistory(store))
+		}
+
+		srv, err := server.New(runner, opts...)
+		if err != nil {
+			return err
+		}
+
+		if params.WebhooksFile != "" {
+			receiver, err := webhook.Load(params.WebhooksFile, srv, log.Logger)
+			if err != nil {
+				return result.WithCategory(result.CategoryValidation, err)
+			}
+
+			srv.Handle("POST /v1/webhooks/{source}", receiver)
+		}
+
+		return srv.Run(cmd.Context())
+	}
+
+	if err := params.Attach(cmd.Flags()); err != nil {
+		log.Error(err, "failed to attach command flags")
+		os.Exit(1)
+	}
+
+	return cmd
+}
+
+// serveRunner executes the deployments submitted to the server as the exec command does,
+// the request parameters override the server parameters.
+type serveRunner struct {
+	params  *parameters
+	history history.Store
+}
+
+func (r *serveRunner) Validate(req server.Request) error {
+	_, err := r.newParameters(req)
+	return err
+}
+
+func (r *serveRunner) Run(ctx context.Context, d *server.Deployment) error {
+	params, err := r.newParameters(d.Request)
+	if err != nil {
+		return err
+	}
+
+	ins, err := newExecInstance(d.Logger(), params)
+	if err != nil {
+		return err
+	}
+	defer ins.Manager.Close()
+
+	ins.RequestID = d.ID
+	ins.ObserveMetrics = true
+	ins.IgnoreCI = true
+	ins.History = r.history
+	ins.Notifier = ins.Notifier.With(d)
+	ins.Summary = d
+	defer func() {
+		d.SetResult(ins.Result)
+	}()
+
+	if len(params.batchTargets) > 0 {
+		return ins.ExecBatch(ctx)
+	}
+
+	return ins.Exec(ctx)
+}
+
+func (r *serveRunner) newParameters(req server.Request) (*parameters, error) {
+	p := *r.params
+
+	// the result is kept by the server, instead of being written per deployment
+	p.Output, p.OutputFile = "", ""
+
+	// the metrics are exposed through '/metrics', instead of being pushed or written per deployment
+	p.MetricsPushgatewayURL, p.MetricsTextfile = "", ""
+
+	// the rest of the exec-only parameters aren't exposed by the serve command, they're taken from the request only
+	p.FromFile, p.batchTargets = "", nil
+	p.RevisionTime, p.revisionTime = "", time.Time{}
+	p.IsTriggerRestart = req.Restart
+
+	p.ReleaseName, p.Environment, p.Cluster, p.Image = req.Release, req.Environment, req.Cluster, req.Image
+	p.RollbackOnSmokeFailure = p.RollbackOnSmokeFailure || req.RollbackOnSmokeFailure
+
+	if req.Profile != "" {
+		p.Profile = req.Profile
+	}
+
+	// the actor is taken from the request, instead of the environment of the server
+	p.Actor = req.Actor
+	if p.Actor == "" {
+		p.Actor = DefaultServerActor
+	}
+
+	if req.Reason != "" {
+		p.Reason = req.Reason
+	}
+
+	if req.KustomizationFileRef != "" {
+		p.KustomizationFileRef = req.KustomizationFileRef
+	}
+
+	if req.KustomizationImageRef != "" {
+		p.KustomizationImageRef = req.KustomizationImageRef
+	}
+
+	if len(req.Deployments) > 0 {
+		if req.Release != "" || req.Cluster != "" {
+			return nil, errors.New("release and cluster are not allowed along with deployments")
+		}
+
+		f := batchFile{Environment: req.Environment, Image: req.Image}
+		for _, d := range req.Deployments {
+			f.Deployments = append(f.Deployments, batchEntry(d))
+		}
+
+		targets, err := f.targets()
+		if err != nil {
+			return nil, err
+		}
+
+		p.batchTargets = targets
+		return &p, nil
+	}
+
+	if req.Release == "" {
+		return nil, errors.New("either release or deployments is required")
+	}
+
+	if req.Image == "" {
+		return nil, errors.New("image is required")
+	}
+
+	if req.Environment == "" {
+		return nil, errors.New("environment is required")
+	}
+
+	if err := p.validateAndSetImageDefinition(); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
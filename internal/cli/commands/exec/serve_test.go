@@ -0,0 +1,64 @@
+package exec
+
+import (
+	"testing"
+
+	"github.com/ardikabs/dpl/internal/server"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeRunner_NewParameters(t *testing.T) {
+	r := &serveRunner{params: &parameters{
+		Profile:          "kustomize",
+		IsTriggerRestart: true,
+		FromFile:         "deployments.yaml",
+		MetricsTextfile:  "dpl.prom",
+		RevisionTime:     "2024-05-01T10:00:00Z",
+	}}
+
+	req := server.Request{Release: "myapp", Environment: "staging", Image: "ghcr.io/ardikabs/app/myapp:b6d7153"}
+
+	t.Run("exec-only parameters are not inherited", func(t *testing.T) {
+		p, err := r.newParameters(req)
+		require.NoError(t, err)
+		require.Equal(t, "kustomize", p.Profile)
+		require.False(t, p.IsTriggerRestart)
+		require.Empty(t, p.FromFile)
+		require.Empty(t, p.MetricsTextfile)
+		require.Empty(t, p.RevisionTime)
+	})
+
+	t.Run("actor defaults to the API instead of the server environment", func(t *testing.T) {
+		t.Setenv("GITHUB_ACTIONS", "true")
+		t.Setenv("GITHUB_ACTOR", "ardikabs")
+		t.Setenv("GITHUB_SERVER_URL", "https://github.com")
+		t.Setenv("GITHUB_REPOSITORY", "ardikabs/dpl")
+		t.Setenv("GITHUB_RUN_ID", "42")
+		t.Setenv("USER", "root")
+
+		r := &serveRunner{params: &parameters{Actor: "server"}}
+
+		p, err := r.newParameters(req)
+		require.NoError(t, err)
+
+		ins := &execInstance{Params: p, IgnoreCI: true}
+		require.Equal(t, deployer{Actor: DefaultServerActor}, ins.newDeployer())
+		require.Nil(t, ins.revisionTime())
+
+		req := req
+		req.Actor = "webhook/github"
+
+		p, err = r.newParameters(req)
+		require.NoError(t, err)
+		require.Equal(t, "webhook/github", p.Actor)
+	})
+
+	t.Run("restart is requested", func(t *testing.T) {
+		req := req
+		req.Restart = true
+
+		p, err := r.newParameters(req)
+		require.NoError(t, err)
+		require.True(t, p.IsTriggerRestart)
+	})
+}
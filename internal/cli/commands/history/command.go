@@ -0,0 +1,74 @@
+package history
+
+import (
+	"os"
+
+	"github.com/ardikabs/dpl/internal/cli/global"
+	"github.com/ardikabs/dpl/internal/log"
+	"github.com/ardikabs/dpl/internal/result"
+	"github.com/spf13/cobra"
+)
+
+func NewCommand() *cobra.Command {
+	params := new(parameters)
+
+	cmd := &cobra.Command{
+		Use:   "history RELEASE_NAME",
+		Short: "show the deployment history of given release",
+		Long: `Show the deployment history of given release.
+
+The history is read from the ledger committed within the manifest repository under '.dpl/history/',
+which is recorded by the exec command with the '--record-history' flag.
+By default, the manifest repository is looked up from ArgoCD, as the exec command does,
+alternatively it can be set directly using the '--repo-url' and '--revision' flags.
+
+When the deployments are executed by the server with the '--history-db' flag,
+the history is read from the server using the '--server' flag instead.
+`,
+		Example: `
+# show the latest deployments of release named myapp
+$ dpl history myapp
+
+# show the failed deployments of release named myapp to production within the last week, as JSON
+$ dpl history myapp --environment production --result failed --since 168h -o json
+
+# show the deployments of release named myapp executed by the server
+$ DPL_SERVER_TOKEN=s3cr3t dpl history myapp --server http://localhost:10080`,
+	}
+
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		log.SetLevel(global.GetLogLevel())
+
+		if err := params.ParseArgs(args); err != nil {
+			return result.WithCategory(result.CategoryValidation, err)
+		}
+
+		if err := params.Validate(); err != nil {
+			return result.WithCategory(result.CategoryValidation, err)
+		}
+
+		// the history owns the stdout, hence the logs are moved to stderr
+		log.Output = os.Stderr
+		if err := log.Configure(global.GetLogFormat(), global.GetLogFile()); err != nil {
+			return err
+		}
+
+		instance := &historyInstance{Params: params, Logger: log.Logger}
+
+		entries, err := instance.Query(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		return write(os.Stdout, params.Output, entries)
+	}
+
+	if err := params.Attach(cmd.Flags()); err != nil {
+		log.Error(err, "failed to attach command flags")
+		os.Exit(1)
+	}
+
+	return cmd
+}
@@ -0,0 +1,203 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/git"
+	"github.com/ardikabs/dpl/internal/history"
+	"github.com/ardikabs/dpl/internal/manager"
+	"github.com/ardikabs/dpl/internal/types"
+	"github.com/go-logr/logr"
+)
+
+const serverRequestTimeout = 30 * time.Second
+
+type historyInstance struct {
+	Params *parameters
+	Logger logr.Logger
+}
+
+// Query reads the history from the server, the local database, or the manifest repository, in that order of precedence.
+func (ins *historyInstance) Query(ctx context.Context) ([]history.Entry, error) {
+	f := ins.Params.GetFilter()
+
+	switch {
+	case ins.Params.ServerURL != "":
+		return ins.queryServer(ctx, f)
+	case ins.Params.DB != "":
+		store, err := history.OpenBoltStore(ins.Params.DB)
+		if err != nil {
+			return nil, err
+		}
+		defer store.Close()
+
+		return store.Query(f)
+	default:
+		return ins.queryRepository(ctx, f)
+	}
+}
+
+func (ins *historyInstance) queryServer(ctx context.Context, f history.Filter) ([]history.Entry, error) {
+	q := url.Values{}
+	q.Set("release", f.Release)
+	q.Set("environment", f.Environment)
+	q.Set("cluster", f.Cluster)
+	q.Set("result", f.Result)
+	q.Set("limit", strconv.Itoa(f.Limit))
+	if !f.Since.IsZero() {
+		q.Set("since", f.Since.Format(time.RFC3339))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, serverRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(ins.Params.ServerURL, "/")+"/v1/history?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+ins.Params.ServerToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var entries []history.Entry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (ins *historyInstance) queryRepository(ctx context.Context, f history.Filter) ([]history.Entry, error) {
+	gitURL, gitRevision := ins.Params.RepoURL, ins.Params.Revision
+	if gitURL == "" {
+		releases, err := ins.listReleases(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		gitURL, gitRevision = releases.GetGitURL(), releases.GetGitRevision()
+	}
+
+	log := ins.Logger.WithName("history").WithValues("release", f.Release, "gitURL", gitURL, "gitRevision", gitRevision)
+
+	g, err := git.New(ins.Params.GetGitSecret())
+	if err != nil {
+		return nil, err
+	}
+
+	workspace, err := os.MkdirTemp("/tmp", "dpl-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workspace)
+
+	cloneOpts := []git.CloneOption{git.WithCloneLogger(log)}
+	if gitRevision != "" {
+		cloneOpts = append(cloneOpts, git.WithCloneBranch(gitRevision))
+	}
+
+	repo, err := g.Clone(ctx, gitURL, workspace, cloneOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return history.NewFileStore(repo.Root()).Query(f)
+}
+
+func (ins *historyInstance) listReleases(ctx context.Context) (types.ListReleases, error) {
+	argo, err := ins.Params.ArgoCD.NewManager()
+	if err != nil {
+		return nil, err
+	}
+	defer argo.Close()
+
+	req, err := manager.NewListReleaseRequestBuilder().
+		SetReleaseSelector(ins.Params.SelectorForRelease, ins.Params.ReleaseName).
+		SetEnvironmentSelector(ins.Params.SelectorForEnvironment, ins.Params.Environment).
+		SetClusterSelector(ins.Params.SelectorForCluster, ins.Params.Cluster).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	return argo.ListReleases(ctx, req, manager.WithLogger(ins.Logger))
+}
+
+func write(w io.Writer, format string, entries []history.Entry) error {
+	switch format {
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if entries == nil {
+			entries = []history.Entry{}
+		}
+		return enc.Encode(entries)
+	case OutputJSONL:
+		enc := json.NewEncoder(w)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STARTED\tENVIRONMENT\tCLUSTERS\tIMAGE\tPREVIOUS IMAGE\tRESULT\tDURATION\tACTOR\tCOMMIT\tREQUEST ID")
+	for _, e := range entries {
+		result := e.Result
+		if e.ErrorCategory != "" {
+			result += " (" + e.ErrorCategory + ")"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.StartedAt.Local().Format(time.DateTime),
+			e.Environment,
+			strings.Join(e.Clusters, ","),
+			e.Image,
+			orNone(e.PreviousImage),
+			result,
+			(time.Duration(e.DurationSeconds) * time.Second).String(),
+			orNone(e.Actor),
+			orNone(shortSHA(e.CommitSHA)),
+			e.RequestID,
+		)
+	}
+
+	return tw.Flush()
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+
+	return sha
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+
+	return s
+}
@@ -0,0 +1,157 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/cli/argoconfig"
+	"github.com/ardikabs/dpl/internal/history"
+	"github.com/ardikabs/dpl/internal/types"
+	"github.com/joeshaw/envdecode"
+	flag "github.com/spf13/pflag"
+)
+
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputJSONL = "jsonl"
+)
+
+type parameters struct {
+	ReleaseName            string
+	Environment            string
+	Cluster                string
+	Result                 string
+	Since                  string
+	Limit                  int
+	Output                 string
+	RepoURL                string
+	Revision               string
+	DB                     string
+	ServerURL              string `env:"DPL_SERVER_URL"`
+	ServerToken            string `env:"DPL_SERVER_TOKEN"`
+	SelectorForRelease     string `env:"DPL_SELECTOR_FOR_RELEASE,default=platform.ardikabs.com/release"`
+	SelectorForEnvironment string `env:"DPL_SELECTOR_FOR_ENVIRONMENT,default=platform.ardikabs.com/environment"`
+	SelectorForCluster     string `env:"DPL_SELECTOR_FOR_CLUSTER,default=platform.ardikabs.com/cluster"`
+	GitSecret              string `env:"GIT_SECRET"`
+
+	ArgoCD argoconfig.Parameters
+
+	gitSecret types.GitSecret
+	since     time.Time
+}
+
+func (p *parameters) Attach(flagset *flag.FlagSet) error {
+	if err := envdecode.Decode(p); err != nil {
+		return err
+	}
+
+	p.Limit, p.Output = 20, OutputTable
+
+	flagset.StringVarP(&p.Environment, "environment", "e", p.Environment, "Filter by the environment")
+	flagset.StringVarP(&p.Cluster, "cluster", "c", p.Cluster, "Filter by the cluster")
+	flagset.StringVar(&p.Result, "result", p.Result, "Filter by the result, either 'succeeded' or 'failed'")
+	flagset.StringVar(&p.Since, "since", p.Since, "Filter the deployments since the duration ago (e.g. 72h) or the date (e.g. 2024-10-01)")
+	flagset.IntVar(&p.Limit, "limit", p.Limit, "Maximum number of the latest deployments to show, 0 means unlimited")
+	flagset.StringVarP(&p.Output, "output", "o", p.Output, "Output format, either 'table', 'json', or 'jsonl'")
+	flagset.StringVar(&p.RepoURL, "repo-url", p.RepoURL, "Manifest repository URL to read the history from, instead of looking it up from ArgoCD")
+	flagset.StringVar(&p.Revision, "revision", p.Revision, "Manifest repository revision to read the history from, along with --repo-url")
+	flagset.StringVar(&p.DB, "db", p.DB, "Local database file of the stopped server to read the history from")
+	flagset.StringVar(&p.ServerURL, "server", p.ServerURL, "Server URL to read the history from, authenticated with DPL_SERVER_TOKEN")
+	flagset.StringVar(&p.SelectorForRelease, "selector-for-release", p.SelectorForRelease, "Selector for 'release' attribute")
+	flagset.StringVar(&p.SelectorForEnvironment, "selector-for-environment", p.SelectorForEnvironment, "Selector for 'environment' attribute")
+	flagset.StringVar(&p.SelectorForCluster, "selector-for-cluster", p.SelectorForCluster, "Selector for 'cluster' attribute")
+	p.ArgoCD.AttachFlags(flagset)
+
+	return nil
+}
+
+func (p *parameters) ParseArgs(args []string) error {
+	if len(args) != 1 {
+		return errors.New("either RELEASE_NAME argument is not provided or too many arguments")
+	}
+
+	p.ReleaseName = args[0]
+	return nil
+}
+
+func (p *parameters) Validate() error {
+	switch p.Output {
+	case OutputTable, OutputJSON, OutputJSONL:
+	default:
+		return fmt.Errorf("unsupported output format '%s', it should be either '%s', '%s', or '%s'", p.Output, OutputTable, OutputJSON, OutputJSONL)
+	}
+
+	if err := p.validateAndSetSince(); err != nil {
+		return err
+	}
+
+	switch {
+	case p.ServerURL != "":
+		if p.ServerToken == "" {
+			return errors.New("server token is required. Please set DPL_SERVER_TOKEN environment variable")
+		}
+
+		return nil
+	case p.DB != "":
+		return nil
+	}
+
+	if p.RepoURL == "" {
+		if err := p.ArgoCD.Validate(); err != nil {
+			return fmt.Errorf("%w, or --repo-url flag to read the history without looking up the manifest repository", err)
+		}
+	}
+
+	if p.GitSecret == "" {
+		return errors.New("git secret is required. Please set GIT_SECRET environment variable")
+	}
+
+	return p.validateAndSetGitSecret()
+}
+
+func (p *parameters) validateAndSetSince() error {
+	if p.Since == "" {
+		return nil
+	}
+
+	if d, err := time.ParseDuration(p.Since); err == nil {
+		p.since = time.Now().Add(-d)
+		return nil
+	}
+
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if t, err := time.Parse(layout, p.Since); err == nil {
+			p.since = t
+			return nil
+		}
+	}
+
+	return errors.New("invalid --since flag, it should be either a duration (e.g. 72h) or a date (e.g. 2024-10-01)")
+}
+
+func (p *parameters) validateAndSetGitSecret() error {
+	parts := strings.Split(p.GitSecret, ":")
+	if len(parts) != 2 {
+		return errors.New("invalid git secret format, it should be in format <username:password>")
+	}
+	p.gitSecret = types.GitSecret{Username: parts[0], Password: parts[1]}
+	return nil
+}
+
+func (p *parameters) GetGitSecret() types.GitSecret {
+	return p.gitSecret
+}
+
+func (p *parameters) GetFilter() history.Filter {
+	return history.Filter{
+		Release:     p.ReleaseName,
+		Environment: p.Environment,
+		Cluster:     p.Cluster,
+		Result:      p.Result,
+		Since:       p.since,
+		Limit:       p.Limit,
+	}
+}
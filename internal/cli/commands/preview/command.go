@@ -0,0 +1,98 @@
+package preview
+
+import (
+	"os"
+
+	"github.com/ardikabs/dpl/internal/cli/global"
+	"github.com/ardikabs/dpl/internal/log"
+	"github.com/spf13/cobra"
+)
+
+func NewCommand() *cobra.Command {
+	params := new(parameters)
+
+	cmd := &cobra.Command{
+		Use:   "preview",
+		Short: "manage ephemeral preview environments for given release",
+		Long: `Manage ephemeral preview environments for given release.
+
+A preview environment is scaffolded from the overlay of an existing release (the template),
+by default the release on the 'staging' environment, which can be changed using the '--template-environment' flag.
+The overlay directory is copied next to the template overlay as 'preview-<ID>',
+rendered with the specified image and namespace, then committed to the manifest repository.
+Afterward, a matching ArgoCD Application is created with the release, environment, cluster, and preview labels.
+`,
+	}
+
+	if err := params.Attach(cmd.PersistentFlags()); err != nil {
+		log.Error(err, "failed to attach command flags")
+		os.Exit(1)
+	}
+
+	cmd.AddCommand(newCreateCommand(params))
+	cmd.AddCommand(newDestroyCommand(params))
+	return cmd
+}
+
+func newCreateCommand(params *parameters) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create --id <ID> --image <IMAGE_NAME[:IMAGE_TAG]> RELEASE_NAME",
+		Short: "create a preview environment for given release",
+		Example: `
+# create a preview environment for release named myapp from pull request 123
+$ dpl preview create --id pr-123 --image ghcr.io/ardikabs/app/myapp:b6d7153 myapp`,
+	}
+
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		instance, err := prepare(params, args, true)
+		if err != nil {
+			return err
+		}
+		defer instance.Manager.Close()
+
+		return instance.Create(cmd.Context())
+	}
+
+	params.AttachRenderFlags(cmd.Flags())
+	return cmd
+}
+
+func newDestroyCommand(params *parameters) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "destroy --id <ID> RELEASE_NAME",
+		Short: "destroy a preview environment for given release",
+		Example: `
+# destroy the preview environment for release named myapp from pull request 123
+$ dpl preview destroy --id pr-123 myapp`,
+	}
+
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		instance, err := prepare(params, args, false)
+		if err != nil {
+			return err
+		}
+		defer instance.Manager.Close()
+
+		return instance.Destroy(cmd.Context())
+	}
+
+	return cmd
+}
+
+func prepare(params *parameters, args []string, withImage bool) (*previewInstance, error) {
+	log.SetLevel(global.GetLogLevel())
+
+	if err := params.ParseArgs(args); err != nil {
+		return nil, err
+	}
+
+	if err := params.Validate(withImage); err != nil {
+		return nil, err
+	}
+
+	return newPreviewInstance(log.Logger, params)
+}
@@ -0,0 +1,156 @@
+package preview
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ardikabs/dpl/internal/cli/argoconfig"
+	"github.com/ardikabs/dpl/internal/cli/gitconfig"
+	"github.com/ardikabs/dpl/internal/types"
+	"github.com/joeshaw/envdecode"
+	flag "github.com/spf13/pflag"
+)
+
+var previewIDPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+type parameters struct {
+	ReleaseName            string
+	PreviewID              string
+	Image                  string
+	Environment            string `env:"DPL_PREVIEW_ENVIRONMENT,default=preview"`
+	TemplateEnvironment    string `env:"DPL_PREVIEW_TEMPLATE_ENVIRONMENT,default=staging"`
+	Cluster                string
+	Namespace              string
+	Profile                string `env:"DPL_PROFILE,default=kustomize"`
+	SelectorForRelease     string `env:"DPL_SELECTOR_FOR_RELEASE,default=platform.ardikabs.com/release"`
+	SelectorForEnvironment string `env:"DPL_SELECTOR_FOR_ENVIRONMENT,default=platform.ardikabs.com/environment"`
+	SelectorForCluster     string `env:"DPL_SELECTOR_FOR_CLUSTER,default=platform.ardikabs.com/cluster"`
+	SelectorForPreview     string `env:"DPL_SELECTOR_FOR_PREVIEW,default=platform.ardikabs.com/preview"`
+	KustomizationFileRef   string `env:"KUSTOMIZE_FILE_REF,default=kustomization.yaml"`
+	KustomizationImageRef  string `env:"KUSTOMIZE_IMAGE_REF,default=img"`
+	GitSecret              string `env:"GIT_SECRET"`
+
+	Identity gitconfig.Identity
+	ArgoCD   argoconfig.Parameters
+
+	gitSecret       types.GitSecret
+	imageDefinition types.ImageDefinition
+}
+
+func (p *parameters) Attach(flagset *flag.FlagSet) error {
+	if err := envdecode.Decode(p); err != nil {
+		return err
+	}
+
+	flagset.StringVar(&p.PreviewID, "id", p.PreviewID, "Identifier of the preview environment, e.g. pr-123")
+	flagset.StringVarP(&p.Environment, "environment", "e", p.Environment, "Environment label assigned to the preview environment")
+	flagset.StringVar(&p.TemplateEnvironment, "template-environment", p.TemplateEnvironment, "Environment of the release used as the template overlay")
+	flagset.StringVarP(&p.Cluster, "cluster", "c", p.Cluster, "Cluster of the release used as the template overlay")
+	flagset.StringVar(&p.SelectorForRelease, "selector-for-release", p.SelectorForRelease, "Selector for 'release' attribute")
+	flagset.StringVar(&p.SelectorForEnvironment, "selector-for-environment", p.SelectorForEnvironment, "Selector for 'environment' attribute")
+	flagset.StringVar(&p.SelectorForCluster, "selector-for-cluster", p.SelectorForCluster, "Selector for 'cluster' attribute")
+	flagset.StringVar(&p.SelectorForPreview, "selector-for-preview", p.SelectorForPreview, "Selector for 'preview' attribute")
+	p.Identity.AttachFlags(flagset)
+	p.ArgoCD.AttachFlags(flagset)
+
+	return nil
+}
+
+func (p *parameters) AttachRenderFlags(flagset *flag.FlagSet) {
+	flagset.StringVarP(&p.Image, "image", "i", p.Image, "Container image to be deployed for the preview environment")
+	flagset.StringVarP(&p.Namespace, "namespace", "n", p.Namespace, "Namespace of the preview environment, defaults to <RELEASE_NAME>-<ID>")
+	flagset.StringVar(&p.Profile, "profile", p.Profile, "Selected profile for deployment")
+	flagset.StringVar(&p.KustomizationFileRef, "kustomize-file-ref", p.KustomizationFileRef, "Kustomization file reference")
+	flagset.StringVar(&p.KustomizationImageRef, "kustomize-image-ref", p.KustomizationImageRef, "Kustomization image reference name")
+}
+
+func (p *parameters) ParseArgs(args []string) error {
+	if len(args) != 1 {
+		return errors.New("either RELEASE_NAME argument is not provided or too many arguments")
+	}
+
+	p.ReleaseName = args[0]
+	return nil
+}
+
+func (p *parameters) Validate(withImage bool) error {
+	if err := p.validateRequiredFlags(withImage); err != nil {
+		return err
+	}
+
+	if err := p.validateAndSetGitSecret(); err != nil {
+		return err
+	}
+
+	if !withImage {
+		return nil
+	}
+
+	if err := p.validateAndSetImageDefinition(); err != nil {
+		return err
+	}
+
+	if p.Namespace == "" {
+		p.Namespace = fmt.Sprintf("%s-%s", p.ReleaseName, p.PreviewID)
+	}
+
+	return nil
+}
+
+func (p *parameters) validateRequiredFlags(withImage bool) error {
+	if p.PreviewID == "" {
+		return errors.New("preview id is required. Please set --id flag")
+	}
+
+	if !previewIDPattern.MatchString(p.PreviewID) {
+		return errors.New("invalid preview id, it must consist of lower case alphanumeric characters or '-'")
+	}
+
+	if withImage && p.Image == "" {
+		return errors.New("image is required. Please set --image flag")
+	}
+
+	if err := p.ArgoCD.Validate(); err != nil {
+		return err
+	}
+
+	if p.GitSecret == "" {
+		return errors.New("git secret is required. Please set GIT_SECRET environment variable")
+	}
+
+	return nil
+}
+
+func (p *parameters) validateAndSetImageDefinition() error {
+	parts := strings.Split(p.Image, ":")
+	if len(parts) == 1 {
+		p.imageDefinition = types.ImageDefinition{Name: parts[0], Tag: "latest"}
+		return nil
+	}
+
+	if len(parts) == 2 {
+		p.imageDefinition = types.ImageDefinition{Name: parts[0], Tag: parts[1]}
+		return nil
+	}
+
+	return errors.New("invalid image format, it should be in format <image-name>:<tag>")
+}
+
+func (p *parameters) validateAndSetGitSecret() error {
+	parts := strings.Split(p.GitSecret, ":")
+	if len(parts) != 2 {
+		return errors.New("invalid git secret format, it should be in format <username:password>")
+	}
+	p.gitSecret = types.GitSecret{Username: parts[0], Password: parts[1]}
+	return nil
+}
+
+func (p *parameters) GetGitSecret() types.GitSecret {
+	return p.gitSecret
+}
+
+func (p *parameters) GetImageDefinition() types.ImageDefinition {
+	return p.imageDefinition
+}
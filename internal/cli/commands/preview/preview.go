@@ -0,0 +1,277 @@
+package preview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ardikabs/dpl/internal/errs"
+	"github.com/ardikabs/dpl/internal/git"
+	"github.com/ardikabs/dpl/internal/manager"
+	"github.com/ardikabs/dpl/internal/manager/argocd"
+	"github.com/ardikabs/dpl/internal/renderer"
+	"github.com/ardikabs/dpl/internal/tools/ioutils"
+	"github.com/ardikabs/dpl/internal/types"
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+)
+
+const previewDirPrefix = "preview-"
+
+var (
+	ErrAmbiguousTemplateRelease = errors.New("multiple template releases found, please narrow it down using --cluster flag")
+	ErrInvalidPreviewPath       = errors.New("refusing to remove a directory that is not a preview overlay")
+)
+
+type previewInstance struct {
+	Params   *parameters
+	Git      git.Interface
+	Manager  manager.Interface
+	Renderer renderer.Interface
+	Logger   logr.Logger
+}
+
+func newPreviewInstance(log logr.Logger, params *parameters) (*previewInstance, error) {
+	g, err := git.New(params.GetGitSecret())
+	if err != nil {
+		return nil, err
+	}
+
+	argo, err := params.ArgoCD.NewManager()
+	if err != nil {
+		return nil, err
+	}
+
+	return &previewInstance{
+		Git:      g,
+		Manager:  argo,
+		Renderer: renderer.New(params.Profile),
+		Logger:   log,
+		Params:   params,
+	}, nil
+}
+
+func (ins *previewInstance) Create(ctx context.Context) error {
+	imageDefinition := ins.Params.GetImageDefinition()
+
+	reqID := uuid.New().String()
+	log := ins.Logger.
+		WithName("preview.create").
+		WithValues(
+			"release", ins.Params.ReleaseName,
+			"previewID", ins.Params.PreviewID,
+			"image", imageDefinition.String(),
+			"requestID", reqID,
+		)
+
+	req, err := manager.NewListReleaseRequestBuilder().
+		SetReleaseSelector(ins.Params.SelectorForRelease, ins.Params.ReleaseName).
+		SetEnvironmentSelector(ins.Params.SelectorForEnvironment, ins.Params.TemplateEnvironment).
+		SetClusterSelector(ins.Params.SelectorForCluster, ins.Params.Cluster).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	templates, err := ins.Manager.ListReleases(ctx, req, manager.WithLogger(log))
+	if err != nil {
+		return err
+	}
+
+	if len(templates) > 1 {
+		return ErrAmbiguousTemplateRelease
+	}
+
+	template := templates[0]
+	rel := ins.newPreviewRelease(template)
+
+	log = log.WithValues(
+		"id", rel.ID,
+		"cluster", rel.Cluster,
+		"namespace", rel.Namespace,
+		"gitURL", rel.GitURL,
+		"gitRevision", rel.GitRevision,
+		"gitPath", rel.GitPath,
+	)
+
+	workspace, err := os.MkdirTemp("/tmp", "dpl-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workspace)
+
+	repo, err := ins.Git.Clone(ctx, rel.GitURL, workspace, git.WithCloneBranch(rel.GitRevision), git.WithCloneLogger(log))
+	if err != nil {
+		return err
+	}
+
+	workdir := filepath.Join(repo.Root(), rel.GitPath)
+	switch _, err := os.Stat(workdir); {
+	case os.IsNotExist(err):
+		log.Info("scaffolding preview overlay", "template", template.GitPath)
+		if err := ioutils.CopyDir(filepath.Join(repo.Root(), template.GitPath), workdir); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	default:
+		log.Info("preview overlay already exists, re-rendering it")
+	}
+
+	if err := ins.Renderer.Render(workdir, ins.Params.ReleaseName, &renderer.KustomizeParams{
+		KustomizationRef:   ins.Params.KustomizationFileRef,
+		ImageReferenceName: ins.Params.KustomizationImageRef,
+		ImageName:          imageDefinition.Name,
+		ImageTag:           imageDefinition.Tag,
+	},
+		renderer.WithLogger(log),
+		renderer.WithNamespace(rel.Namespace),
+	); err != nil {
+		return err
+	}
+
+	if err := repo.Commit(ctx,
+		ins.Params.Identity.CommitOption(),
+		git.WithCommitMessage(fmt.Sprintf("dpl(%s): create preview environment %s", reqID, ins.Params.PreviewID)),
+		git.WithCommitPath("."),
+		git.WithCommitLogger(log),
+	); err != nil {
+		return err
+	}
+
+	if err := repo.Push(ctx, git.WithPushLogger(log)); err != nil {
+		return err
+	}
+
+	if err := ins.Manager.CreateRelease(ctx, rel, manager.WithLogger(log)); err != nil {
+		return err
+	}
+
+	if err := ins.Manager.SyncRelease(ctx, rel, manager.WithLogger(log)); err != nil {
+		return err
+	}
+
+	log.Info("preview environment created successfully")
+	return nil
+}
+
+func (ins *previewInstance) Destroy(ctx context.Context) error {
+	reqID := uuid.New().String()
+	log := ins.Logger.
+		WithName("preview.destroy").
+		WithValues(
+			"release", ins.Params.ReleaseName,
+			"previewID", ins.Params.PreviewID,
+			"requestID", reqID,
+		)
+
+	req, err := manager.NewListReleaseRequestBuilder().
+		SetReleaseSelector(ins.Params.SelectorForRelease, ins.Params.ReleaseName).
+		SetEnvironmentSelector(ins.Params.SelectorForEnvironment, ins.Params.Environment).
+		SetClusterSelector(ins.Params.SelectorForCluster, ins.Params.Cluster).
+		SetLabelSelector(ins.Params.SelectorForPreview, ins.Params.PreviewID).
+		Build()
+	if err != nil {
+		return err
+	}
+
+	releases, err := ins.Manager.ListReleases(ctx, req, manager.WithLogger(log))
+	if err != nil {
+		if errs.IsAny(err, argocd.ErrArgoCDApplicationNotExists) {
+			log.Info("preview environment not found, nothing to destroy")
+			return nil
+		}
+
+		return err
+	}
+
+	// every path is validated before anything is deleted, so a non-preview Application is never cascaded
+	for _, rel := range releases {
+		if !strings.HasPrefix(filepath.Base(rel.GitPath), previewDirPrefix) {
+			return errs.Wrapf(ErrInvalidPreviewPath, "application %s, path: %s", rel.ID, rel.GitPath)
+		}
+	}
+
+	gitURL := releases.GetGitURL()
+	gitRevision := releases.GetGitRevision()
+
+	log = log.WithValues("gitURL", gitURL, "gitRevision", gitRevision)
+
+	workspace, err := os.MkdirTemp("/tmp", "dpl-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workspace)
+
+	repo, err := ins.Git.Clone(ctx, gitURL, workspace, git.WithCloneBranch(gitRevision), git.WithCloneLogger(log))
+	if err != nil {
+		return err
+	}
+
+	// the overlays are removed before the Applications, so a failed push is retried while the Applications are still listed
+	for _, rel := range releases {
+		log.Info("removing preview overlay", "id", rel.ID, "cluster", rel.Cluster, "gitPath", rel.GitPath)
+		if err := os.RemoveAll(filepath.Join(repo.Root(), rel.GitPath)); err != nil {
+			return err
+		}
+	}
+
+	if err := repo.Commit(ctx,
+		ins.Params.Identity.CommitOption(),
+		git.WithCommitMessage(fmt.Sprintf("dpl(%s): destroy preview environment %s", reqID, ins.Params.PreviewID)),
+		git.WithCommitPath("."),
+		git.WithCommitLogger(log),
+	); err != nil {
+		return err
+	}
+
+	if err := repo.Push(ctx, git.WithPushLogger(log)); err != nil {
+		return err
+	}
+
+	for _, rel := range releases {
+		log := log.WithValues("id", rel.ID, "cluster", rel.Cluster, "gitPath", rel.GitPath)
+
+		if err := ins.Manager.DeleteRelease(ctx, rel, manager.WithLogger(log), manager.WithCascade(true)); err != nil {
+			return err
+		}
+	}
+
+	log.Info("preview environment destroyed successfully")
+	return nil
+}
+
+// newPreviewRelease derives the preview release from the template release,
+// the overlay is placed next to the template overlay so relative references (e.g. ../base) keep working.
+func (ins *previewInstance) newPreviewRelease(template *types.Release) *types.Release {
+	labels := make(map[string]string, len(template.Labels)+2)
+	for k, v := range template.Labels {
+		labels[k] = v
+	}
+
+	labels[ins.Params.SelectorForEnvironment] = ins.Params.Environment
+	labels[ins.Params.SelectorForPreview] = ins.Params.PreviewID
+
+	return &types.Release{
+		ID:          fmt.Sprintf("%s-%s", template.ID, ins.Params.PreviewID),
+		Name:        template.Name,
+		Cluster:     template.Cluster,
+		Environment: ins.Params.Environment,
+		Image:       ins.Params.GetImageDefinition(),
+		Labels:      labels,
+
+		Project:           template.Project,
+		Namespace:         ins.Params.Namespace,
+		DestinationServer: template.DestinationServer,
+		DestinationName:   template.DestinationName,
+
+		GitURL:      template.GitURL,
+		GitPath:     filepath.Join(filepath.Dir(template.GitPath), previewDirPrefix+ins.Params.PreviewID),
+		GitRevision: template.GitRevision,
+
+		Instance: template.Instance,
+	}
+}
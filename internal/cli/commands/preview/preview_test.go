@@ -0,0 +1,245 @@
+package preview
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ardikabs/dpl/internal/cli/gitconfig"
+	"github.com/ardikabs/dpl/internal/git"
+	"github.com/ardikabs/dpl/internal/manager"
+	"github.com/ardikabs/dpl/internal/renderer"
+	"github.com/ardikabs/dpl/internal/types"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder keeps the operations on the repository and the manager in order
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) record(event string) {
+	r.events = append(r.events, event)
+}
+
+// fakeManager lists the releases of the environment, every change is recorded
+type fakeManager struct {
+	manager.Interface
+
+	*recorder
+	releases types.ListReleases
+	created  []*types.Release
+}
+
+func (m *fakeManager) ListReleases(_ context.Context, req *manager.ListReleaseRequest, _ ...manager.Option) (types.ListReleases, error) {
+	var rels types.ListReleases
+	for _, rel := range m.releases {
+		if rel.Environment == req.Environment {
+			rels = append(rels, rel)
+		}
+	}
+
+	return rels, nil
+}
+
+func (m *fakeManager) CreateRelease(_ context.Context, rel *types.Release, _ ...manager.Option) error {
+	m.record("create " + rel.ID)
+	m.created = append(m.created, rel)
+	return nil
+}
+
+func (m *fakeManager) SyncRelease(_ context.Context, rel *types.Release, _ ...manager.Option) error {
+	m.record("sync " + rel.ID)
+	return nil
+}
+
+func (m *fakeManager) DeleteRelease(_ context.Context, rel *types.Release, _ ...manager.Option) error {
+	m.record("delete " + rel.ID)
+	return nil
+}
+
+// fakeGit clones the files to the destination, the commits record the overlays existing at the time
+type fakeGit struct {
+	*recorder
+	files   map[string]string
+	pushErr error
+}
+
+func (g *fakeGit) Clone(_ context.Context, _, dest string, _ ...git.CloneOption) (git.Repository, error) {
+	for name, content := range g.files {
+		filename := filepath.Join(dest, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			return nil, err
+		}
+
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	return &fakeRepository{fakeGit: g, root: dest}, nil
+}
+
+type fakeRepository struct {
+	*fakeGit
+	root string
+}
+
+func (r *fakeRepository) Root() string { return r.root }
+
+func (r *fakeRepository) Head() (string, error) { return "b6d7153", nil }
+
+func (r *fakeRepository) Pull(context.Context, ...git.PullOption) error { return nil }
+
+func (r *fakeRepository) Commit(_ context.Context, opts ...git.CommitOption) error {
+	o := git.NewDefaultCommitOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	overlays, err := filepath.Glob(filepath.Join(r.root, "envs", "*"))
+	if err != nil {
+		return err
+	}
+
+	event := "commit by " + o.Committer.Name
+	for _, overlay := range overlays {
+		event += " " + filepath.Base(overlay)
+	}
+
+	r.record(event)
+	return nil
+}
+
+func (r *fakeRepository) Push(context.Context, ...git.PushOption) error {
+	if r.pushErr != nil {
+		return r.pushErr
+	}
+
+	r.record("push")
+	return nil
+}
+
+// fakeRenderer renders the kustomization file of the overlay, which has to exist
+type fakeRenderer struct {
+	*recorder
+}
+
+func (r *fakeRenderer) Render(workdir string, _ string, params interface{}, _ ...renderer.RenderOption) error {
+	p := params.(*renderer.KustomizeParams)
+	if _, err := os.Stat(filepath.Join(workdir, p.KustomizationRef)); err != nil {
+		return err
+	}
+
+	r.record("render " + filepath.Base(workdir) + " " + p.ImageName + ":" + p.ImageTag)
+	return nil
+}
+
+func (r *fakeRenderer) Inspect(string, string, interface{}, ...renderer.RenderOption) (types.ImageDefinition, error) {
+	return types.ImageDefinition{}, nil
+}
+
+func newTestInstance(rec *recorder, g *fakeGit, releases ...*types.Release) (*previewInstance, *fakeManager) {
+	params := &parameters{
+		ReleaseName:            "myapp",
+		PreviewID:              "pr-1",
+		Environment:            "preview",
+		TemplateEnvironment:    "staging",
+		Namespace:              "myapp-pr-1",
+		SelectorForRelease:     "platform.ardikabs.com/release",
+		SelectorForEnvironment: "platform.ardikabs.com/environment",
+		SelectorForCluster:     "platform.ardikabs.com/cluster",
+		SelectorForPreview:     "platform.ardikabs.com/preview",
+		KustomizationFileRef:   "kustomization.yaml",
+		KustomizationImageRef:  "img",
+		Identity:               gitconfig.Identity{CommitterName: "autobot", CommitterEmail: "me@ardikabs"},
+		imageDefinition:        types.ImageDefinition{Name: "ghcr.io/ardikabs/app/myapp", Tag: "b6d7153"},
+	}
+
+	m := &fakeManager{recorder: rec, releases: releases}
+	return &previewInstance{
+		Params:   params,
+		Git:      g,
+		Manager:  m,
+		Renderer: &fakeRenderer{recorder: rec},
+		Logger:   logr.Discard(),
+	}, m
+}
+
+func TestPreviewInstance_Create(t *testing.T) {
+	rec := &recorder{}
+	g := &fakeGit{recorder: rec, files: map[string]string{"envs/staging/kustomization.yaml": "kind: Kustomization\n"}}
+	ins, m := newTestInstance(rec, g, &types.Release{
+		ID:          "myapp-staging",
+		Name:        "myapp",
+		Environment: "staging",
+		Labels:      map[string]string{"platform.ardikabs.com/release": "myapp", "platform.ardikabs.com/environment": "staging"},
+		GitURL:      "https://github.com/ardikabs/manifests.git",
+		GitPath:     "envs/staging",
+	})
+
+	require.NoError(t, ins.Create(context.Background()))
+	require.Equal(t, []string{
+		"render preview-pr-1 ghcr.io/ardikabs/app/myapp:b6d7153",
+		"commit by autobot preview-pr-1 staging",
+		"push",
+		"create myapp-staging-pr-1",
+		"sync myapp-staging-pr-1",
+	}, rec.events)
+
+	require.Len(t, m.created, 1)
+	rel := m.created[0]
+	require.Equal(t, "envs/preview-pr-1", rel.GitPath)
+	require.Equal(t, "myapp-pr-1", rel.Namespace)
+	require.Equal(t, map[string]string{
+		"platform.ardikabs.com/release":     "myapp",
+		"platform.ardikabs.com/environment": "preview",
+		"platform.ardikabs.com/preview":     "pr-1",
+	}, rel.Labels)
+}
+
+func TestPreviewInstance_Destroy(t *testing.T) {
+	files := map[string]string{
+		"envs/staging/kustomization.yaml":      "kind: Kustomization\n",
+		"envs/preview-pr-1/kustomization.yaml": "kind: Kustomization\n",
+	}
+
+	preview := &types.Release{ID: "myapp-staging-pr-1", Name: "myapp", Environment: "preview", GitPath: "envs/preview-pr-1"}
+
+	t.Run("overlay is removed before the application", func(t *testing.T) {
+		rec := &recorder{}
+		ins, _ := newTestInstance(rec, &fakeGit{recorder: rec, files: files}, preview)
+
+		require.NoError(t, ins.Destroy(context.Background()))
+		require.Equal(t, []string{
+			"commit by autobot staging",
+			"push",
+			"delete myapp-staging-pr-1",
+		}, rec.events)
+	})
+
+	t.Run("application is kept when the push fails", func(t *testing.T) {
+		rec := &recorder{}
+		pushErr := errors.New("push is rejected")
+		ins, _ := newTestInstance(rec, &fakeGit{recorder: rec, files: files, pushErr: pushErr}, preview)
+
+		require.ErrorIs(t, ins.Destroy(context.Background()), pushErr)
+		require.Equal(t, []string{"commit by autobot staging"}, rec.events)
+	})
+
+	t.Run("non-preview path is refused", func(t *testing.T) {
+		rec := &recorder{}
+		ins, _ := newTestInstance(rec, &fakeGit{recorder: rec, files: files},
+			preview,
+			&types.Release{ID: "myapp-staging-pr-2", Name: "myapp", Environment: "preview", GitPath: "envs/staging"},
+		)
+
+		err := ins.Destroy(context.Background())
+		require.ErrorIs(t, err, ErrInvalidPreviewPath)
+		require.ErrorContains(t, err, "application myapp-staging-pr-2, path: envs/staging")
+		require.Empty(t, rec.events)
+	})
+}
@@ -0,0 +1,26 @@
+package gitconfig
+
+import (
+	"github.com/ardikabs/dpl/internal/git"
+	flag "github.com/spf13/pflag"
+)
+
+// Identity is the committer and the author of the commits, shared by the commands committing to the manifest repository.
+type Identity struct {
+	CommitterName  string `env:"DPL_COMMITTER_NAME,default=autobot"`
+	CommitterEmail string `env:"DPL_COMMITTER_EMAIL,default=me@ardikabs"`
+	AuthorName     string `env:"DPL_AUTHOR_NAME"`
+	AuthorEmail    string `env:"DPL_AUTHOR_EMAIL"`
+}
+
+func (p *Identity) AttachFlags(flagset *flag.FlagSet) {
+	flagset.StringVar(&p.CommitterName, "committer-name", p.CommitterName, "Committer name of the commits to the manifest repository")
+	flagset.StringVar(&p.CommitterEmail, "committer-email", p.CommitterEmail, "Committer email of the commits to the manifest repository")
+	flagset.StringVar(&p.AuthorName, "author-name", p.AuthorName, "Author name of the commits to the manifest repository, defaults to the committer")
+	flagset.StringVar(&p.AuthorEmail, "author-email", p.AuthorEmail, "Author email of the commits to the manifest repository, defaults to the committer")
+}
+
+// CommitOption returns the commit option setting the committer and the author
+func (p *Identity) CommitOption() git.CommitOption {
+	return git.WithIdentity(p.CommitterName, p.CommitterEmail, p.AuthorName, p.AuthorEmail)
+}
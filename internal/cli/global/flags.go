@@ -4,10 +4,14 @@ import "github.com/spf13/pflag"
 
 const (
 	LOG_VERBOSITY = "v"
+	LOG_FORMAT    = "log-format"
+	LOG_FILE      = "log-file"
 )
 
 func AttachFlags(flagset *pflag.FlagSet) {
 	flagset.IntP(LOG_VERBOSITY, "v", 0, "Number for the log level verbosity")
+	flagset.String(LOG_FORMAT, "text", "Log format, either text, json, or logfmt")
+	flagset.String(LOG_FILE, "", "File to write the logs to, in addition to the standard output")
 
 	global.fs = flagset
 }
@@ -24,3 +28,29 @@ func GetLogLevel() int {
 
 	return v
 }
+
+func GetLogFormat() string {
+	if global.isUnset() {
+		return ""
+	}
+
+	v, err := global.fs.GetString(LOG_FORMAT)
+	if err != nil {
+		return ""
+	}
+
+	return v
+}
+
+func GetLogFile() string {
+	if global.isUnset() {
+		return ""
+	}
+
+	v, err := global.fs.GetString(LOG_FILE)
+	if err != nil {
+		return ""
+	}
+
+	return v
+}
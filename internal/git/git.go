@@ -3,8 +3,10 @@ package git
 import (
 	"errors"
 
+	"github.com/ardikabs/dpl/internal/tracing"
 	"github.com/ardikabs/dpl/internal/types"
 	"github.com/go-git/go-git/v5"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/net/context"
 )
 
@@ -25,7 +27,10 @@ func New(secret types.GitSecret) (*Git, error) {
 	return g, nil
 }
 
-func (g *Git) Clone(ctx context.Context, url, dest string, opts ...CloneOption) (Repository, error) {
+func (g *Git) Clone(ctx context.Context, url, dest string, opts ...CloneOption) (_ Repository, err error) {
+	ctx, span := tracing.Start(ctx, "Git.Clone", attribute.String("git.url", url))
+	defer tracing.End(span, &err)
+
 	o := NewDefaultCloneOptions()
 	for _, opt := range opts {
 		opt(o)
@@ -33,7 +38,7 @@ func (g *Git) Clone(ctx context.Context, url, dest string, opts ...CloneOption)
 
 	authMethod := g.secret.GetAuthMethod()
 
-	gitRepo, err := git.PlainClone(dest, false, &git.CloneOptions{
+	gitRepo, err := git.PlainCloneContext(ctx, dest, false, &git.CloneOptions{
 		URL:           url,
 		Auth:          authMethod,
 		SingleBranch:  o.SingleBranch,
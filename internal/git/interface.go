@@ -8,6 +8,7 @@ type Interface interface {
 
 type Repository interface {
 	Root() string
+	Head() (string, error)
 	Pull(ctx context.Context, opts ...PullOption) error
 	Commit(ctx context.Context, opts ...CommitOption) error
 	Push(ctx context.Context, opts ...PushOption) error
@@ -42,12 +42,16 @@ type CommitOptions struct {
 	Paths     []string
 	Message   string
 	Committer *object.Signature
+
+	// Author defaults to the committer
+	Author *object.Signature
 }
 
 type CommitOption func(*CommitOptions)
 
 func NewDefaultCommitOptions() *CommitOptions {
 	return &CommitOptions{
+		Paths:   []string{"."},
 		Message: "dpl: update deployment manifest",
 		Committer: &object.Signature{
 			Name:  "Deployment Auto BOT",
@@ -67,15 +71,48 @@ func WithCommitter(user, email string) CommitOption {
 	}
 }
 
+func WithAuthor(user, email string) CommitOption {
+	return func(o *CommitOptions) {
+		o.Author = &object.Signature{
+			Name:  user,
+			Email: email,
+			When:  time.Now(),
+		}
+	}
+}
+
+// WithIdentity sets the committer, along with the author when either of its name or email is set,
+// the missing name or email of the author defaults to the committer.
+func WithIdentity(committerName, committerEmail, authorName, authorEmail string) CommitOption {
+	return func(o *CommitOptions) {
+		WithCommitter(committerName, committerEmail)(o)
+
+		if authorName == "" && authorEmail == "" {
+			return
+		}
+
+		if authorName == "" {
+			authorName = committerName
+		}
+
+		if authorEmail == "" {
+			authorEmail = committerEmail
+		}
+
+		WithAuthor(authorName, authorEmail)(o)
+	}
+}
+
 func WithCommitMessage(message string) CommitOption {
 	return func(o *CommitOptions) {
 		o.Message = message
 	}
 }
 
+// WithCommitPath limits the commit to the changes within the paths, it defaults to the whole worktree
 func WithCommitPath(paths ...string) CommitOption {
 	return func(o *CommitOptions) {
-		if len(paths) > 0 {
+		if len(paths) == 0 {
 			o.Paths = []string{"."}
 			return
 		}
@@ -0,0 +1,37 @@
+package git_test
+
+import (
+	"testing"
+
+	"github.com/ardikabs/dpl/internal/git"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithIdentity(t *testing.T) {
+	tests := []struct {
+		name                    string
+		authorName, authorEmail string
+		wantAuthor              []string
+	}{
+		{name: "author defaults to the committer"},
+		{name: "author is set", authorName: "ardikabs", authorEmail: "ardikabs@ardikabs.com", wantAuthor: []string{"ardikabs", "ardikabs@ardikabs.com"}},
+		{name: "author email defaults to the committer", authorName: "ardikabs", wantAuthor: []string{"ardikabs", "autobot@ardikabs.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := git.NewDefaultCommitOptions()
+			git.WithIdentity("autobot", "autobot@ardikabs.com", tt.authorName, tt.authorEmail)(o)
+
+			require.Equal(t, "autobot", o.Committer.Name)
+			require.Equal(t, "autobot@ardikabs.com", o.Committer.Email)
+
+			if tt.wantAuthor == nil {
+				require.Nil(t, o.Author)
+				return
+			}
+
+			require.Equal(t, tt.wantAuthor, []string{o.Author.Name, o.Author.Email})
+		})
+	}
+}
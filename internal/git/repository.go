@@ -9,6 +9,7 @@ import (
 	"github.com/ardikabs/dpl/internal/errs"
 	"github.com/ardikabs/dpl/internal/tools/cmdutils"
 	"github.com/ardikabs/dpl/internal/tools/retry"
+	"github.com/ardikabs/dpl/internal/tracing"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
@@ -47,6 +48,16 @@ func (g *GitRepository) Root() string {
 	return worktree.Filesystem.Root()
 }
 
+// Head returns the commit hash the current HEAD points to
+func (g *GitRepository) Head() (string, error) {
+	ref, err := g.repo.Head()
+	if err != nil {
+		return "", err
+	}
+
+	return ref.Hash().String(), nil
+}
+
 func (g *GitRepository) Pull(ctx context.Context, opts ...PullOption) error {
 	o := new(PullOptions)
 	for _, opt := range opts {
@@ -95,7 +106,10 @@ func (g *GitRepository) rawPull(ctx context.Context, log logr.Logger) error {
 	return nil
 }
 
-func (g *GitRepository) Commit(ctx context.Context, opts ...CommitOption) error {
+func (g *GitRepository) Commit(ctx context.Context, opts ...CommitOption) (err error) {
+	_, span := tracing.Start(ctx, "Repository.Commit")
+	defer tracing.End(span, &err)
+
 	o := NewDefaultCommitOptions()
 	for _, opt := range opts {
 		opt(o)
@@ -126,10 +140,25 @@ func (g *GitRepository) Commit(ctx context.Context, opts ...CommitOption) error
 		}
 	}
 
+	// the changes outside the paths are left out, so it could be clean for the paths
+	if status, err = worktree.Status(); err != nil {
+		return err
+	}
+
+	if !isStaged(status) {
+		log.V(2).Info("skip commit, as because no changes within the paths", "paths", o.Paths)
+		return nil
+	}
+
+	author := o.Author
+	if author == nil {
+		author = o.Committer
+	}
+
 	log.V(2).Info("commit changes", "message", o.Message)
 	if _, err := worktree.Commit(o.Message, &git.CommitOptions{
-		All:    true,
-		Author: o.Committer,
+		Author:    author,
+		Committer: o.Committer,
 	}); err != nil {
 		return err
 	}
@@ -137,7 +166,20 @@ func (g *GitRepository) Commit(ctx context.Context, opts ...CommitOption) error
 	return nil
 }
 
-func (g *GitRepository) Push(ctx context.Context, opts ...PushOption) error {
+func isStaged(status git.Status) bool {
+	for _, s := range status {
+		if s.Staging != git.Unmodified && s.Staging != git.Untracked {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (g *GitRepository) Push(ctx context.Context, opts ...PushOption) (err error) {
+	ctx, span := tracing.Start(ctx, "Repository.Push")
+	defer tracing.End(span, &err)
+
 	o := new(PushOptions)
 	for _, opt := range opts {
 		opt(o)
@@ -145,7 +187,7 @@ func (g *GitRepository) Push(ctx context.Context, opts ...PushOption) error {
 
 	log := o.Logger.WithName("repository.Push")
 
-	err := retry.OnError(ctx, func(err error) bool {
+	err = retry.OnError(ctx, func(err error) bool {
 		if errs.IsAny(err, git.ErrNonFastForwardUpdate, ErrPullFailed, ErrPushFailed) {
 			return true
 		}
@@ -1,10 +1,15 @@
 package git_test
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/ardikabs/dpl/internal/git"
 	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -25,3 +30,71 @@ func TestRepository_Root(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, r.Root(), destDir)
 }
+
+func TestRepository_Head(t *testing.T) {
+	destDir := getTempDir(t)
+	gitRepo, err := gogit.PlainClone(destDir, false, &gogit.CloneOptions{
+		URL: getBasicRepositoryURL(),
+	})
+	require.NoError(t, err)
+
+	ref, err := gitRepo.Head()
+	require.NoError(t, err)
+
+	r, err := git.NewGitRepository(gitRepo, getDummyRepoAuth())
+	require.NoError(t, err)
+
+	head, err := r.Head()
+	require.NoError(t, err)
+	assert.Equal(t, ref.Hash().String(), head)
+}
+
+func TestRepository_Commit(t *testing.T) {
+	destDir := getTempDir(t)
+	gitRepo, err := gogit.PlainClone(destDir, false, &gogit.CloneOptions{
+		URL: getBasicRepositoryURL(),
+	})
+	require.NoError(t, err)
+
+	r, err := git.NewGitRepository(gitRepo, getDummyRepoAuth())
+	require.NoError(t, err)
+
+	for _, dir := range []string{"dev-1", "dev-2"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(destDir, dir), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(destDir, dir, "kustomization.yaml"), []byte("images: []\n"), 0o644))
+	}
+
+	before, err := r.Head()
+	require.NoError(t, err)
+
+	err = r.Commit(context.Background(),
+		git.WithCommitMessage("update dev-1"),
+		git.WithCommitter("autobot", "autobot@ardikabs.com"),
+		git.WithAuthor("ardikabs", "ardikabs@ardikabs.com"),
+		git.WithCommitPath("dev-1"),
+	)
+	require.NoError(t, err)
+
+	head, err := r.Head()
+	require.NoError(t, err)
+	require.NotEqual(t, before, head)
+
+	commit, err := gitRepo.CommitObject(plumbing.NewHash(head))
+	require.NoError(t, err)
+	require.Equal(t, "autobot", commit.Committer.Name)
+	require.Equal(t, "ardikabs", commit.Author.Name)
+
+	_, err = commit.File("dev-1/kustomization.yaml")
+	require.NoError(t, err)
+
+	_, err = commit.File("dev-2/kustomization.yaml")
+	require.ErrorIs(t, err, object.ErrFileNotFound)
+
+	t.Run("skipped when no changes within the paths", func(t *testing.T) {
+		require.NoError(t, r.Commit(context.Background(), git.WithCommitPath("dev-1")))
+
+		again, err := r.Head()
+		require.NoError(t, err)
+		require.Equal(t, head, again)
+	})
+}
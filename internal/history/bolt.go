@@ -0,0 +1,81 @@
+package history
+
+import (
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// keyTimeFormat is fixed width, unlike time.RFC3339Nano, so the keys are sorted chronologically
+const keyTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+var bucketName = []byte("history")
+
+// BoltStore keeps the ledger in a local BoltDB file, for the server that runs many deployments.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+func OpenBoltStore(filename string) (*BoltStore, error) {
+	db, err := bolt.Open(filename, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketName)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// Append stores the entries keyed by the start time, so they are iterated chronologically.
+func (s *BoltStore) Append(entries ...Entry) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketName)
+
+		for _, e := range entries {
+			value, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+
+			key := e.StartedAt.UTC().Format(keyTimeFormat) + "/" + e.RequestID + "/" + e.Release + "/" + e.Environment
+			if err := b.Put([]byte(key), value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *BoltStore) Query(f Filter) ([]Entry, error) {
+	var entries []Entry
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).ForEach(func(_, value []byte) error {
+			var e Entry
+			if err := json.Unmarshal(value, &e); err != nil {
+				return err
+			}
+
+			entries = append(entries, e)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return apply(entries, f), nil
+}
@@ -0,0 +1,99 @@
+package history
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/ardikabs/dpl/internal/errs"
+)
+
+const (
+	fileExt = ".json"
+
+	// fileTimeFormat sorts the entry files of the release by the time the deployment is started
+	fileTimeFormat = "20060102T150405.000000000Z"
+)
+
+// FileStore keeps the ledger as a JSON file per entry, grouped by the release directory, within the manifest repository,
+// so the history is versioned along with the manifests, and the concurrent deployments never change the same file.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns the store of the ledger within the repository root
+func NewFileStore(root string) *FileStore {
+	return &FileStore{dir: filepath.Join(root, Dir)}
+}
+
+func (s *FileStore) Append(entries ...Entry) error {
+	for _, e := range entries {
+		if !isValidName(e.Release) {
+			return errs.Wrapf(ErrInvalidRelease, "release: %s", e.Release)
+		}
+
+		if !isValidName(e.RequestID) {
+			return errs.Wrapf(ErrInvalidRequestID, "requestID: %s", e.RequestID)
+		}
+
+		content, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		dir := filepath.Join(s.dir, e.Release)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+
+		filename := filepath.Join(dir, e.StartedAt.UTC().Format(fileTimeFormat)+"-"+e.RequestID+fileExt)
+		if err := os.WriteFile(filename, append(content, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *FileStore) Query(f Filter) ([]Entry, error) {
+	release := "*"
+	if f.Release != "" {
+		if !isValidName(f.Release) {
+			return nil, errs.Wrapf(ErrInvalidRelease, "release: %s", f.Release)
+		}
+
+		release = f.Release
+	}
+
+	files, err := filepath.Glob(filepath.Join(s.dir, release, "*"+fileExt))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(files))
+	for _, file := range files {
+		e, err := readEntry(file)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return apply(entries, f), nil
+}
+
+func readEntry(filename string) (Entry, error) {
+	var e Entry
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return e, err
+	}
+
+	if err := json.Unmarshal(content, &e); err != nil {
+		return e, errs.Wrapf(err, "file: %s", filename)
+	}
+
+	return e, nil
+}
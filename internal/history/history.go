@@ -0,0 +1,105 @@
+package history
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Dir is the directory of the deployment ledger, relative to the manifest repository root
+const Dir = ".dpl/history"
+
+var (
+	ErrInvalidRelease   = errors.New("invalid release name")
+	ErrInvalidRequestID = errors.New("invalid request ID")
+)
+
+// Entry records a deployment of a release to an environment
+type Entry struct {
+	RequestID       string    `json:"requestID"`
+	Actor           string    `json:"actor,omitempty"`
+	Release         string    `json:"release"`
+	Environment     string    `json:"environment"`
+	Clusters        []string  `json:"clusters"`
+	PreviousImage   string    `json:"previousImage,omitempty"`
+	Image           string    `json:"image"`
+	CommitSHA       string    `json:"commitSHA,omitempty"`
+	Result          string    `json:"result"`
+	ErrorCategory   string    `json:"errorCategory,omitempty"`
+	StartedAt       time.Time `json:"startedAt"`
+	DurationSeconds float64   `json:"durationSeconds"`
+}
+
+// Filter narrows down the entries, the empty fields match everything.
+type Filter struct {
+	Release     string
+	Environment string
+	Cluster     string
+	Result      string
+	Since       time.Time
+	// Limit is the maximum number of the latest entries returned, zero means unlimited.
+	Limit int
+}
+
+func (f Filter) matches(e Entry) bool {
+	if f.Release != "" && f.Release != e.Release {
+		return false
+	}
+
+	if f.Environment != "" && f.Environment != e.Environment {
+		return false
+	}
+
+	if f.Result != "" && f.Result != e.Result {
+		return false
+	}
+
+	if !f.Since.IsZero() && e.StartedAt.Before(f.Since) {
+		return false
+	}
+
+	if f.Cluster == "" {
+		return true
+	}
+
+	for _, cluster := range e.Clusters {
+		if cluster == f.Cluster {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Store keeps the deployment ledger
+type Store interface {
+	Append(entries ...Entry) error
+	// Query returns the entries matching the filter, the latest first.
+	Query(f Filter) ([]Entry, error)
+}
+
+// apply filters the entries, then sorts them from the latest and limits them
+func apply(entries []Entry, f Filter) []Entry {
+	out := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		if f.matches(e) {
+			out = append(out, e)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].StartedAt.After(out[j].StartedAt)
+	})
+
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+
+	return out
+}
+
+// isValidName reports whether the name is safe to be a path element of the ledger
+func isValidName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
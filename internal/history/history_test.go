@@ -0,0 +1,148 @@
+package history_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/git"
+	"github.com/ardikabs/dpl/internal/history"
+	"github.com/ardikabs/dpl/internal/types"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/require"
+)
+
+func newEntries() []history.Entry {
+	now := time.Date(2024, 10, 1, 8, 0, 0, 0, time.UTC)
+
+	return []history.Entry{
+		{RequestID: "req-1", Release: "myapp", Environment: "staging", Clusters: []string{"stg-1"}, Image: "myapp:v1", Result: "succeeded", StartedAt: now},
+		{RequestID: "req-2", Release: "myapp", Environment: "production", Clusters: []string{"prod-1", "prod-2"}, PreviousImage: "myapp:v0", Image: "myapp:v1", Result: "failed", ErrorCategory: "degraded", StartedAt: now.Add(time.Hour)},
+		{RequestID: "req-3", Release: "myapp", Environment: "production", Clusters: []string{"prod-1", "prod-2"}, PreviousImage: "myapp:v0", Image: "myapp:v2", Result: "succeeded", StartedAt: now.Add(2 * time.Hour)},
+		{RequestID: "req-4", Release: "myworker", Environment: "production", Clusters: []string{"prod-1"}, Image: "myworker:v1", Result: "succeeded", StartedAt: now.Add(3 * time.Hour)},
+	}
+}
+
+func requestIDs(entries []history.Entry) []string {
+	out := make([]string, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, e.RequestID)
+	}
+
+	return out
+}
+
+func testStore(t *testing.T, store history.Store) {
+	require.NoError(t, store.Append(newEntries()...))
+
+	tests := map[string]struct {
+		filter history.Filter
+		want   []string
+	}{
+		"latest first":   {history.Filter{Release: "myapp"}, []string{"req-3", "req-2", "req-1"}},
+		"by environment": {history.Filter{Release: "myapp", Environment: "production"}, []string{"req-3", "req-2"}},
+		"by cluster":     {history.Filter{Cluster: "prod-2"}, []string{"req-3", "req-2"}},
+		"by result":      {history.Filter{Release: "myapp", Result: "failed"}, []string{"req-2"}},
+		"since":          {history.Filter{Since: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC)}, []string{"req-4", "req-3"}},
+		"limit":          {history.Filter{Limit: 1}, []string{"req-4"}},
+		"unknown":        {history.Filter{Release: "unknown"}, []string{}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			entries, err := store.Query(tt.filter)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, requestIDs(entries))
+		})
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	root := t.TempDir()
+	testStore(t, history.NewFileStore(root))
+
+	t.Run("file per entry", func(t *testing.T) {
+		content, err := os.ReadFile(filepath.Join(root, history.Dir, "myworker", "20241001T110000.000000000Z-req-4.json"))
+		require.NoError(t, err)
+		require.Contains(t, string(content), `"requestID":"req-4"`)
+	})
+
+	t.Run("invalid release", func(t *testing.T) {
+		err := history.NewFileStore(root).Append(history.Entry{Release: "../myapp"})
+		require.ErrorIs(t, err, history.ErrInvalidRelease)
+
+		err = history.NewFileStore(root).Append(history.Entry{Release: "myapp", RequestID: "../req-1"})
+		require.ErrorIs(t, err, history.ErrInvalidRequestID)
+	})
+}
+
+func TestFileStore_ConcurrentClones(t *testing.T) {
+	t.Setenv("GIT_AUTHOR_NAME", "autobot")
+	t.Setenv("GIT_AUTHOR_EMAIL", "me@ardikabs")
+	t.Setenv("GIT_COMMITTER_NAME", "autobot")
+	t.Setenv("GIT_COMMITTER_EMAIL", "me@ardikabs")
+
+	remote := newRemoteRepository(t)
+
+	g, err := git.New(types.GitSecret{})
+	require.NoError(t, err)
+
+	// both runs record the release before either of them pushes, as the concurrent deployments do
+	entries := newEntries()[:2]
+	repos := make([]git.Repository, len(entries))
+	for i, e := range entries {
+		repos[i], err = g.Clone(context.Background(), remote, t.TempDir())
+		require.NoError(t, err)
+
+		require.NoError(t, history.NewFileStore(repos[i].Root()).Append(e))
+		require.NoError(t, repos[i].Commit(context.Background(), git.WithCommitMessage("record "+e.RequestID), git.WithCommitPath(history.Dir)))
+	}
+
+	for _, repo := range repos {
+		require.NoError(t, repo.Push(context.Background()))
+	}
+
+	repo, err := g.Clone(context.Background(), remote, t.TempDir())
+	require.NoError(t, err)
+
+	got, err := history.NewFileStore(repo.Root()).Query(history.Filter{Release: "myapp"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"req-2", "req-1"}, requestIDs(got))
+}
+
+// newRemoteRepository returns a bare repository with an initial commit, to be cloned and pushed to
+func newRemoteRepository(t *testing.T) string {
+	seed := t.TempDir()
+	repo, err := gogit.PlainInit(seed, false)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(seed, "kustomization.yaml"), []byte("images: []\n"), 0644))
+
+	worktree, err := repo.Worktree()
+	require.NoError(t, err)
+
+	_, err = worktree.Add(".")
+	require.NoError(t, err)
+
+	_, err = worktree.Commit("initial commit", &gogit.CommitOptions{
+		Author: &object.Signature{Name: "autobot", Email: "me@ardikabs", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	remote := filepath.Join(t.TempDir(), "manifests.git")
+	_, err = gogit.PlainClone(remote, true, &gogit.CloneOptions{URL: seed})
+	require.NoError(t, err)
+
+	return remote
+}
+
+func TestBoltStore(t *testing.T) {
+	store, err := history.OpenBoltStore(filepath.Join(t.TempDir(), "history.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	testStore(t, store)
+}
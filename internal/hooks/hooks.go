@@ -0,0 +1,215 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/errs"
+	"github.com/ardikabs/dpl/internal/tools/cmdutils"
+	"github.com/ardikabs/dpl/internal/tools/ioutils"
+	goyaml "gopkg.in/yaml.v3"
+)
+
+var (
+	DefaultTimeoutSec uint = 300 // 5 minutes
+
+	ErrHookFailed        = errors.New("hook execution failed")
+	ErrHookInvalidConfig = errors.New("invalid hook configuration")
+)
+
+// Stage is a point of the deployment lifecycle where hooks are executed
+type Stage string
+
+const (
+	StagePreRender  Stage = "pre-render"
+	StagePostRender Stage = "post-render"
+	StagePostPush   Stage = "post-push"
+	StagePostSync   Stage = "post-sync"
+	StageOnFailure  Stage = "on-failure"
+)
+
+func (s Stage) isValid() bool {
+	switch s {
+	case StagePreRender, StagePostRender, StagePostPush, StagePostSync, StageOnFailure:
+		return true
+	}
+
+	return false
+}
+
+// Policy decides what happens to the deployment lifecycle when a hook fails
+type Policy string
+
+const (
+	PolicyFail   Policy = "fail"
+	PolicyIgnore Policy = "ignore"
+)
+
+type Hook struct {
+	Name       string `yaml:"name"`
+	Stage      Stage  `yaml:"stage"`
+	Command    string `yaml:"command"`
+	TimeoutSec uint   `yaml:"timeoutSec"`
+	Policy     Policy `yaml:"policy"`
+}
+
+// Context is the structured deployment context given to the hook,
+// both as environment variables and as JSON document on the standard input.
+type Context struct {
+	Stage       Stage            `json:"stage"`
+	RequestID   string           `json:"requestID"`
+	Release     string           `json:"release,omitempty"`
+	Environment string           `json:"environment,omitempty"`
+	Image       string           `json:"image,omitempty"`
+	CommitSHA   string           `json:"commitSHA,omitempty"`
+	Error       string           `json:"error,omitempty"`
+	Releases    []ReleaseContext `json:"releases,omitempty"`
+}
+
+type ReleaseContext struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Environment string `json:"environment"`
+	Cluster     string `json:"cluster"`
+	Image       string `json:"image"`
+	GitPath     string `json:"gitPath"`
+}
+
+func (c Context) environ() []string {
+	clusters := make([]string, 0, len(c.Releases))
+	for _, rel := range c.Releases {
+		clusters = append(clusters, rel.Cluster)
+	}
+
+	return []string{
+		"DPL_HOOK_STAGE=" + string(c.Stage),
+		"DPL_REQUEST_ID=" + c.RequestID,
+		"DPL_RELEASE=" + c.Release,
+		"DPL_ENVIRONMENT=" + c.Environment,
+		"DPL_IMAGE=" + c.Image,
+		"DPL_COMMIT_SHA=" + c.CommitSHA,
+		"DPL_CLUSTERS=" + strings.Join(clusters, ","),
+		"DPL_ERROR=" + c.Error,
+	}
+}
+
+type config struct {
+	Hooks []Hook `yaml:"hooks"`
+}
+
+type Runner struct {
+	hooks []Hook
+}
+
+// Load reads the hooks configuration file, for example:
+//
+//	hooks:
+//	  - name: validate-manifest
+//	    stage: post-render
+//	    command: make validate-manifest
+//	    timeoutSec: 60
+//	    policy: fail
+func Load(filename string) (*Runner, error) {
+	content, err := ioutils.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg config
+	if err := goyaml.Unmarshal(content, &cfg); err != nil {
+		return nil, err
+	}
+
+	return New(cfg.Hooks...)
+}
+
+func New(hooks ...Hook) (*Runner, error) {
+	for idx := range hooks {
+		h := &hooks[idx]
+
+		if h.Name == "" {
+			h.Name = fmt.Sprintf("hook-%d", idx)
+		}
+
+		if !h.Stage.isValid() {
+			return nil, errs.Wrapf(ErrHookInvalidConfig, "hook %s has unknown stage '%s'", h.Name, h.Stage)
+		}
+
+		if h.Command == "" {
+			return nil, errs.Wrapf(ErrHookInvalidConfig, "hook %s has no command", h.Name)
+		}
+
+		switch h.Policy {
+		case "":
+			h.Policy = PolicyFail
+		case PolicyFail, PolicyIgnore:
+		default:
+			return nil, errs.Wrapf(ErrHookInvalidConfig, "hook %s has unknown policy '%s'", h.Name, h.Policy)
+		}
+
+		if h.TimeoutSec == 0 {
+			h.TimeoutSec = DefaultTimeoutSec
+		}
+	}
+
+	return &Runner{hooks: hooks}, nil
+}
+
+// Run executes every hook registered on the stage sequentially in the order they are defined.
+// A nil runner is valid and does nothing, so callers don't need to check whether hooks are configured.
+func (r *Runner) Run(ctx context.Context, stage Stage, hctx Context, opts ...RunOption) error {
+	if r == nil {
+		return nil
+	}
+
+	o := newRunOptions(opts...)
+
+	log := o.Logger.WithName("hooks.Run").WithValues("stage", stage)
+
+	hctx.Stage = stage
+	payload, err := json.Marshal(hctx)
+	if err != nil {
+		return err
+	}
+
+	for _, h := range r.hooks {
+		if h.Stage != stage {
+			continue
+		}
+
+		log := log.WithValues("hook", h.Name, "policy", h.Policy)
+
+		if err := r.exec(ctx, h, hctx, payload, o); err != nil {
+			if h.Policy == PolicyIgnore {
+				log.Error(err, "hook failed, ignoring as per policy")
+				continue
+			}
+
+			return errs.Wrap(err, errs.Wrapf(ErrHookFailed, "hook: %s", h.Name))
+		}
+
+		log.V(1).Info("hook executed successfully")
+	}
+
+	return nil
+}
+
+func (r *Runner) exec(ctx context.Context, h Hook, hctx Context, payload []byte, o *RunOptions) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(h.TimeoutSec)*time.Second)
+	defer cancel()
+
+	return cmdutils.Exec(ctx, h.Command,
+		cmdutils.WithShellMode(o.Shell),
+		cmdutils.WithWorkdir(o.Workdir),
+		cmdutils.WithEnv(hctx.environ()...),
+		cmdutils.WithStdin(bytes.NewReader(payload)),
+		cmdutils.WithStdout(o.Stdout),
+		cmdutils.WithStderr(o.Stderr),
+		cmdutils.WithLogger(o.Logger),
+	)
+}
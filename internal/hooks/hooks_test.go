@@ -0,0 +1,83 @@
+package hooks_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/ardikabs/dpl/internal/hooks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("unknown stage", func(t *testing.T) {
+		_, err := hooks.New(hooks.Hook{Stage: "pre-everything", Command: "true"})
+		require.ErrorIs(t, err, hooks.ErrHookInvalidConfig)
+	})
+
+	t.Run("empty command", func(t *testing.T) {
+		_, err := hooks.New(hooks.Hook{Stage: hooks.StagePreRender})
+		require.ErrorIs(t, err, hooks.ErrHookInvalidConfig)
+	})
+
+	t.Run("unknown policy", func(t *testing.T) {
+		_, err := hooks.New(hooks.Hook{Stage: hooks.StagePreRender, Command: "true", Policy: "retry"})
+		require.ErrorIs(t, err, hooks.ErrHookInvalidConfig)
+	})
+}
+
+func TestRunner_Run(t *testing.T) {
+	hctx := hooks.Context{
+		RequestID:   "b6d7153",
+		Release:     "myapp",
+		Environment: "staging",
+		Releases: []hooks.ReleaseContext{
+			{ID: "myapp-dev-1", Cluster: "dev-1"},
+			{ID: "myapp-dev-2", Cluster: "dev-2"},
+		},
+	}
+
+	t.Run("nil runner does nothing", func(t *testing.T) {
+		var r *hooks.Runner
+		require.NoError(t, r.Run(context.TODO(), hooks.StagePreRender, hctx))
+	})
+
+	t.Run("context is given as env vars and stdin", func(t *testing.T) {
+		r, err := hooks.New(
+			hooks.Hook{Stage: hooks.StagePostPush, Command: `printf "%s %s %s " "$DPL_HOOK_STAGE" "$DPL_RELEASE" "$DPL_CLUSTERS"; cat`},
+			hooks.Hook{Stage: hooks.StagePostSync, Command: `echo "must not run"`},
+		)
+		require.NoError(t, err)
+
+		stdout := &bytes.Buffer{}
+		err = r.Run(context.TODO(), hooks.StagePostPush, hctx, hooks.WithOutput(stdout, io.Discard))
+		require.NoError(t, err)
+		require.Contains(t, stdout.String(), "post-push myapp dev-1,dev-2 ")
+		require.Contains(t, stdout.String(), `"requestID":"b6d7153"`)
+	})
+
+	t.Run("failure policy", func(t *testing.T) {
+		r, err := hooks.New(hooks.Hook{Stage: hooks.StagePreRender, Command: "exit 1"})
+		require.NoError(t, err)
+
+		err = r.Run(context.TODO(), hooks.StagePreRender, hctx, hooks.WithOutput(io.Discard, io.Discard))
+		require.ErrorIs(t, err, hooks.ErrHookFailed)
+	})
+
+	t.Run("ignore policy", func(t *testing.T) {
+		r, err := hooks.New(hooks.Hook{Stage: hooks.StagePreRender, Command: "exit 1", Policy: hooks.PolicyIgnore})
+		require.NoError(t, err)
+
+		err = r.Run(context.TODO(), hooks.StagePreRender, hctx, hooks.WithOutput(io.Discard, io.Discard))
+		require.NoError(t, err)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		r, err := hooks.New(hooks.Hook{Stage: hooks.StagePreRender, Command: "exec sleep 5", TimeoutSec: 1})
+		require.NoError(t, err)
+
+		err = r.Run(context.TODO(), hooks.StagePreRender, hctx, hooks.WithOutput(io.Discard, io.Discard))
+		require.ErrorIs(t, err, hooks.ErrHookFailed)
+	})
+}
@@ -0,0 +1,52 @@
+package hooks
+
+import (
+	"io"
+	"os"
+
+	"github.com/go-logr/logr"
+)
+
+type RunOptions struct {
+	Logger  logr.Logger
+	Workdir string
+	Shell   string
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+func newRunOptions(opts ...RunOption) *RunOptions {
+	o := &RunOptions{
+		Shell:  "/bin/sh",
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+type RunOption func(*RunOptions)
+
+func WithLogger(logger logr.Logger) RunOption {
+	return func(o *RunOptions) {
+		o.Logger = logger
+	}
+}
+
+func WithWorkdir(workdir string) RunOption {
+	return func(o *RunOptions) {
+		o.Workdir = workdir
+	}
+}
+
+func WithOutput(stdout, stderr io.Writer) RunOption {
+	return func(o *RunOptions) {
+		o.Stdout = stdout
+		o.Stderr = stderr
+	}
+}
@@ -1,7 +1,9 @@
 package log
 
 import (
+	"io"
 	"log/slog"
+	"os"
 
 	"github.com/go-logr/logr"
 )
@@ -9,13 +11,49 @@ import (
 var (
 	defaultLevel slog.LevelVar
 
-	Logger = logr.FromSlogHandler(newSLogHandler())
+	// Output is where the logs are written to by Configure, besides the log file
+	Output io.Writer = os.Stdout
+
+	Logger = logr.FromSlogHandler(mustNewSLogHandler(FormatText, os.Stdout))
+
+	// loggerFormat and loggerWriter are the ones used by the Logger, kept for New
+	loggerFormat           = FormatText
+	loggerWriter io.Writer = os.Stdout
 )
 
 func SetLevel(lvl int) {
 	defaultLevel.Set(slog.Level(-lvl))
 }
 
+// Configure replaces the Logger with the given format, when the file is set,
+// logs are written to both the Output and the file.
+func Configure(format, file string) error {
+	w := Output
+	if file != "" {
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+
+		w = io.MultiWriter(Output, f)
+	}
+
+	handler, err := newSLogHandler(format, w)
+	if err != nil {
+		return err
+	}
+
+	Logger = logr.FromSlogHandler(handler)
+	loggerFormat, loggerWriter = format, w
+	return nil
+}
+
+// New returns a logger with the same format and destination of the Logger, that also writes to the given writer,
+// for example to capture the logs of a single deployment.
+func New(w io.Writer) logr.Logger {
+	return logr.FromSlogHandler(mustNewSLogHandler(loggerFormat, io.MultiWriter(loggerWriter, w)))
+}
+
 func Info(msg string, keysAndValues ...any) {
 	Logger.Info(msg, keysAndValues...)
 }
@@ -27,3 +65,12 @@ func Error(err error, msg string, keysAndValues ...any) {
 func V(level int) logr.Logger {
 	return Logger.V(level)
 }
+
+func mustNewSLogHandler(format string, w io.Writer) slog.Handler {
+	handler, err := newSLogHandler(format, w)
+	if err != nil {
+		panic(err)
+	}
+
+	return handler
+}
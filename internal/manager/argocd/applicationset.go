@@ -0,0 +1,185 @@
+package argocd
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/ardikabs/dpl/internal/errs"
+	"github.com/ardikabs/dpl/internal/manager"
+	"github.com/ardikabs/dpl/internal/types"
+	applicationsetpkg "github.com/argoproj/argo-cd/v2/pkg/apiclient/applicationset"
+	applicationv1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/go-logr/logr"
+)
+
+const applicationSetKind = "ApplicationSet"
+
+// listApplicationSets returns every ApplicationSet, as they are matched by their template labels as well,
+// which the server side selector doesn't look at.
+func (c *Client) listApplicationSets(ctx context.Context) ([]applicationv1.ApplicationSet, error) {
+	conn, appSetClient, err := c.argocdClient.NewApplicationSetClient()
+	if err != nil {
+		return nil, errs.Wrap(err, ErrConnectionFailed)
+	}
+	defer conn.Close()
+
+	list, err := appSetClient.List(ctx, &applicationsetpkg.ApplicationSetListQuery{})
+	if err != nil {
+		return nil, err
+	}
+
+	return list.Items, nil
+}
+
+// discoverApplicationSets adds the Applications generated by the ApplicationSets matching the request to the listed ones,
+// then returns them along with their owning ApplicationSet by the Application name. The labels of the generated Application
+// are controlled by the template, so it inherits the ApplicationSet labels before it is matched against the request.
+func (c *Client) discoverApplicationSets(ctx context.Context, log logr.Logger, req *manager.ListReleaseRequest, apps []applicationv1.Application) ([]applicationv1.Application, map[string]*applicationv1.ApplicationSet, error) {
+	appsets, err := c.listApplicationSets(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	owners := make(map[string]*applicationv1.ApplicationSet)
+	for _, app := range apps {
+		if appset := ownerApplicationSet(app, appsets); appset != nil {
+			owners[app.Name] = appset
+		}
+	}
+
+	requirements := req.Requirements()
+	for idx := range appsets {
+		appset := &appsets[idx]
+		if !applicationSetMatches(appset, requirements) {
+			continue
+		}
+
+		children, err := c.listApplications(ctx, templateSelector(appset))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var generated int
+		for _, child := range children {
+			if _, ok := owners[child.Name]; ok || ownerApplicationSet(child, appsets) != appset {
+				continue
+			}
+
+			labels := maps.Clone(appset.Labels)
+			if labels == nil {
+				labels = make(map[string]string, len(child.Labels))
+			}
+			maps.Copy(labels, child.Labels)
+
+			if !labelsMatch(labels, requirements) {
+				continue
+			}
+
+			child.Labels = labels
+			apps = append(apps, child)
+			owners[child.Name] = appset
+			generated++
+		}
+
+		log.V(1).Info("applicationset is matched", "applicationset", appset.Name, "applications", generated)
+	}
+
+	return apps, owners, nil
+}
+
+// ownerApplicationSet returns the ApplicationSet generating the Application, nil when it isn't generated
+func ownerApplicationSet(app applicationv1.Application, appsets []applicationv1.ApplicationSet) *applicationv1.ApplicationSet {
+	for _, ref := range app.OwnerReferences {
+		if ref.Kind != applicationSetKind {
+			continue
+		}
+
+		for idx := range appsets {
+			if appsets[idx].Name == ref.Name && (ref.UID == "" || appsets[idx].UID == ref.UID) {
+				return &appsets[idx]
+			}
+		}
+	}
+
+	return nil
+}
+
+// applicationSetMatches returns true when every requirement is met by either the ApplicationSet labels or its template labels,
+// the templated label is left to the generated Applications to be matched.
+func applicationSetMatches(appset *applicationv1.ApplicationSet, requirements map[string]string) bool {
+	for key, value := range requirements {
+		if appset.Labels[key] == value {
+			continue
+		}
+
+		if tmpl, ok := appset.Spec.Template.Labels[key]; ok && (tmpl == value || isTemplated(tmpl)) {
+			continue
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// templateSelector returns the selector of the static template labels, to narrow down the generated Applications
+func templateSelector(appset *applicationv1.ApplicationSet) string {
+	var selectors []string
+	for key, value := range appset.Spec.Template.Labels {
+		if !isTemplated(value) {
+			selectors = append(selectors, key+"="+value)
+		}
+	}
+
+	slices.Sort(selectors)
+	return strings.Join(selectors, ",")
+}
+
+func labelsMatch(labels map[string]string, requirements map[string]string) bool {
+	for key, value := range requirements {
+		if labels[key] != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isTemplated(value string) bool {
+	return strings.Contains(value, "{{")
+}
+
+// gitFilesGenerator returns the first git files generator of the ApplicationSet, nil when there is none
+func gitFilesGenerator(appset *applicationv1.ApplicationSet) *types.GitFilesGenerator {
+	for _, generator := range appset.Spec.Generators {
+		git := generator.Git
+		if git == nil || len(git.Files) == 0 {
+			continue
+		}
+
+		// the template of the generator overrides the template of the ApplicationSet
+		nameTemplate := appset.Spec.Template.Name
+		if git.Template.Name != "" {
+			nameTemplate = git.Template.Name
+		}
+
+		files := make([]string, 0, len(git.Files))
+		for _, file := range git.Files {
+			files = append(files, file.Path)
+		}
+
+		return &types.GitFilesGenerator{
+			RepoURL:           git.RepoURL,
+			Revision:          git.Revision,
+			Files:             files,
+			NameTemplate:      nameTemplate,
+			GoTemplate:        appset.Spec.GoTemplate,
+			GoTemplateOptions: appset.Spec.GoTemplateOptions,
+			PathParamPrefix:   git.PathParamPrefix,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package argocd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/types"
+	"github.com/argoproj/argo-cd/v2/pkg/apiclient"
+	applicationpkg "github.com/argoproj/argo-cd/v2/pkg/apiclient/application"
+	applicationsetpkg "github.com/argoproj/argo-cd/v2/pkg/apiclient/applicationset"
+	sessionpkg "github.com/argoproj/argo-cd/v2/pkg/apiclient/session"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// loginTimeout bounds the session login
+const loginTimeout = 30 * time.Second
+
+// apiClient opens the connections to Argo CD with the fresh credentials, as the token file might be rotated
+// and the session might be expired since the previous connection.
+type apiClient struct {
+	cfg types.ArgoConfig
+}
+
+func newAPIClient(cfg types.ArgoConfig) (*apiClient, error) {
+	// the client options are checked upfront, such as the certificate files
+	if _, err := apiclient.NewClient(options(cfg, cfg.Secret.Token)); err != nil {
+		return nil, err
+	}
+
+	return &apiClient{cfg: cfg}, nil
+}
+
+func (a *apiClient) NewApplicationClient() (io.Closer, applicationpkg.ApplicationServiceClient, error) {
+	token, err := a.token()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cl, err := apiclient.NewClient(options(a.cfg, token))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cl.NewApplicationClient()
+}
+
+func (a *apiClient) NewApplicationSetClient() (io.Closer, applicationsetpkg.ApplicationSetServiceClient, error) {
+	token, err := a.token()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cl, err := apiclient.NewClient(options(a.cfg, token))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cl.NewApplicationSetClient()
+}
+
+// token returns the token from the token file or the session login, otherwise the static token
+func (a *apiClient) token() (string, error) {
+	secret := a.cfg.Secret
+
+	switch {
+	case secret.TokenFile != "":
+		content, err := os.ReadFile(secret.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token file: %w", err)
+		}
+
+		token := strings.TrimSpace(string(content))
+		if token == "" {
+			return "", errors.New("token file is empty")
+		}
+
+		return token, nil
+	case secret.Username != "":
+		return a.login()
+	default:
+		return secret.Token, nil
+	}
+}
+
+// login creates a session with the username and password, then returns its token
+func (a *apiClient) login() (string, error) {
+	cl, err := apiclient.NewClient(options(a.cfg, ""))
+	if err != nil {
+		return "", err
+	}
+
+	conn, sessionClient, err := cl.NewSessionClient()
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), loginTimeout)
+	defer cancel()
+
+	res, err := sessionClient.Create(ctx, &sessionpkg.SessionCreateRequest{
+		Username: a.cfg.Secret.Username,
+		Password: a.cfg.Secret.Password,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to log in as %s: %w", a.cfg.Secret.Username, err)
+	}
+
+	return res.GetToken(), nil
+}
+
+func options(cfg types.ArgoConfig, token string) *apiclient.ClientOptions {
+	return &apiclient.ClientOptions{
+		ServerAddr:        cfg.Host,
+		PlainText:         cfg.PlainText,
+		Insecure:          cfg.Insecure,
+		GRPCWeb:           cfg.GRPCWeb,
+		CertFile:          cfg.CACertFile,
+		ClientCertFile:    cfg.ClientCertFile,
+		ClientCertKeyFile: cfg.ClientKeyFile,
+		AuthToken:         token,
+	}
+}
+
+// reauthenticate drops the connection whose session is expired, so the next use reconnects with the fresh credentials,
+// the dropped connection is closed along with the client, as the other calls might still be using it.
+func (c *Client) reauthenticate(expired applicationpkg.ApplicationServiceClient) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.appClient != expired || c.conn == nil {
+		return
+	}
+
+	c.expiredConns = append(c.expiredConns, c.conn)
+	c.conn, c.appClient = nil, nil
+}
+
+// call runs the call with the application client, it is retried once on the new connection when the session is expired
+func (c *Client) call(fn func(appClient applicationpkg.ApplicationServiceClient) error) error {
+	appClient, err := c.applicationClient()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(appClient); !isUnauthenticated(err) {
+		return err
+	}
+
+	c.reauthenticate(appClient)
+	if appClient, err = c.applicationClient(); err != nil {
+		return err
+	}
+
+	return fn(appClient)
+}
+
+func isUnauthenticated(err error) bool {
+	return err != nil && status.Code(err) == codes.Unauthenticated
+}
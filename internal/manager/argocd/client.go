@@ -2,22 +2,30 @@ package argocd
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/ardikabs/dpl/internal/errs"
 	"github.com/ardikabs/dpl/internal/manager"
 	"github.com/ardikabs/dpl/internal/tools/retry"
+	"github.com/ardikabs/dpl/internal/tracing"
 	"github.com/ardikabs/dpl/internal/types"
-	"github.com/argoproj/argo-cd/v2/pkg/apiclient"
 	applicationpkg "github.com/argoproj/argo-cd/v2/pkg/apiclient/application"
+	applicationsetpkg "github.com/argoproj/argo-cd/v2/pkg/apiclient/applicationset"
 	applicationv1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
 	synccommon "github.com/argoproj/gitops-engine/pkg/sync/common"
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
 )
 
@@ -30,40 +38,91 @@ var (
 	ErrSyncOperationTimeout       = errors.New("sync operation timeout is exceeded")
 	ErrSyncOnWatchTimeout         = errors.New("watch operation timeout is exceeded")
 	ErrSyncFailed                 = errors.New("sync failed")
+	ErrSyncReleasesFailed         = errors.New("one or more releases failed to sync")
+	ErrConnectionFailed           = errors.New("failed to connect to argocd")
+	ErrSyncCancelled              = errors.New("sync is cancelled")
+	ErrRolloutAborted             = errors.New("rollout is aborted")
+	ErrInstanceNotFound           = errors.New("argocd instance not found")
+	ErrInvalidInstanceConfig      = errors.New("invalid argocd instance config")
+)
+
+var (
+	// syncRetryInterval is the interval of triggering the sync again while another sync operation is in progress
+	syncRetryInterval = time.Second
+	// abandonTimeout bounds the cleanup of the sync which is cancelled or timed out
+	abandonTimeout = 30 * time.Second
 )
 
 type client interface {
-	NewApplicationClientOrDie() (io.Closer, applicationpkg.ApplicationServiceClient)
-	WatchApplicationWithRetry(ctx context.Context, appName string, revision string) chan *applicationv1.ApplicationWatchEvent
+	NewApplicationClient() (io.Closer, applicationpkg.ApplicationServiceClient, error)
+	NewApplicationSetClient() (io.Closer, applicationsetpkg.ApplicationSetServiceClient, error)
 }
 
+// Client shares a single connection to Argo CD, it is opened on the first use and kept until closed
 type Client struct {
 	argocdClient client
+	name         string
+
+	mu           sync.Mutex
+	conn         io.Closer
+	appClient    applicationpkg.ApplicationServiceClient
+	expiredConns []io.Closer
 }
 
 func NewClient(cfg types.ArgoConfig) (*Client, error) {
-	cl, err := apiclient.NewClient(&apiclient.ClientOptions{
-		ServerAddr: cfg.Host,
-		PlainText:  cfg.PlainText,
-		Insecure:   cfg.Insecure,
-		GRPCWeb:    cfg.GRPCWeb,
-		AuthToken:  cfg.Secret.Token,
-	})
+	cl, err := newAPIClient(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Client{argocdClient: cl}, nil
+	return &Client{argocdClient: cl, name: cfg.Name}, nil
 }
 
-func (c *Client) listApplications(ctx context.Context, selector string) ([]applicationv1.Application, error) {
-	con, appClient := c.argocdClient.NewApplicationClientOrDie()
-	defer con.Close()
+// applicationClient returns the application client of the shared connection
+func (c *Client) applicationClient() (applicationpkg.ApplicationServiceClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	appList, err := appClient.List(ctx, &applicationpkg.ApplicationQuery{
-		Selector: ptr.To(selector),
-	})
+	if c.appClient != nil {
+		return c.appClient, nil
+	}
+
+	conn, appClient, err := c.argocdClient.NewApplicationClient()
 	if err != nil {
+		return nil, errs.Wrap(err, ErrConnectionFailed)
+	}
+
+	c.conn, c.appClient = conn, appClient
+	return appClient, nil
+}
+
+// Close closes the shared connection, the next use reopens it
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []error
+	for _, conn := range c.expiredConns {
+		errs = append(errs, conn.Close())
+	}
+	c.expiredConns = nil
+
+	if c.conn != nil {
+		errs = append(errs, c.conn.Close())
+		c.conn, c.appClient = nil, nil
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *Client) listApplications(ctx context.Context, selector string) ([]applicationv1.Application, error) {
+	var appList *applicationv1.ApplicationList
+	if err := c.call(func(appClient applicationpkg.ApplicationServiceClient) (err error) {
+		appList, err = appClient.List(ctx, &applicationpkg.ApplicationQuery{
+			Selector: ptr.To(selector),
+		})
+		return err
+	}); err != nil {
 		return nil, err
 	}
 
@@ -71,21 +130,24 @@ func (c *Client) listApplications(ctx context.Context, selector string) ([]appli
 }
 
 func (c *Client) getApplication(ctx context.Context, appName string) (*applicationv1.Application, error) {
-	con, appClient := c.argocdClient.NewApplicationClientOrDie()
-	defer con.Close()
-
-	app, err := appClient.Get(ctx, &applicationpkg.ApplicationQuery{
-		Name:    ptr.To(appName),
-		Refresh: ptr.To("true"),
-	})
-	if err != nil {
+	var app *applicationv1.Application
+	if err := c.call(func(appClient applicationpkg.ApplicationServiceClient) (err error) {
+		app, err = appClient.Get(ctx, &applicationpkg.ApplicationQuery{
+			Name:    ptr.To(appName),
+			Refresh: ptr.To("true"),
+		})
+		return err
+	}); err != nil {
 		return nil, err
 	}
 
 	return app, nil
 }
 
-func (c *Client) ListReleases(ctx context.Context, req *manager.ListReleaseRequest, opts ...manager.Option) (types.ListReleases, error) {
+func (c *Client) ListReleases(ctx context.Context, req *manager.ListReleaseRequest, opts ...manager.Option) (_ types.ListReleases, err error) {
+	ctx, span := tracing.Start(ctx, "Manager.ListReleases", attribute.String("argocd.selector", req.Selector))
+	defer tracing.End(span, &err)
+
 	o := manager.NewDefaultOptions(opts...)
 
 	log := o.Logger.WithName("argocd.ListReleases").WithValues("selector", req.Selector)
@@ -94,34 +156,142 @@ func (c *Client) ListReleases(ctx context.Context, req *manager.ListReleaseReque
 		return nil, err
 	}
 
+	var owners map[string]*applicationv1.ApplicationSet
+	if o.ApplicationSets {
+		if apps, owners, err = c.discoverApplicationSets(ctx, log, req, apps); err != nil {
+			return nil, err
+		}
+	}
+
 	log.V(1).Info("releases found", "releases", len(apps))
-	return appsToReleases(req, apps)
+	rels, err := appsToReleases(req, apps)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rel := range rels {
+		rel.Instance = c.name
+
+		if appset, ok := owners[rel.ID]; ok {
+			rel.ApplicationSet = appset.Name
+			rel.Generator = gitFilesGenerator(appset)
+		}
+	}
+
+	return rels, nil
 }
 
-func (c *Client) SyncReleases(ctx context.Context, rels types.ListReleases, opts ...manager.Option) error {
+func (c *Client) SyncReleases(ctx context.Context, rels types.ListReleases, opts ...manager.Option) (err error) {
+	ctx, span := tracing.Start(ctx, "Manager.SyncReleases", attribute.Int("argocd.applications", len(rels)))
+	defer tracing.End(span, &err)
+
 	o := manager.NewDefaultOptions(opts...)
 
 	log := o.Logger.WithName("argocd.SyncReleases")
 
-	g, ctx := errgroup.WithContext(ctx)
-	for _, rel := range rels {
-		rel := rel
+	// the rest of the options, such as the timeout, apply to every release
+	syncOpts := append(slices.Clone(opts), manager.WithLogger(log))
 
-		g.Go(func() error {
-			if err := c.SyncRelease(ctx, rel, manager.WithLogger(log)); err != nil {
-				log.Error(err, "sync operation failed", "argocd_application", rel.ID, "cluster", rel.Cluster)
-				return err
+	// every release is watched through a single stream
+	w, err := c.startWatch(ctx, log, &applicationpkg.ApplicationQuery{Selector: ptr.To(releasesSelector(rels))})
+	if err != nil {
+		return err
+	}
+	defer w.stop()
+
+	return syncWaves(ctx, log, rels, o, func(ctx context.Context, rel *types.Release) error {
+		return c.syncRelease(ctx, rel, w, syncOpts...)
+	})
+}
+
+// syncWaves runs the sync of the releases in waves, concurrently within a wave, as tuned by the options
+func syncWaves(ctx context.Context, log logr.Logger, rels types.ListReleases, o *manager.Options, syncFn func(ctx context.Context, rel *types.Release) error) error {
+	var (
+		mu       sync.Mutex
+		failures []error
+		started  int
+	)
+
+	waves := orderReleases(rels, o.SyncOrderLabel, o.SyncOrder)
+	for i, wave := range waves {
+		if len(waves) > 1 {
+			log.Info("syncing releases wave", "wave", i+1, "waves", len(waves), "releases", len(wave))
+		}
+
+		// the first failure cancels the rest, unless every sync is kept going
+		g, gctx := errgroup.WithContext(ctx)
+		if o.KeepGoing {
+			g, gctx = new(errgroup.Group), ctx
+		}
+
+		if o.MaxConcurrency > 0 {
+			g.SetLimit(o.MaxConcurrency)
+		}
+
+		for _, rel := range wave {
+			rel := rel
+
+			if started > 0 && o.SyncDelay > 0 {
+				select {
+				case <-gctx.Done():
+				case <-time.After(o.SyncDelay):
+				}
 			}
-			return nil
-		})
+			started++
+
+			g.Go(func() error {
+				if err := syncFn(gctx, rel); err != nil {
+					log.Error(err, "sync operation failed", "argocd_application", rel.ID, "cluster", rel.Cluster)
+					if !o.KeepGoing {
+						return err
+					}
+
+					mu.Lock()
+					failures = append(failures, fmt.Errorf("%s on %s: %w", rel.ID, rel.Cluster, err))
+					mu.Unlock()
+				}
+				return nil
+			})
+		}
+
+		if err := g.Wait(); err != nil {
+			return err
+		}
+	}
+
+	if len(failures) > 0 {
+		return errs.Wrap(errors.Join(failures...), ErrSyncReleasesFailed)
 	}
 
-	return g.Wait()
+	return nil
 }
 
 func (c *Client) SyncRelease(ctx context.Context, rel *types.Release, opts ...manager.Option) error {
 	o := manager.NewDefaultOptions(opts...)
 
+	w, err := c.startWatch(ctx, o.Logger, &applicationpkg.ApplicationQuery{Name: ptr.To(rel.ID)})
+	if err != nil {
+		return err
+	}
+	defer w.stop()
+
+	return c.syncRelease(ctx, rel, w, opts...)
+}
+
+// syncRelease triggers the sync of the release, then waits for it through the watcher
+func (c *Client) syncRelease(ctx context.Context, rel *types.Release, w *watcher, opts ...manager.Option) (err error) {
+	ctx, span := tracing.Start(ctx, "Manager.SyncRelease",
+		attribute.String("argocd.application", rel.ID),
+		attribute.String("dpl.cluster", rel.Cluster),
+	)
+	defer tracing.End(span, &err)
+
+	defer func() {
+		rel.Status.Result, rel.Status.Message = syncResult(rel.Status, err)
+	}()
+
+	o := manager.NewDefaultOptions(opts...)
+
 	log := o.Logger.
 		WithName("argocd.SyncRelease").
 		WithValues(
@@ -129,21 +299,29 @@ func (c *Client) SyncRelease(ctx context.Context, rel *types.Release, opts ...ma
 			"cluster", rel.Cluster,
 		)
 
-	conn, appClient := c.argocdClient.NewApplicationClientOrDie()
-	defer conn.Close()
+	if rel.Instance != "" {
+		log = log.WithValues("argocd_instance", rel.Instance)
+	}
 
 	currentApp, err := c.getApplication(ctx, rel.ID)
 	if err != nil {
 		return err
 	}
 
+	// subscribed before the sync is triggered, so none of its progress is missed
+	events, unsubscribe := w.subscribe(rel.ID)
+	defer unsubscribe()
+
 	if err := retry.OnError(ctx, func(err error) bool {
 		if errs.IsAny(err, ErrAnotherSyncInProgress) {
 			return true
 		}
 		return false
 	}, func(ctx context.Context) error {
-		if _, err := appClient.Sync(ctx, &applicationpkg.ApplicationSyncRequest{Name: ptr.To(rel.ID)}); err != nil {
+		if err := c.call(func(appClient applicationpkg.ApplicationServiceClient) error {
+			_, err := appClient.Sync(ctx, newSyncRequest(rel.ID, o))
+			return err
+		}); err != nil {
 			status, ok := status.FromError(err)
 			if !ok {
 				return err
@@ -160,7 +338,7 @@ func (c *Client) SyncRelease(ctx context.Context, rel *types.Release, opts ...ma
 
 		return nil
 	},
-		retry.WithRetryIntervalSec(1),
+		retry.WithRetryInterval(syncRetryInterval),
 		retry.WithRetryTimoutSec(int(o.TimeoutSec)),
 		retry.WithLogger(log),
 	); err != nil {
@@ -174,9 +352,43 @@ func (c *Client) SyncRelease(ctx context.Context, rel *types.Release, opts ...ma
 
 	log.Info("application sync is triggered")
 
-	if err := c.watch(ctx, currentApp, watchOnSync,
+	// the states observed before the sync is triggered are stale
+	select {
+	case <-events:
+	default:
+	}
+
+	condition := watchOnSync
+	if o.DryRun || len(o.SyncResources) > 0 {
+		condition = watchOnOperation(currentApp.Status.OperationState, !o.DryRun)
+	}
+
+	rollouts := c.newRolloutGate(ctx, o.RolloutPaused)
+
+	lastStatus, err := c.watch(ctx, currentApp, events, rollouts.condition(condition),
 		manager.WithTimeoutSec(o.TimeoutSec),
-		manager.WithLogger(log)); err != nil {
+		manager.WithLogger(log))
+
+	if errs.IsAny(err, ErrSyncOnWatchTimeout, ErrSyncCancelled) {
+		lastStatus = c.abandonSync(ctx, log, rel.ID, lastStatus, o.TerminateOnCancel)
+	}
+
+	rel.Status = types.ReleaseStatus{
+		Sync:    string(lastStatus.Sync.Status),
+		Health:  string(lastStatus.Health.Status),
+		Message: rollouts.paused,
+	}
+
+	if err != nil {
+		if errs.IsAny(err, ErrSyncCancelled) {
+			return err
+		}
+
+		rel.Status.Diagnostics = c.diagnose(ctx, log, rel.ID, lastStatus)
+		if len(rel.Status.Diagnostics) > 0 {
+			return fmt.Errorf("%w: %s", err, diagnosticsSummary(rel.Status.Diagnostics))
+		}
+
 		return err
 	}
 
@@ -184,6 +396,183 @@ func (c *Client) SyncRelease(ctx context.Context, rel *types.Release, opts ...ma
 	return nil
 }
 
+// abandonSync terminates the running sync operation when it is enabled, then returns the final application status,
+// it outlives the cancellation so the final state is still reported.
+func (c *Client) abandonSync(ctx context.Context, log logr.Logger, appName string, lastStatus applicationv1.ApplicationStatus, terminate bool) applicationv1.ApplicationStatus {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), abandonTimeout)
+	defer cancel()
+
+	if terminate {
+		if err := c.call(func(appClient applicationpkg.ApplicationServiceClient) error {
+			_, err := appClient.TerminateOperation(ctx, &applicationpkg.OperationTerminateRequest{Name: ptr.To(appName)})
+			return err
+		}); err != nil {
+			// the operation might be completed in the meantime
+			log.Error(err, "failed to terminate sync operation")
+		} else {
+			log.Info("sync operation is terminated")
+		}
+	}
+
+	var app *applicationv1.Application
+	if err := c.call(func(appClient applicationpkg.ApplicationServiceClient) (err error) {
+		app, err = appClient.Get(ctx, &applicationpkg.ApplicationQuery{Name: ptr.To(appName)})
+		return err
+	}); err != nil {
+		log.Error(err, "failed to get the final application state")
+		return lastStatus
+	}
+
+	var phase synccommon.OperationPhase
+	if app.Status.OperationState != nil {
+		phase = app.Status.OperationState.Phase
+	}
+
+	log.Info("final application state",
+		"sync.status", app.Status.Sync.Status,
+		"health.status", app.Status.Health.Status,
+		"operation.phase", phase,
+	)
+
+	return app.Status
+}
+
+func (c *Client) CreateRelease(ctx context.Context, rel *types.Release, opts ...manager.Option) error {
+	o := manager.NewDefaultOptions(opts...)
+
+	log := o.Logger.
+		WithName("argocd.CreateRelease").
+		WithValues(
+			"argocd_application", rel.ID,
+			"cluster", rel.Cluster,
+		)
+
+	app := &applicationv1.Application{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   rel.ID,
+			Labels: rel.Labels,
+		},
+		Spec: applicationv1.ApplicationSpec{
+			Project: rel.Project,
+			Source: &applicationv1.ApplicationSource{
+				RepoURL:        rel.GitURL,
+				Path:           rel.GitPath,
+				TargetRevision: rel.GitRevision,
+			},
+			Destination: applicationv1.ApplicationDestination{
+				Server:    rel.DestinationServer,
+				Name:      rel.DestinationName,
+				Namespace: rel.Namespace,
+			},
+			SyncPolicy: &applicationv1.SyncPolicy{
+				SyncOptions: applicationv1.SyncOptions{"CreateNamespace=true"},
+			},
+		},
+	}
+
+	if err := c.call(func(appClient applicationpkg.ApplicationServiceClient) error {
+		_, err := appClient.Create(ctx, &applicationpkg.ApplicationCreateRequest{
+			Application: app,
+			Upsert:      ptr.To(true),
+		})
+		return err
+	}); err != nil {
+		return err
+	}
+
+	log.Info("application is created")
+	return nil
+}
+
+func (c *Client) DeleteRelease(ctx context.Context, rel *types.Release, opts ...manager.Option) error {
+	o := manager.NewDefaultOptions(opts...)
+
+	log := o.Logger.
+		WithName("argocd.DeleteRelease").
+		WithValues(
+			"argocd_application", rel.ID,
+			"cluster", rel.Cluster,
+			"cascade", o.Cascade,
+		)
+
+	if err := c.call(func(appClient applicationpkg.ApplicationServiceClient) error {
+		_, err := appClient.Delete(ctx, &applicationpkg.ApplicationDeleteRequest{
+			Name:    ptr.To(rel.ID),
+			Cascade: ptr.To(o.Cascade),
+		})
+		return err
+	}); err != nil {
+		if status.Code(err) == codes.NotFound {
+			log.V(1).Info("application is already deleted")
+			return nil
+		}
+
+		return err
+	}
+
+	log.Info("application is deleted")
+	return nil
+}
+
+// AnnotateRelease merges the annotations into the Application metadata, the other annotations are kept.
+func (c *Client) AnnotateRelease(ctx context.Context, rel *types.Release, annotations map[string]string, opts ...manager.Option) error {
+	o := manager.NewDefaultOptions(opts...)
+
+	log := o.Logger.
+		WithName("argocd.AnnotateRelease").
+		WithValues(
+			"argocd_application", rel.ID,
+			"cluster", rel.Cluster,
+		)
+
+	// the empty value removes the annotation, so the stale value of the previous deployment isn't left behind
+	values := make(map[string]any, len(annotations))
+	for k, v := range annotations {
+		if v == "" {
+			values[k] = nil
+			continue
+		}
+
+		values[k] = v
+	}
+
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": values,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := c.call(func(appClient applicationpkg.ApplicationServiceClient) error {
+		_, err := appClient.Patch(ctx, &applicationpkg.ApplicationPatchRequest{
+			Name:      ptr.To(rel.ID),
+			Patch:     ptr.To(string(patch)),
+			PatchType: ptr.To("merge"),
+		})
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if rel.Annotations == nil {
+		rel.Annotations = make(map[string]string, len(annotations))
+	}
+
+	for k, v := range annotations {
+		if v == "" {
+			delete(rel.Annotations, k)
+			continue
+		}
+
+		rel.Annotations[k] = v
+	}
+
+	log.V(1).Info("application is annotated")
+	return nil
+}
+
 func watchOnSync(log logr.Logger, app applicationv1.Application) (bool, error) {
 	good, err := checkAppStatus(log, app)
 	if err != nil {
@@ -210,3 +599,40 @@ func watchOnSync(log logr.Logger, app applicationv1.Application) (bool, error) {
 
 	return good, nil
 }
+
+// watchOnOperation completes once the sync operation started after the previous one succeeded, and the application
+// is healthy when it is required, as the dry-run or the selective sync leaves the application out of sync.
+func watchOnOperation(previous *applicationv1.OperationState, requireHealthy bool) appConditionFunc {
+	var previousStartedAt metav1.Time
+	if previous != nil {
+		previousStartedAt = previous.StartedAt
+	}
+
+	return func(log logr.Logger, app applicationv1.Application) (bool, error) {
+		state := app.Status.OperationState
+		if state == nil || !state.StartedAt.After(previousStartedAt.Time) {
+			log.V(1).Info("sync operation is not started yet")
+			return false, nil
+		}
+
+		switch state.Phase {
+		case synccommon.OperationError, synccommon.OperationFailed:
+			return watchOnSync(log, app)
+		case synccommon.OperationSucceeded:
+		default:
+			log.V(1).Info("sync operation is on progress", "operation.phase", state.Phase)
+			return false, nil
+		}
+
+		if !requireHealthy {
+			return true, nil
+		}
+
+		healthy, err := checkAppHealthStatus(log, app)
+		if err != nil {
+			return false, nil
+		}
+
+		return healthy, nil
+	}
+}
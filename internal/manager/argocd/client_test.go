@@ -0,0 +1,850 @@
+package argocd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/manager"
+	"github.com/ardikabs/dpl/internal/types"
+	applicationpkg "github.com/argoproj/argo-cd/v2/pkg/apiclient/application"
+	applicationsetpkg "github.com/argoproj/argo-cd/v2/pkg/apiclient/applicationset"
+	applicationv1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/gitops-engine/pkg/health"
+	synccommon "github.com/argoproj/gitops-engine/pkg/sync/common"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+)
+
+// fakeClient syncs every application successfully after the sync duration, unless it is failing,
+// the synced applications are sent to the watch streams.
+type fakeClient struct {
+	duration time.Duration
+	failing  map[string]bool
+	hanging  map[string]bool
+	degraded map[string]bool
+	paused   map[string]bool
+
+	// expired is the number of the connections whose session is expired
+	expired int
+
+	// applications are listed by any selector
+	applications []string
+
+	// appsets are the ApplicationSets, and generated are their Applications listed by the matching selector
+	appsets   []applicationv1.ApplicationSet
+	generated []applicationv1.Application
+
+	mu          sync.Mutex
+	apps        map[string]*applicationv1.Application
+	connections int
+	streams     []chan *applicationv1.ApplicationWatchEvent
+	synced      []string
+	requests    []*applicationpkg.ApplicationSyncRequest
+	syncedAt    []time.Time
+	terminated  []string
+	actions     []string
+	patches     []string
+	created     []*applicationpkg.ApplicationCreateRequest
+	deleted     []*applicationpkg.ApplicationDeleteRequest
+	active      int
+	maxActive   int
+}
+
+func (f *fakeClient) NewApplicationClient() (io.Closer, applicationpkg.ApplicationServiceClient, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.connections++
+	expired := f.expired > 0
+	if expired {
+		f.expired--
+	}
+
+	return io.NopCloser(nil), &fakeApplicationClient{fake: f, expired: expired}, nil
+}
+
+func (f *fakeClient) NewApplicationSetClient() (io.Closer, applicationsetpkg.ApplicationSetServiceClient, error) {
+	return io.NopCloser(nil), &fakeApplicationSetClient{fake: f}, nil
+}
+
+// complete finishes the sync operation, the dry-run leaves the application out of sync
+func (f *fakeClient) complete(req *applicationpkg.ApplicationSyncRequest) {
+	startedAt := metav1.Now()
+	time.Sleep(f.duration)
+
+	app := newFakeApplication(req.GetName())
+	app.Status.Sync = applicationv1.SyncStatus{Status: applicationv1.SyncStatusCodeSynced, Revision: "main"}
+	app.Status.Health = applicationv1.HealthStatus{Status: health.HealthStatusHealthy}
+	app.Status.OperationState = &applicationv1.OperationState{Phase: synccommon.OperationSucceeded, StartedAt: startedAt}
+
+	if req.GetDryRun() {
+		app.Status.Sync.Status = applicationv1.SyncStatusCodeOutOfSync
+	}
+
+	if f.degraded[req.GetName()] {
+		app.Status.Health.Status = health.HealthStatusDegraded
+	}
+
+	f.mu.Lock()
+	if f.paused[req.GetName()] {
+		app.Status.Health.Status = health.HealthStatusSuspended
+	}
+	f.mu.Unlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.active--
+	if f.apps == nil {
+		f.apps = make(map[string]*applicationv1.Application)
+	}
+	f.apps[app.Name] = app
+
+	for _, stream := range f.streams {
+		stream <- &applicationv1.ApplicationWatchEvent{Application: *app}
+	}
+}
+
+type fakeWatchClient struct {
+	grpc.ClientStream
+
+	ctx    context.Context
+	events chan *applicationv1.ApplicationWatchEvent
+}
+
+func (w *fakeWatchClient) Recv() (*applicationv1.ApplicationWatchEvent, error) {
+	select {
+	case <-w.ctx.Done():
+		return nil, w.ctx.Err()
+	case ev := <-w.events:
+		return ev, nil
+	}
+}
+
+type fakeApplicationClient struct {
+	applicationpkg.ApplicationServiceClient
+
+	fake    *fakeClient
+	expired bool
+}
+
+var errSessionExpired = status.Error(codes.Unauthenticated, "invalid session: token has invalid claims: token is expired")
+
+func (c *fakeApplicationClient) Get(ctx context.Context, in *applicationpkg.ApplicationQuery, opts ...grpc.CallOption) (*applicationv1.Application, error) {
+	c.fake.mu.Lock()
+	defer c.fake.mu.Unlock()
+
+	if app, ok := c.fake.apps[in.GetName()]; ok {
+		return app, nil
+	}
+
+	return newFakeApplication(in.GetName()), nil
+}
+
+func (c *fakeApplicationClient) List(ctx context.Context, in *applicationpkg.ApplicationQuery, opts ...grpc.CallOption) (*applicationv1.ApplicationList, error) {
+	list := &applicationv1.ApplicationList{}
+	for _, name := range c.fake.applications {
+		list.Items = append(list.Items, *newFakeApplication(name))
+	}
+
+	requirements := make(map[string]string)
+	for _, selector := range strings.Split(in.GetSelector(), ",") {
+		if key, value, ok := strings.Cut(selector, "="); ok {
+			requirements[key] = value
+		}
+	}
+
+	for _, app := range c.fake.generated {
+		if labelsMatch(app.Labels, requirements) {
+			list.Items = append(list.Items, app)
+		}
+	}
+
+	return list, nil
+}
+
+type fakeApplicationSetClient struct {
+	applicationsetpkg.ApplicationSetServiceClient
+
+	fake *fakeClient
+}
+
+func (c *fakeApplicationSetClient) List(ctx context.Context, in *applicationsetpkg.ApplicationSetListQuery, opts ...grpc.CallOption) (*applicationv1.ApplicationSetList, error) {
+	return &applicationv1.ApplicationSetList{Items: c.fake.appsets}, nil
+}
+
+func (c *fakeApplicationClient) Watch(ctx context.Context, in *applicationpkg.ApplicationQuery, opts ...grpc.CallOption) (applicationpkg.ApplicationService_WatchClient, error) {
+	if c.expired {
+		return nil, errSessionExpired
+	}
+
+	c.fake.mu.Lock()
+	defer c.fake.mu.Unlock()
+
+	events := make(chan *applicationv1.ApplicationWatchEvent, 100)
+	c.fake.streams = append(c.fake.streams, events)
+	return &fakeWatchClient{ctx: ctx, events: events}, nil
+}
+
+func (c *fakeApplicationClient) Sync(ctx context.Context, in *applicationpkg.ApplicationSyncRequest, opts ...grpc.CallOption) (*applicationv1.Application, error) {
+	if c.expired {
+		return nil, errSessionExpired
+	}
+
+	if c.fake.failing[in.GetName()] {
+		return nil, status.Error(codes.Internal, "sync is rejected")
+	}
+
+	c.fake.mu.Lock()
+	defer c.fake.mu.Unlock()
+
+	c.fake.synced = append(c.fake.synced, in.GetName())
+	c.fake.requests = append(c.fake.requests, in)
+	c.fake.syncedAt = append(c.fake.syncedAt, time.Now())
+	c.fake.active++
+	c.fake.maxActive = max(c.fake.maxActive, c.fake.active)
+
+	if !c.fake.hanging[in.GetName()] {
+		go c.fake.complete(in)
+	}
+
+	return newFakeApplication(in.GetName()), nil
+}
+
+func (c *fakeApplicationClient) Create(ctx context.Context, in *applicationpkg.ApplicationCreateRequest, opts ...grpc.CallOption) (*applicationv1.Application, error) {
+	c.fake.mu.Lock()
+	defer c.fake.mu.Unlock()
+
+	c.fake.created = append(c.fake.created, in)
+	return in.GetApplication(), nil
+}
+
+func (c *fakeApplicationClient) Delete(ctx context.Context, in *applicationpkg.ApplicationDeleteRequest, opts ...grpc.CallOption) (*applicationpkg.ApplicationResponse, error) {
+	c.fake.mu.Lock()
+	defer c.fake.mu.Unlock()
+
+	if !slices.Contains(c.fake.applications, in.GetName()) {
+		return nil, status.Errorf(codes.NotFound, "applications.argoproj.io %q not found", in.GetName())
+	}
+
+	c.fake.deleted = append(c.fake.deleted, in)
+	return &applicationpkg.ApplicationResponse{}, nil
+}
+
+func (c *fakeApplicationClient) Patch(ctx context.Context, in *applicationpkg.ApplicationPatchRequest, opts ...grpc.CallOption) (*applicationv1.Application, error) {
+	if c.expired {
+		return nil, errSessionExpired
+	}
+
+	c.fake.mu.Lock()
+	defer c.fake.mu.Unlock()
+
+	c.fake.patches = append(c.fake.patches, in.GetPatch())
+	return newFakeApplication(in.GetName()), nil
+}
+
+func (c *fakeApplicationClient) TerminateOperation(ctx context.Context, in *applicationpkg.OperationTerminateRequest, opts ...grpc.CallOption) (*applicationpkg.OperationTerminateResponse, error) {
+	c.fake.mu.Lock()
+	defer c.fake.mu.Unlock()
+
+	c.fake.terminated = append(c.fake.terminated, in.GetName())
+	return &applicationpkg.OperationTerminateResponse{}, nil
+}
+
+func (c *fakeApplicationClient) ResourceTree(ctx context.Context, in *applicationpkg.ResourcesQuery, opts ...grpc.CallOption) (*applicationv1.ApplicationTree, error) {
+	c.fake.mu.Lock()
+	defer c.fake.mu.Unlock()
+
+	if c.fake.paused[in.GetApplicationName()] {
+		return &applicationv1.ApplicationTree{
+			Nodes: []applicationv1.ResourceNode{
+				{
+					ResourceRef: applicationv1.ResourceRef{Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout", Name: "myapp", UID: "4"},
+					Health:      &applicationv1.HealthStatus{Status: health.HealthStatusSuspended},
+				},
+			},
+		}, nil
+	}
+
+	return &applicationv1.ApplicationTree{
+		Nodes: []applicationv1.ResourceNode{
+			{
+				ResourceRef: applicationv1.ResourceRef{Group: "apps", Version: "v1", Kind: "Deployment", Name: "myapp", UID: "1"},
+				Health:      &applicationv1.HealthStatus{Status: health.HealthStatusDegraded, Message: "Deployment \"myapp\" exceeded its progress deadline"},
+			},
+			{
+				ResourceRef: applicationv1.ResourceRef{Version: "v1", Kind: "Pod", Name: "myapp-7d9f", UID: "2"},
+				Health:      &applicationv1.HealthStatus{Status: health.HealthStatusDegraded, Message: "back-off restarting failed container"},
+			},
+			{
+				ResourceRef: applicationv1.ResourceRef{Version: "v1", Kind: "Service", Name: "myapp", UID: "3"},
+				Health:      &applicationv1.HealthStatus{Status: health.HealthStatusHealthy},
+			},
+		},
+	}, nil
+}
+
+func (c *fakeApplicationClient) GetResource(ctx context.Context, in *applicationpkg.ApplicationResourceRequest, opts ...grpc.CallOption) (*applicationpkg.ApplicationResourceResponse, error) {
+	if in.GetKind() == "Rollout" {
+		return &applicationpkg.ApplicationResourceResponse{Manifest: ptr.To(`{
+			"spec": {"strategy": {"canary": {"steps": [{"setWeight": 20}, {"pause": {}}, {"setWeight": 50}, {"pause": {"duration": "10m"}}]}}},
+			"status": {"phase": "Paused", "currentStepIndex": 1, "pauseConditions": [{"reason": "CanaryPauseStep"}]}
+		}`)}, nil
+	}
+
+	pod := corev1.Pod{
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name:                 "app",
+					State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+					LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1}},
+				},
+				{
+					Name:  "sidecar",
+					State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+				},
+			},
+		},
+	}
+
+	manifest, err := json.Marshal(pod)
+	if err != nil {
+		return nil, err
+	}
+
+	return &applicationpkg.ApplicationResourceResponse{Manifest: ptr.To(string(manifest))}, nil
+}
+
+func (c *fakeApplicationClient) ListResourceEvents(ctx context.Context, in *applicationpkg.ApplicationResourceEventsQuery, opts ...grpc.CallOption) (*corev1.EventList, error) {
+	if in.GetResourceName() != "myapp-7d9f" {
+		return &corev1.EventList{}, nil
+	}
+
+	return &corev1.EventList{
+		Items: []corev1.Event{
+			{Type: corev1.EventTypeNormal, Reason: "Pulled", Message: "Container image is pulled"},
+			{Type: corev1.EventTypeWarning, Reason: "BackOff", Message: "Back-off restarting failed container"},
+		},
+	}, nil
+}
+
+func (c *fakeApplicationClient) RunResourceAction(ctx context.Context, in *applicationpkg.ResourceActionRunRequest, opts ...grpc.CallOption) (*applicationpkg.ApplicationResponse, error) {
+	c.fake.mu.Lock()
+	defer c.fake.mu.Unlock()
+
+	c.fake.actions = append(c.fake.actions, in.GetAction()+" "+in.GetKind()+"/"+in.GetResourceName())
+	if in.GetAction() == "resume" {
+		c.fake.paused[in.GetName()] = false
+		c.fake.active++
+		go c.fake.complete(&applicationpkg.ApplicationSyncRequest{Name: in.Name})
+	}
+
+	return &applicationpkg.ApplicationResponse{}, nil
+}
+
+func newFakeApplication(name string) *applicationv1.Application {
+	app := &applicationv1.Application{
+		Spec: applicationv1.ApplicationSpec{
+			Source: &applicationv1.ApplicationSource{TargetRevision: "main"},
+		},
+	}
+	app.Name = name
+
+	return app
+}
+
+func newReleases(tiers ...string) types.ListReleases {
+	var rels types.ListReleases
+	for i, tier := range tiers {
+		rels = append(rels, &types.Release{
+			ID:      tier + "-" + string(rune('a'+i)),
+			Cluster: tier,
+			Labels:  map[string]string{"tier": tier},
+		})
+	}
+
+	return rels
+}
+
+func TestMain(m *testing.M) {
+	// the syncs are triggered and abandoned against the fake client, there is nothing to wait for
+	syncRetryInterval = time.Millisecond
+	watchReconnectInterval = time.Millisecond
+	abandonTimeout = time.Second
+
+	os.Exit(m.Run())
+}
+
+func TestClient_SyncReleases(t *testing.T) {
+	t.Run("bounded by the max concurrency", func(t *testing.T) {
+		fake := &fakeClient{duration: 20 * time.Millisecond}
+		c := &Client{argocdClient: fake}
+
+		rels := newReleases("stable", "stable", "stable", "stable")
+		require.NoError(t, c.SyncReleases(context.Background(), rels, manager.WithMaxConcurrency(2)))
+		require.Len(t, fake.synced, 4)
+		require.LessOrEqual(t, fake.maxActive, 2)
+	})
+
+	t.Run("single connection and watch stream", func(t *testing.T) {
+		fake := &fakeClient{duration: 10 * time.Millisecond}
+		c := &Client{argocdClient: fake}
+
+		rels := newReleases("stable", "stable", "stable")
+		require.NoError(t, c.SyncReleases(context.Background(), rels))
+		require.Equal(t, 1, fake.connections)
+		require.Len(t, fake.streams, 1)
+		require.NoError(t, c.Close())
+	})
+
+	t.Run("delayed between syncs", func(t *testing.T) {
+		fake := &fakeClient{duration: 10 * time.Millisecond}
+		c := &Client{argocdClient: fake}
+
+		rels := newReleases("stable", "stable", "stable")
+		require.NoError(t, c.SyncReleases(context.Background(), rels, manager.WithSyncDelay(30*time.Millisecond)))
+		require.Len(t, fake.syncedAt, 3)
+		require.GreaterOrEqual(t, fake.syncedAt[2].Sub(fake.syncedAt[0]), 60*time.Millisecond)
+	})
+
+	t.Run("ordered by the label", func(t *testing.T) {
+		fake := &fakeClient{duration: 10 * time.Millisecond}
+		c := &Client{argocdClient: fake}
+
+		rels := newReleases("stable", "canary", "other", "stable", "canary")
+		require.NoError(t, c.SyncReleases(context.Background(), rels, manager.WithSyncOrder("tier", "canary", "stable")))
+
+		tiers := make([]string, 0, len(fake.synced))
+		for _, id := range fake.synced {
+			tiers = append(tiers, id[:len(id)-2])
+		}
+		require.Equal(t, []string{"canary", "canary", "stable", "stable", "other"}, tiers)
+	})
+
+	t.Run("keep going past the failures", func(t *testing.T) {
+		rels := newReleases("canary", "stable", "stable")
+		fake := &fakeClient{duration: 10 * time.Millisecond, failing: map[string]bool{rels[1].ID: true}}
+		c := &Client{argocdClient: fake}
+
+		err := c.SyncReleases(context.Background(), rels, manager.WithKeepGoing(true))
+		require.ErrorIs(t, err, ErrSyncReleasesFailed)
+		require.Len(t, fake.synced, 2)
+
+		results := make([]string, 0, len(rels))
+		for _, rel := range rels {
+			results = append(results, rel.Status.Result)
+		}
+		require.Equal(t, []string{types.ReleaseResultSucceeded, types.ReleaseResultSyncFailed, types.ReleaseResultSucceeded}, results)
+		require.Len(t, rels.Succeeded(), 2)
+	})
+
+	t.Run("terminate on cancel", func(t *testing.T) {
+		rels := newReleases("stable", "stable")
+		fake := &fakeClient{duration: 10 * time.Millisecond, hanging: map[string]bool{rels[1].ID: true}}
+		c := &Client{argocdClient: fake}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+
+		err := c.SyncReleases(ctx, rels, manager.WithKeepGoing(true), manager.WithTerminateOnCancel(true))
+		require.ErrorIs(t, err, ErrSyncCancelled, err)
+		require.Equal(t, []string{rels[1].ID}, fake.terminated)
+		require.Equal(t, types.ReleaseResultSucceeded, rels[0].Status.Result)
+		require.Equal(t, types.ReleaseResultCancelled, rels[1].Status.Result)
+	})
+
+	t.Run("stop at the failing wave", func(t *testing.T) {
+		rels := newReleases("canary", "stable")
+		fake := &fakeClient{failing: map[string]bool{rels[0].ID: true}}
+		c := &Client{argocdClient: fake}
+
+		err := c.SyncReleases(context.Background(), rels, manager.WithSyncOrder("tier", "canary"))
+		require.Error(t, err)
+		require.False(t, slices.Contains(fake.synced, rels[1].ID))
+	})
+}
+
+func TestClient_SyncRelease(t *testing.T) {
+	t.Run("sync parameters", func(t *testing.T) {
+		fake := &fakeClient{duration: 10 * time.Millisecond}
+		c := &Client{argocdClient: fake}
+
+		rel := newReleases("stable")[0]
+		require.NoError(t, c.SyncRelease(context.Background(), rel,
+			manager.WithPrune(true),
+			manager.WithForce(true),
+			manager.WithSyncStrategy(manager.SyncStrategyApply),
+			manager.WithSyncOptions("ServerSideApply=true", "ApplyOutOfSyncOnly=true"),
+			manager.WithSyncResources(manager.SyncResource{Group: "apps", Kind: "Deployment", Name: "myapp"}),
+			manager.WithSyncRetry(manager.SyncRetry{Limit: 3, Backoff: 5 * time.Second, BackoffFactor: 2, MaxBackoff: 3 * time.Minute}),
+		))
+		require.Len(t, fake.requests, 1)
+
+		req := fake.requests[0]
+		require.True(t, req.GetPrune())
+		require.False(t, req.GetDryRun())
+		require.Equal(t, &applicationv1.SyncStrategy{Apply: &applicationv1.SyncStrategyApply{Force: true}}, req.GetStrategy())
+		require.Equal(t, []string{"ServerSideApply=true", "ApplyOutOfSyncOnly=true"}, req.GetSyncOptions().GetItems())
+		require.Equal(t, []*applicationv1.SyncOperationResource{{Group: "apps", Kind: "Deployment", Name: "myapp"}}, req.GetResources())
+		require.Equal(t, &applicationv1.RetryStrategy{
+			Limit:   3,
+			Backoff: &applicationv1.Backoff{Duration: "5s", Factor: ptr.To[int64](2), MaxDuration: "3m0s"},
+		}, req.GetRetryStrategy())
+	})
+
+	t.Run("bare by default", func(t *testing.T) {
+		fake := &fakeClient{duration: 10 * time.Millisecond}
+		c := &Client{argocdClient: fake}
+
+		require.NoError(t, c.SyncRelease(context.Background(), newReleases("stable")[0]))
+		require.Equal(t, &applicationpkg.ApplicationSyncRequest{Name: ptr.To("stable-a")}, fake.requests[0])
+	})
+
+	t.Run("dry-run completes on the operation", func(t *testing.T) {
+		fake := &fakeClient{duration: 10 * time.Millisecond}
+		c := &Client{argocdClient: fake}
+
+		rel := newReleases("stable")[0]
+		require.NoError(t, c.SyncRelease(context.Background(), rel, manager.WithDryRun(true), manager.WithTimeoutSec(5)))
+		require.Equal(t, string(applicationv1.SyncStatusCodeOutOfSync), rel.Status.Sync)
+	})
+}
+
+func TestClient_SyncRelease_Diagnostics(t *testing.T) {
+	rel := newReleases("stable")[0]
+	fake := &fakeClient{duration: 10 * time.Millisecond, degraded: map[string]bool{rel.ID: true}}
+	c := &Client{argocdClient: fake}
+
+	// the degraded application never completes the watch, until the deadline is exceeded
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := c.SyncRelease(ctx, rel)
+	require.ErrorIs(t, err, ErrSyncOnWatchTimeout)
+	require.ErrorContains(t, err, "Pod/myapp-7d9f: app: CrashLoopBackOff")
+	require.Equal(t, types.ReleaseResultDegraded, rel.Status.Result)
+
+	require.Equal(t, []types.ResourceDiagnostic{
+		{
+			Kind:    "Deployment",
+			Name:    "myapp",
+			Health:  "Degraded",
+			Message: `Deployment "myapp" exceeded its progress deadline`,
+		},
+		{
+			Kind:       "Pod",
+			Name:       "myapp-7d9f",
+			Health:     "Degraded",
+			Message:    "back-off restarting failed container",
+			Containers: []string{"app: CrashLoopBackOff, last terminated with Error, exit code 1"},
+			Events:     []string{"BackOff: Back-off restarting failed container"},
+		},
+	}, rel.Status.Diagnostics)
+}
+
+func TestClient_SyncRelease_Rollouts(t *testing.T) {
+	t.Run("accept the paused rollout", func(t *testing.T) {
+		rel := newReleases("stable")[0]
+		fake := &fakeClient{duration: 10 * time.Millisecond, paused: map[string]bool{rel.ID: true}}
+		c := &Client{argocdClient: fake}
+
+		require.NoError(t, c.SyncRelease(context.Background(), rel, manager.WithRolloutPaused(manager.RolloutPausedAccept)))
+		require.Equal(t, types.ReleaseResultPaused, rel.Status.Result)
+		require.Equal(t, "rollout myapp is paused at step 1/4, canary weight 20%", rel.Status.Message)
+		require.False(t, rel.Status.IsFailed())
+	})
+
+	t.Run("promote the paused rollout", func(t *testing.T) {
+		rel := newReleases("stable")[0]
+		fake := &fakeClient{duration: 10 * time.Millisecond, paused: map[string]bool{rel.ID: true}}
+		c := &Client{argocdClient: fake}
+
+		require.NoError(t, c.SyncRelease(context.Background(), rel, manager.WithRolloutPaused(manager.RolloutPausedPromote)))
+		require.Equal(t, []string{"resume Rollout/myapp"}, fake.actions)
+		require.Equal(t, types.ReleaseResultSucceeded, rel.Status.Result)
+	})
+
+	t.Run("abort the paused rollout", func(t *testing.T) {
+		rel := newReleases("stable")[0]
+		fake := &fakeClient{duration: 10 * time.Millisecond, paused: map[string]bool{rel.ID: true}}
+		c := &Client{argocdClient: fake}
+
+		err := c.SyncRelease(context.Background(), rel, manager.WithRolloutPaused(manager.RolloutPausedAbort))
+		require.ErrorIs(t, err, ErrRolloutAborted)
+		require.Equal(t, []string{"abort Rollout/myapp"}, fake.actions)
+		require.Equal(t, types.ReleaseResultAborted, rel.Status.Result)
+	})
+}
+
+func TestClient_SyncRelease_Reauthenticate(t *testing.T) {
+	fake := &fakeClient{duration: 10 * time.Millisecond, expired: 1}
+	c := &Client{argocdClient: fake}
+
+	rel := newReleases("stable")[0]
+	require.NoError(t, c.SyncRelease(context.Background(), rel, manager.WithTimeoutSec(10)))
+	require.Equal(t, types.ReleaseResultSucceeded, rel.Status.Result)
+	require.Equal(t, 2, fake.connections)
+	require.Len(t, c.expiredConns, 1)
+	require.NoError(t, c.Close())
+}
+
+func TestClient_CreateRelease(t *testing.T) {
+	fake := &fakeClient{}
+	c := &Client{argocdClient: fake}
+
+	rel := &types.Release{
+		ID:                "myapp-staging-pr-1",
+		Labels:            map[string]string{"platform.ardikabs.com/preview": "pr-1"},
+		Project:           "default",
+		Namespace:         "myapp-pr-1",
+		DestinationServer: "https://kubernetes.default.svc",
+		GitURL:            "https://github.com/ardikabs/manifests.git",
+		GitPath:           "envs/preview-pr-1",
+		GitRevision:       "main",
+	}
+	require.NoError(t, c.CreateRelease(context.Background(), rel))
+	require.Len(t, fake.created, 1)
+
+	req := fake.created[0]
+	require.True(t, req.GetUpsert())
+
+	app := req.GetApplication()
+	require.Equal(t, "myapp-staging-pr-1", app.Name)
+	require.Equal(t, rel.Labels, app.Labels)
+	require.Equal(t, &applicationv1.ApplicationSource{RepoURL: rel.GitURL, Path: rel.GitPath, TargetRevision: "main"}, app.Spec.Source)
+	require.Equal(t, applicationv1.ApplicationDestination{Server: rel.DestinationServer, Namespace: "myapp-pr-1"}, app.Spec.Destination)
+	require.Equal(t, applicationv1.SyncOptions{"CreateNamespace=true"}, app.Spec.SyncPolicy.SyncOptions)
+}
+
+func TestClient_DeleteRelease(t *testing.T) {
+	fake := &fakeClient{applications: []string{"myapp-staging-pr-1"}}
+	c := &Client{argocdClient: fake}
+
+	require.NoError(t, c.DeleteRelease(context.Background(), &types.Release{ID: "myapp-staging-pr-1"}, manager.WithCascade(true)))
+	require.Len(t, fake.deleted, 1)
+	require.True(t, fake.deleted[0].GetCascade())
+
+	// the application deleted in the meantime is already gone
+	require.NoError(t, c.DeleteRelease(context.Background(), &types.Release{ID: "myapp-staging-pr-2"}))
+	require.Len(t, fake.deleted, 1)
+}
+
+func TestClient_AnnotateRelease_Reauthenticate(t *testing.T) {
+	fake := &fakeClient{expired: 1}
+	c := &Client{argocdClient: fake}
+
+	rel := newReleases("stable")[0]
+	require.NoError(t, c.AnnotateRelease(context.Background(), rel, map[string]string{"dpl.ardikabs.com/image": "myapp:v1"}))
+	require.Equal(t, []string{`{"metadata":{"annotations":{"dpl.ardikabs.com/image":"myapp:v1"}}}`}, fake.patches)
+	require.Equal(t, 2, fake.connections)
+	require.NoError(t, c.Close())
+}
+
+func TestAPIClient_Token(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("first\n"), 0o600))
+
+	a := &apiClient{cfg: types.ArgoConfig{Secret: types.ArgoSecret{Token: "static", TokenFile: tokenFile}}}
+
+	token, err := a.token()
+	require.NoError(t, err)
+	require.Equal(t, "first", token)
+
+	// the rotated token is picked up on the next connection
+	require.NoError(t, os.WriteFile(tokenFile, []byte("second"), 0o600))
+	token, err = a.token()
+	require.NoError(t, err)
+	require.Equal(t, "second", token)
+
+	require.NoError(t, os.WriteFile(tokenFile, nil, 0o600))
+	_, err = a.token()
+	require.Error(t, err)
+
+	a.cfg.Secret.TokenFile = ""
+	token, err = a.token()
+	require.NoError(t, err)
+	require.Equal(t, "static", token)
+}
+
+func TestInstances(t *testing.T) {
+	eu := &fakeClient{duration: 10 * time.Millisecond, applications: []string{"myapp-prod-eu"}}
+	us := &fakeClient{duration: 10 * time.Millisecond, applications: []string{"myapp-prod-us"}}
+
+	ins := &Instances{instances: []*instance{
+		{InstanceConfig: InstanceConfig{Name: "eu", Clusters: []string{"prod-eu"}}, client: &Client{argocdClient: eu, name: "eu"}},
+		{InstanceConfig: InstanceConfig{Name: "us", Clusters: []string{"prod-us"}}, client: &Client{argocdClient: us, name: "us"}},
+	}}
+
+	req, err := manager.NewListReleaseRequestBuilder().
+		SetEnvironmentSelector("environment", "production").
+		SetClusterSelector("cluster", "prod-eu").
+		Build()
+	require.NoError(t, err)
+
+	rels, err := ins.ListReleases(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, rels, 1)
+	require.Equal(t, "eu", rels[0].Instance)
+
+	// every instance is queried once the cluster is not selected
+	req, err = manager.NewListReleaseRequestBuilder().SetEnvironmentSelector("environment", "production").Build()
+	require.NoError(t, err)
+
+	rels, err = ins.ListReleases(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, rels, 2)
+
+	require.NoError(t, ins.SyncReleases(context.Background(), rels))
+	require.Equal(t, []string{"myapp-prod-eu"}, eu.synced)
+	require.Equal(t, []string{"myapp-prod-us"}, us.synced)
+	require.Equal(t, 1, eu.connections)
+	require.Equal(t, 1, us.connections)
+
+	err = ins.SyncRelease(context.Background(), &types.Release{ID: "myapp-prod-ap", Instance: "ap"})
+	require.ErrorIs(t, err, ErrInstanceNotFound)
+
+	require.NoError(t, ins.Close())
+}
+
+func TestNewInstances(t *testing.T) {
+	_, err := NewInstances()
+	require.ErrorIs(t, err, ErrInvalidInstanceConfig)
+
+	_, err = NewInstances(InstanceConfig{Name: "eu"})
+	require.ErrorIs(t, err, ErrInvalidInstanceConfig)
+
+	_, err = NewInstances(InstanceConfig{Name: "eu", Host: "argocd.eu.example.com", Auth: InstanceAuth{Username: "admin"}})
+	require.ErrorIs(t, err, ErrInvalidInstanceConfig)
+
+	t.Setenv("ARGOCD_EU_AUTH_TOKEN", "s3cr3t")
+	ins, err := NewInstances(
+		InstanceConfig{Name: "eu", Host: "argocd.eu.example.com", Auth: InstanceAuth{Token: "${ARGOCD_EU_AUTH_TOKEN}"}},
+		InstanceConfig{Name: "us", Host: "argocd.us.example.com", Auth: InstanceAuth{Token: "${ARGOCD_EU_AUTH_TOKEN}"}},
+	)
+	require.NoError(t, err)
+	require.Equal(t, "s3cr3t", ins.instances[0].argoConfig().Secret.Token)
+	require.True(t, ins.instances[0].argoConfig().GRPCWeb)
+	require.Equal(t, "us", ins.instances[1].client.name)
+
+	_, err = NewInstances(
+		InstanceConfig{Name: "eu", Host: "argocd.eu.example.com", Auth: InstanceAuth{Token: "s3cr3t"}},
+		InstanceConfig{Name: "eu", Host: "argocd.us.example.com", Auth: InstanceAuth{Token: "s3cr3t"}},
+	)
+	require.ErrorIs(t, err, ErrInvalidInstanceConfig)
+}
+
+func loadApplicationSet(t *testing.T, name string) applicationv1.ApplicationSet {
+	content, err := os.ReadFile(filepath.Join("testdata", "applicationsets", name+".yaml"))
+	require.NoError(t, err)
+
+	var appset applicationv1.ApplicationSet
+	require.NoError(t, yaml.Unmarshal(content, &appset))
+
+	return appset
+}
+
+// newGeneratedApplication returns the Application generated by the ApplicationSet, labeled by the template
+func newGeneratedApplication(appset applicationv1.ApplicationSet, name string, labels map[string]string) applicationv1.Application {
+	app := *newFakeApplication(name)
+	app.Labels = labels
+	app.OwnerReferences = []metav1.OwnerReference{{Kind: "ApplicationSet", Name: appset.Name, UID: appset.UID}}
+
+	return app
+}
+
+func TestClient_ListReleases_ApplicationSets(t *testing.T) {
+	myapp, otherapp := loadApplicationSet(t, "myapp"), loadApplicationSet(t, "otherapp")
+
+	fake := &fakeClient{
+		appsets: []applicationv1.ApplicationSet{myapp, otherapp},
+		generated: []applicationv1.Application{
+			newGeneratedApplication(myapp, "myapp-prod-1", map[string]string{
+				"platform.ardikabs.com/environment": "production",
+				"platform.ardikabs.com/cluster":     "prod-1",
+				"team":                              "payments",
+			}),
+			newGeneratedApplication(myapp, "myapp-staging-1", map[string]string{
+				"platform.ardikabs.com/environment": "staging",
+				"platform.ardikabs.com/cluster":     "staging-1",
+				"team":                              "payments",
+			}),
+			newGeneratedApplication(otherapp, "otherapp-prod-1", map[string]string{
+				"platform.ardikabs.com/release":     "otherapp",
+				"platform.ardikabs.com/environment": "production",
+				"platform.ardikabs.com/cluster":     "prod-1",
+			}),
+		},
+	}
+	c := &Client{argocdClient: fake}
+
+	newRequest := func(release string) *manager.ListReleaseRequest {
+		req, err := manager.NewListReleaseRequestBuilder().
+			SetReleaseSelector("platform.ardikabs.com/release", release).
+			SetEnvironmentSelector("platform.ardikabs.com/environment", "production").
+			SetClusterSelector("platform.ardikabs.com/cluster", "").
+			Build()
+		require.NoError(t, err)
+
+		return req
+	}
+
+	t.Run("matched by the applicationset labels", func(t *testing.T) {
+		rels, err := c.ListReleases(context.Background(), newRequest("myapp"), manager.WithApplicationSets(true))
+		require.NoError(t, err)
+		require.Len(t, rels, 1)
+
+		rel := rels[0]
+		require.Equal(t, "myapp-prod-1", rel.ID)
+		require.Equal(t, "myapp", rel.Name)
+		require.Equal(t, "production", rel.Environment)
+		require.Equal(t, "prod-1", rel.Cluster)
+		require.Equal(t, "myapp", rel.ApplicationSet)
+		require.Equal(t, &types.GitFilesGenerator{
+			RepoURL:      "https://github.com/ardikabs/manifests.git",
+			Revision:     "main",
+			Files:        []string{"clusters/*/myapp.json"},
+			NameTemplate: "myapp-{{cluster.name}}",
+		}, rel.Generator)
+	})
+
+	t.Run("listed directly", func(t *testing.T) {
+		rels, err := c.ListReleases(context.Background(), newRequest("otherapp"), manager.WithApplicationSets(true))
+		require.NoError(t, err)
+		require.Len(t, rels, 1)
+		require.Equal(t, "otherapp-prod-1", rels[0].ID)
+		require.Equal(t, "otherapp", rels[0].ApplicationSet)
+		require.Nil(t, rels[0].Generator)
+	})
+
+	t.Run("not discovered by default", func(t *testing.T) {
+		_, err := c.ListReleases(context.Background(), newRequest("myapp"))
+		require.ErrorIs(t, err, ErrArgoCDApplicationNotExists)
+	})
+}
+
+func TestReleasesSelector(t *testing.T) {
+	rels := types.ListReleases{
+		{Labels: map[string]string{"release": "myapp", "environment": "dev", "cluster": "dev-1"}},
+		{Labels: map[string]string{"release": "myapp", "environment": "dev", "cluster": "dev-2"}},
+		{Labels: map[string]string{"release": "myapp", "environment": "dev"}},
+	}
+	require.Equal(t, "environment=dev,release=myapp", releasesSelector(rels))
+
+	rels[2].Labels["release"] = "otherapp"
+	require.Equal(t, "environment=dev,release in (myapp,otherapp)", releasesSelector(rels))
+}
@@ -0,0 +1,223 @@
+package argocd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/types"
+	applicationpkg "github.com/argoproj/argo-cd/v2/pkg/apiclient/application"
+	applicationv1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/gitops-engine/pkg/health"
+	synccommon "github.com/argoproj/gitops-engine/pkg/sync/common"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// diagnoseTimeout bounds the time spent on inspecting the resources of the failed application
+	diagnoseTimeout = 30 * time.Second
+
+	// maxDiagnosedResources bounds the resources inspected for their details and events
+	maxDiagnosedResources = 10
+
+	// maxResourceEvents is the number of the recent warning events reported per resource
+	maxResourceEvents = 5
+)
+
+// diagnose reports the resources failed to be synced and the unhealthy resources of the application,
+// along with the container states of the pods and their recent warning events. It is best-effort,
+// failing to inspect a resource only leaves its details out.
+func (c *Client) diagnose(ctx context.Context, log logr.Logger, appName string, status applicationv1.ApplicationStatus) []types.ResourceDiagnostic {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), diagnoseTimeout)
+	defer cancel()
+
+	var diagnostics []types.ResourceDiagnostic
+	if state := status.OperationState; state != nil && state.SyncResult != nil {
+		for _, res := range state.SyncResult.Resources {
+			if res.Status != synccommon.ResultCodeSyncFailed {
+				continue
+			}
+
+			diagnostics = append(diagnostics, types.ResourceDiagnostic{
+				Kind:      res.Kind,
+				Namespace: res.Namespace,
+				Name:      res.Name,
+				Health:    string(res.Status),
+				Message:   res.Message,
+			})
+		}
+	}
+
+	var tree *applicationv1.ApplicationTree
+	if err := c.call(func(appClient applicationpkg.ApplicationServiceClient) (err error) {
+		tree, err = appClient.ResourceTree(ctx, &applicationpkg.ResourcesQuery{ApplicationName: ptr.To(appName)})
+		return err
+	}); err != nil {
+		log.Error(err, "failed to get application resource tree")
+		return diagnostics
+	}
+
+	var inspected int
+	for _, node := range tree.Nodes {
+		if node.Health == nil || node.Health.Status == health.HealthStatusHealthy {
+			continue
+		}
+
+		diag := types.ResourceDiagnostic{
+			Kind:      node.Kind,
+			Namespace: node.Namespace,
+			Name:      node.Name,
+			Health:    string(node.Health.Status),
+			Message:   node.Health.Message,
+		}
+
+		if inspected < maxDiagnosedResources {
+			inspected++
+
+			if node.Group == "" && node.Kind == "Pod" {
+				diag.Containers = c.containerStates(ctx, log, appName, node)
+			}
+
+			diag.Events = c.warningEvents(ctx, log, appName, node)
+		}
+
+		if len(diag.Containers) == 0 {
+			diag.Containers = statusReason(node)
+		}
+
+		diagnostics = append(diagnostics, diag)
+	}
+
+	for _, diag := range diagnostics {
+		log.Info("unhealthy resource",
+			"kind", diag.Kind,
+			"namespace", diag.Namespace,
+			"name", diag.Name,
+			"health", diag.Health,
+			"message", diag.Message,
+			"containers", diag.Containers,
+			"events", diag.Events,
+		)
+	}
+
+	return diagnostics
+}
+
+// containerStates returns the waiting and the failed terminated states of the pod containers
+func (c *Client) containerStates(ctx context.Context, log logr.Logger, appName string, node applicationv1.ResourceNode) []string {
+	var res *applicationpkg.ApplicationResourceResponse
+	if err := c.call(func(appClient applicationpkg.ApplicationServiceClient) (err error) {
+		res, err = appClient.GetResource(ctx, &applicationpkg.ApplicationResourceRequest{
+			Name:         ptr.To(appName),
+			Namespace:    ptr.To(node.Namespace),
+			ResourceName: ptr.To(node.Name),
+			Version:      ptr.To(node.Version),
+			Group:        ptr.To(node.Group),
+			Kind:         ptr.To(node.Kind),
+		})
+		return err
+	}); err != nil {
+		log.V(1).Info("failed to get pod", "name", node.Name, "err", err)
+		return nil
+	}
+
+	var pod corev1.Pod
+	if err := json.Unmarshal([]byte(res.GetManifest()), &pod); err != nil {
+		log.V(1).Info("failed to decode pod", "name", node.Name, "err", err)
+		return nil
+	}
+
+	var states []string
+	for _, cs := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		switch {
+		case cs.State.Waiting != nil && cs.State.Waiting.Reason != "":
+			state := fmt.Sprintf("%s: %s", cs.Name, cs.State.Waiting.Reason)
+			if cs.State.Waiting.Message != "" {
+				state += " (" + cs.State.Waiting.Message + ")"
+			}
+
+			// the crashing container tells why on its last termination
+			if last := cs.LastTerminationState.Terminated; last != nil {
+				state += fmt.Sprintf(", last terminated with %s, exit code %d", last.Reason, last.ExitCode)
+			}
+
+			states = append(states, state)
+		case cs.State.Terminated != nil && cs.State.Terminated.ExitCode != 0:
+			states = append(states, fmt.Sprintf("%s: terminated with %s, exit code %d", cs.Name, cs.State.Terminated.Reason, cs.State.Terminated.ExitCode))
+		}
+	}
+
+	return states
+}
+
+// warningEvents returns the recent warning events of the resource, the oldest first
+func (c *Client) warningEvents(ctx context.Context, log logr.Logger, appName string, node applicationv1.ResourceNode) []string {
+	var list *corev1.EventList
+	if err := c.call(func(appClient applicationpkg.ApplicationServiceClient) (err error) {
+		list, err = appClient.ListResourceEvents(ctx, &applicationpkg.ApplicationResourceEventsQuery{
+			Name:              ptr.To(appName),
+			ResourceNamespace: ptr.To(node.Namespace),
+			ResourceName:      ptr.To(node.Name),
+			ResourceUID:       ptr.To(node.UID),
+		})
+		return err
+	}); err != nil {
+		log.V(1).Info("failed to list resource events", "name", node.Name, "err", err)
+		return nil
+	}
+
+	var warnings []corev1.Event
+	for _, ev := range list.Items {
+		if ev.Type == corev1.EventTypeWarning {
+			warnings = append(warnings, ev)
+		}
+	}
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return warnings[i].LastTimestamp.Before(&warnings[j].LastTimestamp)
+	})
+
+	if len(warnings) > maxResourceEvents {
+		warnings = warnings[len(warnings)-maxResourceEvents:]
+	}
+
+	var events []string
+	for _, ev := range warnings {
+		events = append(events, fmt.Sprintf("%s: %s", ev.Reason, strings.TrimSpace(ev.Message)))
+	}
+
+	return events
+}
+
+// statusReason returns the status reason of the resource tree node, which Argo CD derives from the pod containers
+func statusReason(node applicationv1.ResourceNode) []string {
+	for _, info := range node.Info {
+		if info.Name == "Status Reason" && info.Value != "" {
+			return []string{info.Value}
+		}
+	}
+
+	return nil
+}
+
+// diagnosticsSummary returns the first few diagnostics in a line, to complement the error of the failed sync
+func diagnosticsSummary(diagnostics []types.ResourceDiagnostic) string {
+	const maxSummarized = 3
+
+	summary := make([]string, 0, maxSummarized)
+	for _, diag := range diagnostics {
+		if len(summary) == maxSummarized {
+			summary = append(summary, fmt.Sprintf("and %d more", len(diagnostics)-maxSummarized))
+			break
+		}
+
+		summary = append(summary, diag.String())
+	}
+
+	return strings.Join(summary, "; ")
+}
@@ -0,0 +1,359 @@
+package argocd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+
+	"github.com/ardikabs/dpl/internal/errs"
+	"github.com/ardikabs/dpl/internal/manager"
+	"github.com/ardikabs/dpl/internal/tools/ioutils"
+	"github.com/ardikabs/dpl/internal/tracing"
+	"github.com/ardikabs/dpl/internal/types"
+	applicationpkg "github.com/argoproj/argo-cd/v2/pkg/apiclient/application"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/errgroup"
+	goyaml "gopkg.in/yaml.v3"
+	"k8s.io/utils/ptr"
+)
+
+// InstanceConfig is a named Argo CD instance, along with the environments and clusters whose Applications it owns
+type InstanceConfig struct {
+	Name string `yaml:"name"`
+	Host string `yaml:"host"`
+
+	// GRPCWeb defaults to true, as for the single instance
+	GRPCWeb   *bool `yaml:"grpcWeb"`
+	PlainText bool  `yaml:"plainText"`
+	Insecure  bool  `yaml:"insecure"`
+
+	CACert     string `yaml:"caCert"`
+	ClientCert string `yaml:"clientCert"`
+	ClientKey  string `yaml:"clientKey"`
+
+	Auth InstanceAuth `yaml:"auth"`
+
+	// Environments and Clusters pick the instance for the releases, it is picked for any of them when both are empty
+	Environments []string `yaml:"environments"`
+	Clusters     []string `yaml:"clusters"`
+}
+
+// InstanceAuth is the credentials of the instance, environment variables are expanded,
+// so the secrets don't need to be stored in the file.
+type InstanceAuth struct {
+	Token     string `yaml:"token"`
+	TokenFile string `yaml:"tokenFile"`
+	Username  string `yaml:"username"`
+	Password  string `yaml:"password"`
+}
+
+func (cfg InstanceConfig) argoConfig() types.ArgoConfig {
+	grpcWeb := true
+	if cfg.GRPCWeb != nil {
+		grpcWeb = *cfg.GRPCWeb
+	}
+
+	return types.ArgoConfig{
+		Name:           cfg.Name,
+		Host:           cfg.Host,
+		Insecure:       cfg.Insecure,
+		PlainText:      cfg.PlainText,
+		GRPCWeb:        grpcWeb,
+		CACertFile:     cfg.CACert,
+		ClientCertFile: cfg.ClientCert,
+		ClientKeyFile:  cfg.ClientKey,
+		Secret: types.ArgoSecret{
+			Token:     os.ExpandEnv(cfg.Auth.Token),
+			TokenFile: cfg.Auth.TokenFile,
+			Username:  os.ExpandEnv(cfg.Auth.Username),
+			Password:  os.ExpandEnv(cfg.Auth.Password),
+		},
+	}
+}
+
+func (cfg InstanceConfig) validate() error {
+	if cfg.Host == "" {
+		return errs.Wrapf(ErrInvalidInstanceConfig, "instance %s has no host", cfg.Name)
+	}
+
+	secret := cfg.argoConfig().Secret
+	if secret.Token == "" && secret.TokenFile == "" && secret.Username == "" {
+		return errs.Wrapf(ErrInvalidInstanceConfig, "instance %s has no credentials", cfg.Name)
+	}
+
+	if secret.Username != "" && secret.Password == "" {
+		return errs.Wrapf(ErrInvalidInstanceConfig, "instance %s has no password", cfg.Name)
+	}
+
+	if (cfg.ClientCert == "") != (cfg.ClientKey == "") {
+		return errs.Wrapf(ErrInvalidInstanceConfig, "instance %s must have both client certificate and key", cfg.Name)
+	}
+
+	return nil
+}
+
+// instance is a named Argo CD instance along with its client
+type instance struct {
+	InstanceConfig
+	client *Client
+}
+
+// owns returns true when the instance is mapped to the environment and the cluster, the empty value matches any
+func (i *instance) owns(environment, cluster string) bool {
+	if environment != "" && len(i.Environments) > 0 && !slices.Contains(i.Environments, environment) {
+		return false
+	}
+
+	if cluster != "" && len(i.Clusters) > 0 && !slices.Contains(i.Clusters, cluster) {
+		return false
+	}
+
+	return true
+}
+
+// Instances manages the releases across many Argo CD instances, such as one per region.
+// The releases are discovered on the instances mapped to the requested environment and cluster,
+// then they are synced on the instance owning them.
+type Instances struct {
+	instances []*instance
+}
+
+type instancesConfig struct {
+	Instances []InstanceConfig `yaml:"instances"`
+}
+
+// LoadInstances reads the instances configuration file, for example:
+//
+//	instances:
+//	  - name: eu
+//	    host: argocd.eu.example.com
+//	    auth:
+//	      token: ${ARGOCD_EU_AUTH_TOKEN}
+//	    environments: [production]
+//	    clusters: [prod-eu-1, prod-eu-2]
+func LoadInstances(filename string) (*Instances, error) {
+	content, err := ioutils.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg instancesConfig
+	if err := goyaml.Unmarshal(content, &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewInstances(cfg.Instances...)
+}
+
+func NewInstances(cfgs ...InstanceConfig) (*Instances, error) {
+	if len(cfgs) == 0 {
+		return nil, errs.Wrapf(ErrInvalidInstanceConfig, "no instance is configured")
+	}
+
+	ins := &Instances{instances: make([]*instance, 0, len(cfgs))}
+	for _, cfg := range cfgs {
+		if cfg.Name == "" {
+			return nil, errs.Wrapf(ErrInvalidInstanceConfig, "instance of %s has no name", cfg.Host)
+		}
+
+		if _, err := ins.get(cfg.Name); err == nil {
+			return nil, errs.Wrapf(ErrInvalidInstanceConfig, "instance %s is duplicated", cfg.Name)
+		}
+
+		if err := cfg.validate(); err != nil {
+			return nil, err
+		}
+
+		client, err := NewClient(cfg.argoConfig())
+		if err != nil {
+			return nil, errs.Wrapf(err, "instance %s", cfg.Name)
+		}
+
+		ins.instances = append(ins.instances, &instance{InstanceConfig: cfg, client: client})
+	}
+
+	return ins, nil
+}
+
+func (ins *Instances) get(name string) (*instance, error) {
+	for _, i := range ins.instances {
+		if i.Name == name {
+			return i, nil
+		}
+	}
+
+	return nil, errs.Wrapf(ErrInstanceNotFound, "instance %s", name)
+}
+
+// discover returns the instances mapped to the environment and the cluster, or every instance when none is mapped
+func (ins *Instances) discover(environment, cluster string) []*instance {
+	var found []*instance
+	for _, i := range ins.instances {
+		if i.owns(environment, cluster) {
+			found = append(found, i)
+		}
+	}
+
+	if len(found) == 0 {
+		return ins.instances
+	}
+
+	return found
+}
+
+// owner returns the instance owning the release, the release without the instance is picked by the mapping
+func (ins *Instances) owner(rel *types.Release) (*instance, error) {
+	if rel.Instance != "" {
+		return ins.get(rel.Instance)
+	}
+
+	found := ins.discover(rel.Environment, rel.Cluster)
+	if len(found) != 1 {
+		return nil, errs.Wrapf(ErrInstanceNotFound, "release %s on %s is owned by %d instances", rel.ID, rel.Cluster, len(found))
+	}
+
+	return found[0], nil
+}
+
+func (ins *Instances) ListReleases(ctx context.Context, req *manager.ListReleaseRequest, opts ...manager.Option) (_ types.ListReleases, err error) {
+	ctx, span := tracing.Start(ctx, "Manager.ListReleases", attribute.String("argocd.selector", req.Selector))
+	defer tracing.End(span, &err)
+
+	o := manager.NewDefaultOptions(opts...)
+
+	found := ins.discover(req.Environment, req.Cluster)
+	listed := make([]types.ListReleases, len(found))
+
+	g, gctx := errgroup.WithContext(ctx)
+	for idx, i := range found {
+		idx, i := idx, i
+
+		g.Go(func() error {
+			listOpts := append(slices.Clone(opts), manager.WithLogger(o.Logger.WithValues("argocd_instance", i.Name)))
+
+			rels, err := i.client.ListReleases(gctx, req, listOpts...)
+			if err != nil && !errors.Is(err, ErrArgoCDApplicationNotExists) {
+				return errs.Wrapf(err, "instance %s", i.Name)
+			}
+
+			listed[idx] = rels
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	var rels types.ListReleases
+	for _, l := range listed {
+		rels = append(rels, l...)
+	}
+
+	if len(rels) == 0 {
+		return nil, ErrArgoCDApplicationNotExists
+	}
+
+	for _, rel := range rels {
+		if rel.GitURL != rels.GetGitURL() || rel.GitRevision != rels.GetGitRevision() {
+			return nil, ErrGitRepoAndRevisionMismatch
+		}
+	}
+
+	return rels, nil
+}
+
+func (ins *Instances) SyncReleases(ctx context.Context, rels types.ListReleases, opts ...manager.Option) (err error) {
+	ctx, span := tracing.Start(ctx, "Manager.SyncReleases", attribute.Int("argocd.applications", len(rels)))
+	defer tracing.End(span, &err)
+
+	o := manager.NewDefaultOptions(opts...)
+
+	log := o.Logger.WithName("argocd.SyncReleases")
+
+	syncOpts := append(slices.Clone(opts), manager.WithLogger(log))
+
+	owned := make(map[*instance]types.ListReleases)
+	for _, rel := range rels {
+		i, err := ins.owner(rel)
+		if err != nil {
+			return err
+		}
+
+		owned[i] = append(owned[i], rel)
+	}
+
+	// the releases of every instance are watched through a single stream of the instance
+	watchers := make(map[*instance]*watcher, len(owned))
+	for i, rels := range owned {
+		w, err := i.client.startWatch(ctx, log.WithValues("argocd_instance", i.Name), &applicationpkg.ApplicationQuery{Selector: ptr.To(releasesSelector(rels))})
+		if err != nil {
+			return errs.Wrapf(err, "instance %s", i.Name)
+		}
+		defer w.stop()
+
+		watchers[i] = w
+	}
+
+	// the waves, the concurrency, and the failure handling span every instance
+	return syncWaves(ctx, log, rels, o, func(ctx context.Context, rel *types.Release) error {
+		i, err := ins.owner(rel)
+		if err != nil {
+			return err
+		}
+
+		return i.client.syncRelease(ctx, rel, watchers[i], syncOpts...)
+	})
+}
+
+func (ins *Instances) SyncRelease(ctx context.Context, rel *types.Release, opts ...manager.Option) error {
+	i, err := ins.owner(rel)
+	if err != nil {
+		return err
+	}
+
+	return i.client.SyncRelease(ctx, rel, opts...)
+}
+
+// CreateRelease creates the Application on the instance owning the release, the release records the instance afterwards
+func (ins *Instances) CreateRelease(ctx context.Context, rel *types.Release, opts ...manager.Option) error {
+	i, err := ins.owner(rel)
+	if err != nil {
+		return err
+	}
+
+	if err := i.client.CreateRelease(ctx, rel, opts...); err != nil {
+		return err
+	}
+
+	rel.Instance = i.Name
+	return nil
+}
+
+func (ins *Instances) DeleteRelease(ctx context.Context, rel *types.Release, opts ...manager.Option) error {
+	i, err := ins.owner(rel)
+	if err != nil {
+		return err
+	}
+
+	return i.client.DeleteRelease(ctx, rel, opts...)
+}
+
+func (ins *Instances) AnnotateRelease(ctx context.Context, rel *types.Release, annotations map[string]string, opts ...manager.Option) error {
+	i, err := ins.owner(rel)
+	if err != nil {
+		return err
+	}
+
+	return i.client.AnnotateRelease(ctx, rel, annotations, opts...)
+}
+
+func (ins *Instances) Close() error {
+	var errs []error
+	for _, i := range ins.instances {
+		errs = append(errs, i.client.Close())
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,293 @@
+package argocd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/errs"
+	"github.com/ardikabs/dpl/internal/manager"
+	applicationpkg "github.com/argoproj/argo-cd/v2/pkg/apiclient/application"
+	applicationv1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/gitops-engine/pkg/health"
+	"github.com/go-logr/logr"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	rolloutGroup = "argoproj.io"
+	rolloutKind  = "Rollout"
+
+	rolloutActionResume = "resume"
+	rolloutActionAbort  = "abort"
+
+	// rolloutInspectInterval throttles the inspection of the progressing rollouts, only to report their progress
+	rolloutInspectInterval = 10 * time.Second
+)
+
+// rollout is the progress of an Argo Rollout, decoded from its live manifest
+type rollout struct {
+	node applicationv1.ResourceNode
+
+	Spec struct {
+		Strategy struct {
+			Canary *struct {
+				Steps []struct {
+					SetWeight *int32 `json:"setWeight"`
+				} `json:"steps"`
+			} `json:"canary"`
+		} `json:"strategy"`
+	} `json:"spec"`
+
+	Status struct {
+		Phase            string `json:"phase"`
+		Message          string `json:"message"`
+		CurrentStepIndex *int32 `json:"currentStepIndex"`
+		ControllerPause  bool   `json:"controllerPause"`
+		PauseConditions  []struct {
+			Reason string `json:"reason"`
+		} `json:"pauseConditions"`
+		Canary struct {
+			Weights *struct {
+				Canary struct {
+					Weight int32 `json:"weight"`
+				} `json:"canary"`
+			} `json:"weights"`
+		} `json:"canary"`
+	} `json:"status"`
+}
+
+func (r *rollout) isPaused() bool {
+	return r.Status.Phase == "Paused" || r.Status.ControllerPause || len(r.Status.PauseConditions) > 0
+}
+
+// step returns the current canary step out of the steps, zero steps for the other strategies
+func (r *rollout) step() (int32, int) {
+	if r.Spec.Strategy.Canary == nil {
+		return 0, 0
+	}
+
+	steps := len(r.Spec.Strategy.Canary.Steps)
+	if r.Status.CurrentStepIndex == nil {
+		return int32(steps), steps
+	}
+
+	return *r.Status.CurrentStepIndex, steps
+}
+
+// weight returns the canary weight, from the traffic routing when it is used, otherwise from the last weight step
+func (r *rollout) weight() int32 {
+	if r.Status.Canary.Weights != nil {
+		return r.Status.Canary.Weights.Canary.Weight
+	}
+
+	if r.Spec.Strategy.Canary == nil {
+		return 0
+	}
+
+	current, steps := r.step()
+	if int(current) >= steps {
+		return 100
+	}
+
+	var weight int32
+	for _, step := range r.Spec.Strategy.Canary.Steps[:current] {
+		if step.SetWeight != nil {
+			weight = *step.SetWeight
+		}
+	}
+
+	return weight
+}
+
+func (r *rollout) String() string {
+	current, steps := r.step()
+	if steps == 0 {
+		return fmt.Sprintf("rollout %s is %s", r.node.Name, strings.ToLower(r.Status.Phase))
+	}
+
+	return fmt.Sprintf("rollout %s is %s at step %d/%d, canary weight %d%%", r.node.Name, strings.ToLower(r.Status.Phase), current, steps, r.weight())
+}
+
+// rolloutGate applies the policy on the paused rollouts of the application, while the sync is being watched
+type rolloutGate struct {
+	c      *Client
+	ctx    context.Context
+	policy string
+
+	// progress is the last reported progress per rollout, so the progress is reported once it changes
+	progress map[string]string
+
+	// resumed is the steps the rollouts are resumed at, so they are resumed once per pause
+	resumed map[string]bool
+
+	// paused is the progress of the paused rollouts, when the sync is completed on the pause
+	paused string
+
+	inspectedAt time.Time
+	noRollouts  bool
+}
+
+func (c *Client) newRolloutGate(ctx context.Context, policy string) *rolloutGate {
+	return &rolloutGate{
+		c:        c,
+		ctx:      ctx,
+		policy:   policy,
+		progress: make(map[string]string),
+		resumed:  make(map[string]bool),
+	}
+}
+
+// condition wraps the condition, so the suspended application is inspected for its paused rollouts
+func (g *rolloutGate) condition(condition appConditionFunc) appConditionFunc {
+	return func(log logr.Logger, app applicationv1.Application) (bool, error) {
+		good, err := condition(log, app)
+		if good || err != nil || g.noRollouts {
+			return good, err
+		}
+
+		switch app.Status.Health.Status {
+		case health.HealthStatusSuspended:
+		case health.HealthStatusProgressing:
+			if time.Since(g.inspectedAt) < rolloutInspectInterval {
+				return false, nil
+			}
+		default:
+			return false, nil
+		}
+
+		rollouts, err := g.c.rollouts(g.ctx, app.Name)
+		if err != nil {
+			log.V(1).Info("failed to inspect rollouts", "err", err)
+			return false, nil
+		}
+
+		g.inspectedAt = time.Now()
+		g.noRollouts = len(rollouts) == 0
+
+		var paused []*rollout
+		for _, r := range rollouts {
+			progress := r.String()
+			if g.progress[r.node.Name] != progress {
+				g.progress[r.node.Name] = progress
+				log.Info(progress, "rollout", r.node.Name, "message", r.Status.Message)
+			}
+
+			if r.isPaused() {
+				paused = append(paused, r)
+			}
+		}
+
+		if len(paused) == 0 {
+			return false, nil
+		}
+
+		summary := make([]string, 0, len(paused))
+		for _, r := range paused {
+			summary = append(summary, r.String())
+		}
+
+		switch g.policy {
+		case manager.RolloutPausedAccept:
+			if app.Status.Sync.Status != applicationv1.SyncStatusCodeSynced {
+				return false, nil
+			}
+
+			g.paused = strings.Join(summary, "; ")
+			log.Info("rollout is paused, the sync is completed")
+			return true, nil
+		case manager.RolloutPausedPromote:
+			for _, r := range paused {
+				current, _ := r.step()
+				key := fmt.Sprintf("%s/%d", r.node.Name, current)
+				if g.resumed[key] {
+					continue
+				}
+
+				if err := g.c.runRolloutAction(g.ctx, app.Name, r.node, rolloutActionResume); err != nil {
+					log.Error(err, "failed to resume rollout", "rollout", r.node.Name)
+					continue
+				}
+
+				g.resumed[key] = true
+				log.Info("rollout is resumed", "rollout", r.node.Name, "step", current)
+			}
+		case manager.RolloutPausedAbort:
+			for _, r := range paused {
+				if err := g.c.runRolloutAction(g.ctx, app.Name, r.node, rolloutActionAbort); err != nil {
+					log.Error(err, "failed to abort rollout", "rollout", r.node.Name)
+					continue
+				}
+
+				log.Info("rollout is aborted", "rollout", r.node.Name)
+			}
+
+			return false, errs.Wrapf(ErrRolloutAborted, "%s", strings.Join(summary, "; "))
+		}
+
+		return false, nil
+	}
+}
+
+// rollouts returns the Argo Rollouts of the application along with their live state
+func (c *Client) rollouts(ctx context.Context, appName string) (rollouts []*rollout, err error) {
+	err = c.call(func(appClient applicationpkg.ApplicationServiceClient) error {
+		rollouts, err = c.listRollouts(ctx, appClient, appName)
+		return err
+	})
+
+	return rollouts, err
+}
+
+func (c *Client) listRollouts(ctx context.Context, appClient applicationpkg.ApplicationServiceClient, appName string) ([]*rollout, error) {
+	tree, err := appClient.ResourceTree(ctx, &applicationpkg.ResourcesQuery{ApplicationName: ptr.To(appName)})
+	if err != nil {
+		return nil, err
+	}
+
+	var rollouts []*rollout
+	for _, node := range tree.Nodes {
+		if node.Group != rolloutGroup || node.Kind != rolloutKind {
+			continue
+		}
+
+		res, err := appClient.GetResource(ctx, &applicationpkg.ApplicationResourceRequest{
+			Name:         ptr.To(appName),
+			Namespace:    ptr.To(node.Namespace),
+			ResourceName: ptr.To(node.Name),
+			Version:      ptr.To(node.Version),
+			Group:        ptr.To(node.Group),
+			Kind:         ptr.To(node.Kind),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		r := &rollout{node: node}
+		if err := json.Unmarshal([]byte(res.GetManifest()), r); err != nil {
+			return nil, fmt.Errorf("failed to decode rollout %s: %w", node.Name, err)
+		}
+
+		rollouts = append(rollouts, r)
+	}
+
+	return rollouts, nil
+}
+
+// runRolloutAction runs the Argo Rollouts action on the rollout through the Argo CD resource action
+func (c *Client) runRolloutAction(ctx context.Context, appName string, node applicationv1.ResourceNode, action string) error {
+	return c.call(func(appClient applicationpkg.ApplicationServiceClient) error {
+		_, err := appClient.RunResourceAction(ctx, &applicationpkg.ResourceActionRunRequest{
+			Name:         ptr.To(appName),
+			Namespace:    ptr.To(node.Namespace),
+			ResourceName: ptr.To(node.Name),
+			Version:      ptr.To(node.Version),
+			Group:        ptr.To(node.Group),
+			Kind:         ptr.To(node.Kind),
+			Action:       ptr.To(action),
+		})
+		return err
+	})
+}
@@ -2,13 +2,17 @@ package argocd
 
 import (
 	"fmt"
+	"slices"
 
+	"github.com/ardikabs/dpl/internal/errs"
 	"github.com/ardikabs/dpl/internal/manager"
 	"github.com/ardikabs/dpl/internal/types"
+	applicationpkg "github.com/argoproj/argo-cd/v2/pkg/apiclient/application"
 	applicationv1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
 	"github.com/argoproj/gitops-engine/pkg/health"
 
 	"github.com/go-logr/logr"
+	"k8s.io/utils/ptr"
 )
 
 func appsToReleases(req *manager.ListReleaseRequest, apps []applicationv1.Application) (types.ListReleases, error) {
@@ -32,6 +36,18 @@ func appsToReleases(req *manager.ListReleaseRequest, apps []applicationv1.Applic
 			Name:        req.GetReleaseFrom(app.Labels),
 			Environment: req.GetEnvironmentFrom(app.Labels),
 			Cluster:     req.GetClusterFrom(app.Labels),
+			Labels:      app.Labels,
+			Annotations: app.Annotations,
+			Status: types.ReleaseStatus{
+				Sync:   string(app.Status.Sync.Status),
+				Health: string(app.Status.Health.Status),
+			},
+
+			Project:           app.Spec.Project,
+			Namespace:         app.Spec.Destination.Namespace,
+			DestinationServer: app.Spec.Destination.Server,
+			DestinationName:   app.Spec.Destination.Name,
+
 			GitURL:      app.Spec.Source.RepoURL,
 			GitPath:     app.Spec.Source.Path,
 			GitRevision: app.Spec.Source.TargetRevision,
@@ -91,8 +107,112 @@ func checkAppHealthStatus(log logr.Logger, app applicationv1.Application) (bool,
 		log.V(1).Info("application health status check is failed or missing")
 	case health.HealthStatusDegraded:
 		log.V(1).Info("application health degraded for some reason, please check")
+		if app.Status.Health.Message == "" {
+			return false, ErrStatusHealthDegraded
+		}
+
 		return false, fmt.Errorf("%w, %s", ErrStatusHealthDegraded, app.Status.Health.Message)
 	}
 
 	return false, nil
 }
+
+// syncResult classifies the outcome of the release sync, along with the message of the failure
+func syncResult(status types.ReleaseStatus, err error) (string, string) {
+	switch {
+	case err == nil && status.Health == string(health.HealthStatusSuspended):
+		// the sync is only completed while suspended on the paused rollout, which tells its progress
+		return types.ReleaseResultPaused, status.Message
+	case err == nil:
+		return types.ReleaseResultSucceeded, ""
+	case errs.IsAny(err, ErrSyncCancelled):
+		return types.ReleaseResultCancelled, err.Error()
+	case errs.IsAny(err, ErrRolloutAborted):
+		return types.ReleaseResultAborted, err.Error()
+	case errs.IsAny(err, ErrStatusHealthDegraded):
+		return types.ReleaseResultDegraded, err.Error()
+	case errs.IsAny(err, ErrSyncOnWatchTimeout, ErrSyncOperationTimeout):
+		// the release which never turns healthy is rather degraded than slow
+		if status.Health == string(health.HealthStatusDegraded) {
+			return types.ReleaseResultDegraded, err.Error()
+		}
+
+		return types.ReleaseResultTimeout, err.Error()
+	default:
+		return types.ReleaseResultSyncFailed, err.Error()
+	}
+}
+
+// orderReleases groups the releases into waves by the label value in the order of the values,
+// the releases with other values form the last wave. There is a single wave without the label.
+func orderReleases(rels types.ListReleases, label string, values []string) []types.ListReleases {
+	if label == "" || len(values) == 0 {
+		return []types.ListReleases{rels}
+	}
+
+	waves := make([]types.ListReleases, len(values)+1)
+	for _, rel := range rels {
+		idx := slices.Index(values, rel.Labels[label])
+		if idx < 0 {
+			idx = len(values)
+		}
+
+		waves[idx] = append(waves[idx], rel)
+	}
+
+	return slices.DeleteFunc(waves, func(wave types.ListReleases) bool {
+		return len(wave) == 0
+	})
+}
+
+// newSyncRequest returns the sync request of the application along with the sync parameters of the options
+func newSyncRequest(appName string, o *manager.Options) *applicationpkg.ApplicationSyncRequest {
+	req := &applicationpkg.ApplicationSyncRequest{Name: ptr.To(appName)}
+
+	if o.Prune {
+		req.Prune = ptr.To(true)
+	}
+
+	if o.DryRun {
+		req.DryRun = ptr.To(true)
+	}
+
+	apply := applicationv1.SyncStrategyApply{Force: o.Force}
+	switch {
+	case o.SyncStrategy == manager.SyncStrategyApply:
+		req.Strategy = &applicationv1.SyncStrategy{Apply: &apply}
+	case o.SyncStrategy == manager.SyncStrategyHook || o.Force:
+		req.Strategy = &applicationv1.SyncStrategy{Hook: &applicationv1.SyncStrategyHook{SyncStrategyApply: apply}}
+	}
+
+	if len(o.SyncOptions) > 0 {
+		req.SyncOptions = &applicationpkg.SyncOptions{Items: o.SyncOptions}
+	}
+
+	for _, res := range o.SyncResources {
+		req.Resources = append(req.Resources, &applicationv1.SyncOperationResource{
+			Group:     res.Group,
+			Kind:      res.Kind,
+			Namespace: res.Namespace,
+			Name:      res.Name,
+		})
+	}
+
+	if o.SyncRetry.Limit != 0 {
+		req.RetryStrategy = &applicationv1.RetryStrategy{Limit: o.SyncRetry.Limit}
+
+		if o.SyncRetry.Backoff > 0 {
+			req.RetryStrategy.Backoff = &applicationv1.Backoff{Duration: o.SyncRetry.Backoff.String()}
+
+			if o.SyncRetry.MaxBackoff > 0 {
+				req.RetryStrategy.Backoff.MaxDuration = o.SyncRetry.MaxBackoff.String()
+			}
+
+			if o.SyncRetry.BackoffFactor > 0 {
+				req.RetryStrategy.Backoff.Factor = ptr.To(o.SyncRetry.BackoffFactor)
+			}
+		}
+	}
+
+	return req
+}
@@ -7,13 +7,21 @@ import (
 
 	"github.com/ardikabs/dpl/internal/errs"
 	"github.com/ardikabs/dpl/internal/manager"
+	"github.com/ardikabs/dpl/internal/tracing"
 	applicationv1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type appConditionFunc func(log logr.Logger, app applicationv1.Application) (bool, error)
 
-func (c *Client) watch(ctx context.Context, app *applicationv1.Application, condition appConditionFunc, opts ...manager.Option) error {
+// watch waits until the application observed from the events satisfies the condition,
+// it returns the last observed application status regardless the watch is succeeded or not.
+func (c *Client) watch(ctx context.Context, app *applicationv1.Application, events <-chan applicationv1.Application, condition appConditionFunc, opts ...manager.Option) (_ applicationv1.ApplicationStatus, err error) {
+	ctx, span := tracing.Start(ctx, "Manager.watch", attribute.String("argocd.application", app.Name))
+	defer tracing.End(span, &err)
+
 	options := manager.NewDefaultOptions()
 	for _, o := range opts {
 		o(options)
@@ -25,34 +33,35 @@ func (c *Client) watch(ctx context.Context, app *applicationv1.Application, cond
 	log := options.Logger.WithValues("operation", "watch")
 
 	var unknownRetryCount uint
-
-	appCh := c.argocdClient.WatchApplicationWithRetry(ctx, app.Name, app.Spec.Source.TargetRevision)
+	lastStatus := app.Status
 
 	for {
 		select {
-		case ev, isOpen := <-appCh:
+		case app, isOpen := <-events:
 			if !isOpen {
-				return ErrStatusSyncUnknown
+				return lastStatus, ErrStatusSyncUnknown
 			}
 
-			app := ev.Application
+			lastStatus = app.Status
+
 			good, err := condition(log, app)
 			if err != nil {
 				if errs.IsAny(err, ErrStatusSyncUnknown) {
 					if unknownRetryCount > uint(options.MaxRetryUnknownCount) {
-						return err
+						return lastStatus, err
 					}
 
 					// Trigger refresh
 					if _, err := c.getApplication(ctx, app.Name); err != nil {
-						return err
+						return lastStatus, err
 					}
 
 					unknownRetryCount++
+					span.AddEvent("refresh on unknown status", trace.WithAttributes(attribute.Int("retry.attempt", int(unknownRetryCount))))
 					continue
 				}
 
-				return err
+				return lastStatus, err
 			}
 
 			if good {
@@ -61,15 +70,15 @@ func (c *Client) watch(ctx context.Context, app *applicationv1.Application, cond
 					"health.status", app.Status.Health.Status,
 					"revision", app.Status.GetRevisions()[0],
 				)
-				return nil
+				return lastStatus, nil
 			}
 
 		case <-ctx.Done():
 			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
-				return ErrSyncOnWatchTimeout
+				return lastStatus, ErrSyncOnWatchTimeout
 			}
 
-			return nil
+			return lastStatus, errs.Wrap(context.Cause(ctx), ErrSyncCancelled)
 		}
 	}
 }
@@ -0,0 +1,187 @@
+package argocd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/types"
+	applicationpkg "github.com/argoproj/argo-cd/v2/pkg/apiclient/application"
+	applicationv1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/go-logr/logr"
+)
+
+// watchReconnectInterval is the interval before reopening the broken watch stream
+var watchReconnectInterval = time.Second
+
+// watcher multiplexes a single Application watch stream to the per-application waiters,
+// the stream is reopened when it is broken until the watcher is stopped, on a new connection when the session is expired.
+type watcher struct {
+	mu      sync.Mutex
+	waiters map[string][]chan applicationv1.Application
+	stopped bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startWatch opens the watch stream of the Applications matching the query
+func (c *Client) startWatch(ctx context.Context, log logr.Logger, query *applicationpkg.ApplicationQuery) (*watcher, error) {
+	if _, err := c.applicationClient(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	w := &watcher{
+		waiters: make(map[string][]chan applicationv1.Application),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(w.done)
+		defer w.closeWaiters()
+
+		for {
+			appClient, err := c.applicationClient()
+			if err == nil {
+				err = w.stream(ctx, appClient, query)
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			// the session might be expired during the long watch
+			if isUnauthenticated(err) {
+				log.Info("argocd session is expired, re-authenticating")
+				c.reauthenticate(appClient)
+			}
+
+			log.V(1).Info("application watch stream is broken, reconnecting", "err", err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(watchReconnectInterval):
+			}
+		}
+	}()
+
+	return w, nil
+}
+
+func (w *watcher) stream(ctx context.Context, appClient applicationpkg.ApplicationServiceClient, query *applicationpkg.ApplicationQuery) error {
+	wc, err := appClient.Watch(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	for {
+		ev, err := wc.Recv()
+		if err != nil {
+			return err
+		}
+
+		w.dispatch(ev.Application)
+	}
+}
+
+// dispatch hands the application over to its waiters, a slow waiter only gets the latest state
+func (w *watcher) dispatch(app applicationv1.Application) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, ch := range w.waiters[app.Name] {
+		select {
+		case <-ch:
+		default:
+		}
+
+		ch <- app
+	}
+}
+
+// subscribe returns the channel of the application states, it is closed when the watcher is stopped
+func (w *watcher) subscribe(appName string) (<-chan applicationv1.Application, func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ch := make(chan applicationv1.Application, 1)
+	if w.stopped {
+		close(ch)
+		return ch, func() {}
+	}
+
+	w.waiters[appName] = append(w.waiters[appName], ch)
+
+	return ch, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		if idx := slices.Index(w.waiters[appName], ch); idx >= 0 {
+			w.waiters[appName] = slices.Delete(w.waiters[appName], idx, idx+1)
+		}
+	}
+}
+
+func (w *watcher) closeWaiters() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.stopped = true
+	for name, chs := range w.waiters {
+		for _, ch := range chs {
+			close(ch)
+		}
+
+		delete(w.waiters, name)
+	}
+}
+
+// stop closes the watch stream and waits until it is done
+func (w *watcher) stop() {
+	w.cancel()
+	<-w.done
+}
+
+// releasesSelector returns the label selector matching every release, from the labels they have in common,
+// it might match other Applications as well, which are left out by the watcher.
+func releasesSelector(rels types.ListReleases) string {
+	if len(rels) == 0 {
+		return ""
+	}
+
+	var requirements []string
+	for key := range rels[0].Labels {
+		var values []string
+		for _, rel := range rels {
+			value, ok := rel.Labels[key]
+			if !ok {
+				values = nil
+				break
+			}
+
+			if !slices.Contains(values, value) {
+				values = append(values, value)
+			}
+		}
+
+		switch len(values) {
+		case 0:
+			continue
+		case 1:
+			requirements = append(requirements, key+"="+values[0])
+		default:
+			sort.Strings(values)
+			requirements = append(requirements, fmt.Sprintf("%s in (%s)", key, strings.Join(values, ",")))
+		}
+	}
+
+	sort.Strings(requirements)
+	return strings.Join(requirements, ",")
+}
@@ -10,4 +10,8 @@ type Interface interface {
 	ListReleases(ctx context.Context, req *ListReleaseRequest, opts ...Option) (types.ListReleases, error)
 	SyncRelease(ctx context.Context, rel *types.Release, opts ...Option) error
 	SyncReleases(ctx context.Context, rels types.ListReleases, opts ...Option) error
+	CreateRelease(ctx context.Context, rel *types.Release, opts ...Option) error
+	DeleteRelease(ctx context.Context, rel *types.Release, opts ...Option) error
+	AnnotateRelease(ctx context.Context, rel *types.Release, annotations map[string]string, opts ...Option) error
+	Close() error
 }
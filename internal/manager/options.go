@@ -1,21 +1,81 @@
 package manager
 
-import "github.com/go-logr/logr"
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+)
 
 var (
 	DefaultTimeout uint = 900 // 15 minutes
 )
 
+// SyncStrategy* are the strategies the manifests are synced with
+const (
+	SyncStrategyApply = "apply"
+	SyncStrategyHook  = "hook"
+)
+
+// RolloutPaused* are the policies on the paused Argo Rollouts of the release, as the canary pauses for the analysis
+const (
+	// RolloutPausedWait keeps waiting for the rollout to be resumed, until the sync times out
+	RolloutPausedWait = "wait"
+
+	// RolloutPausedAccept completes the sync once the rollout is paused, the release is left paused
+	RolloutPausedAccept = "accept"
+
+	// RolloutPausedPromote resumes the rollout on every pause, until it is fully promoted
+	RolloutPausedPromote = "promote"
+
+	// RolloutPausedAbort aborts the rollout once it is paused, then the sync fails
+	RolloutPausedAbort = "abort"
+)
+
+// SyncResource selects a resource to be synced, instead of every resource of the release
+type SyncResource struct {
+	Group     string
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// SyncRetry retries the failed sync operation on the server, up to the limit, with an exponential backoff,
+// zero limit disables the retry while a negative one retries indefinitely.
+type SyncRetry struct {
+	Limit         int64
+	Backoff       time.Duration
+	BackoffFactor int64
+	MaxBackoff    time.Duration
+}
+
 type Options struct {
 	Logger               logr.Logger
 	TimeoutSec           uint
 	MaxRetryUnknownCount int
+	Cascade              bool
+	MaxConcurrency       int
+	KeepGoing            bool
+	SyncDelay            time.Duration
+	SyncOrderLabel       string
+	SyncOrder            []string
+	TerminateOnCancel    bool
+	Prune                bool
+	DryRun               bool
+	Force                bool
+	SyncStrategy         string
+	SyncOptions          []string
+	SyncResources        []SyncResource
+	SyncRetry            SyncRetry
+	RolloutPaused        string
+	ApplicationSets      bool
 }
 
 func NewDefaultOptions(opts ...Option) *Options {
 	o := &Options{
 		TimeoutSec:           DefaultTimeout,
 		MaxRetryUnknownCount: 5,
+		Cascade:              true,
+		RolloutPaused:        RolloutPausedWait,
 	}
 
 	for _, opt := range opts {
@@ -44,3 +104,116 @@ func WithLogger(logger logr.Logger) Option {
 		opts.Logger = logger
 	}
 }
+
+func WithCascade(cascade bool) Option {
+	return func(opts *Options) {
+		opts.Cascade = cascade
+	}
+}
+
+// WithMaxConcurrency limits the number of releases synced concurrently, zero or negative means unlimited
+func WithMaxConcurrency(maxConcurrency int) Option {
+	return func(opts *Options) {
+		opts.MaxConcurrency = maxConcurrency
+	}
+}
+
+// WithKeepGoing lets every release sync run to completion regardless the other failures,
+// the failures are returned at once, instead of cancelling the rest on the first failure.
+func WithKeepGoing(keepGoing bool) Option {
+	return func(opts *Options) {
+		opts.KeepGoing = keepGoing
+	}
+}
+
+// WithSyncDelay waits for the delay before starting every release sync but the first,
+// to spread the load on the server.
+func WithSyncDelay(delay time.Duration) Option {
+	return func(opts *Options) {
+		opts.SyncDelay = delay
+	}
+}
+
+// WithSyncOrder syncs the releases in waves by the value of the label, in the order of the values,
+// the releases with other values are synced last. The next wave starts once the previous one completes.
+func WithSyncOrder(label string, values ...string) Option {
+	return func(opts *Options) {
+		opts.SyncOrderLabel = label
+		opts.SyncOrder = values
+	}
+}
+
+// WithTerminateOnCancel terminates the running sync operation when the sync is cancelled or timed out,
+// otherwise the operation keeps running on the server.
+func WithTerminateOnCancel(terminate bool) Option {
+	return func(opts *Options) {
+		opts.TerminateOnCancel = terminate
+	}
+}
+
+// WithPrune deletes the resources which are no longer part of the release manifests
+func WithPrune(prune bool) Option {
+	return func(opts *Options) {
+		opts.Prune = prune
+	}
+}
+
+// WithDryRun syncs the release without applying the changes, the sync completes once the operation succeeded
+func WithDryRun(dryRun bool) Option {
+	return func(opts *Options) {
+		opts.DryRun = dryRun
+	}
+}
+
+// WithForce replaces the resources which cannot be patched, by deleting and recreating them
+func WithForce(force bool) Option {
+	return func(opts *Options) {
+		opts.Force = force
+	}
+}
+
+// WithSyncStrategy syncs the release with either SyncStrategyApply or SyncStrategyHook,
+// empty means the default strategy of the server.
+func WithSyncStrategy(strategy string) Option {
+	return func(opts *Options) {
+		opts.SyncStrategy = strategy
+	}
+}
+
+// WithSyncOptions sets the sync options of the operation, such as ServerSideApply=true or ApplyOutOfSyncOnly=true
+func WithSyncOptions(syncOptions ...string) Option {
+	return func(opts *Options) {
+		opts.SyncOptions = syncOptions
+	}
+}
+
+// WithSyncResources syncs only the selected resources of the release, the sync completes once the operation
+// succeeded and the release is healthy, as the other resources might be left out of sync.
+func WithSyncResources(resources ...SyncResource) Option {
+	return func(opts *Options) {
+		opts.SyncResources = resources
+	}
+}
+
+// WithSyncRetry retries the failed sync operation on the server
+func WithSyncRetry(retry SyncRetry) Option {
+	return func(opts *Options) {
+		opts.SyncRetry = retry
+	}
+}
+
+// WithRolloutPaused sets the policy on the paused Argo Rollouts of the release, either RolloutPausedWait,
+// RolloutPausedAccept, RolloutPausedPromote, or RolloutPausedAbort.
+func WithRolloutPaused(policy string) Option {
+	return func(opts *Options) {
+		opts.RolloutPaused = policy
+	}
+}
+
+// WithApplicationSets discovers the releases through the ApplicationSets as well, the ApplicationSets are matched
+// by their labels or their template labels, then their generated Applications are enumerated.
+func WithApplicationSets(enabled bool) Option {
+	return func(opts *Options) {
+		opts.ApplicationSets = enabled
+	}
+}
@@ -26,6 +26,10 @@ type ListReleaseRequest struct {
 	selectors         []string
 
 	Selector string
+
+	// Environment and Cluster are the selected environment and cluster, empty when any of them is selected
+	Environment string
+	Cluster     string
 }
 
 func (r *ListReleaseRequest) GetReleaseFrom(labels map[string]string) string {
@@ -52,6 +56,16 @@ func (r *ListReleaseRequest) GetEnvironmentFrom(labels map[string]string) string
 	return r.environmentGetter(labels)
 }
 
+// Requirements returns the selected label values by their keys
+func (r *ListReleaseRequest) Requirements() map[string]string {
+	requirements := make(map[string]string, len(r.selectors)/2)
+	for i := 0; i+1 < len(r.selectors); i += 2 {
+		requirements[r.selectors[i]] = r.selectors[i+1]
+	}
+
+	return requirements
+}
+
 type ListReleaseRequestBuilder struct {
 	req *ListReleaseRequest
 }
@@ -75,6 +89,7 @@ func (b *ListReleaseRequestBuilder) SetReleaseSelector(key, value string) *ListR
 
 func (b *ListReleaseRequestBuilder) SetEnvironmentSelector(key, value string) *ListReleaseRequestBuilder {
 	b.req.environmentGetter = createLabelGetter(key)
+	b.req.Environment = value
 
 	if value == "" {
 		return b
@@ -86,7 +101,17 @@ func (b *ListReleaseRequestBuilder) SetEnvironmentSelector(key, value string) *L
 
 func (b *ListReleaseRequestBuilder) SetClusterSelector(key, value string) *ListReleaseRequestBuilder {
 	b.req.clusterGetter = createLabelGetter(key)
+	b.req.Cluster = value
+
+	if value == "" {
+		return b
+	}
+
+	b.req.selectors = append(b.req.selectors, key, value)
+	return b
+}
 
+func (b *ListReleaseRequestBuilder) SetLabelSelector(key, value string) *ListReleaseRequestBuilder {
 	if value == "" {
 		return b
 	}
@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ardikabs/dpl/internal/result"
+	"github.com/ardikabs/dpl/internal/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+const (
+	namespace = "dpl"
+	jobName   = "dpl"
+)
+
+var (
+	// Registry holds every dpl metric, it doesn't include the Go runtime metrics
+	// as they are meaningless for a short-lived CLI run.
+	Registry = prometheus.NewRegistry()
+
+	deploymentsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deployments_total",
+		Help:      "Total number of deployments per release, environment, cluster, and result.",
+	}, []string{"release", "environment", "cluster", "result"})
+
+	deploymentDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "deployment_duration_seconds",
+		Help:      "Duration of the deployments, from the request until the releases are synced and healthy.",
+		Buckets:   []float64{30, 60, 120, 300, 600, 900, 1800, 3600},
+	}, []string{"release", "environment", "result"})
+
+	stageDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "deployment_stage_duration_seconds",
+		Help:      "Duration of each deployment stage, such as clone, render, commit, push, and sync.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 12),
+	}, []string{"stage", "result"})
+
+	lastSuccessTimestampSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "deployment_last_success_timestamp_seconds",
+		Help:      "Unix timestamp of the last successful deployment per release, environment, and cluster.",
+	}, []string{"release", "environment", "cluster"})
+
+	// the last run gauges describe a single run on their own, so they stay meaningful once pushed or written per run,
+	// where the counters and the histograms start over on every CLI run.
+	lastRunTimestampSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "deployment_last_run_timestamp_seconds",
+		Help:      "Unix timestamp of the last deployment per release, environment, and cluster, whatever the result is.",
+	}, []string{"release", "environment", "cluster"})
+
+	lastRunSucceeded = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "deployment_last_run_succeeded",
+		Help:      "Whether the last deployment per release, environment, and cluster is succeeded, either 1 or 0.",
+	}, []string{"release", "environment", "cluster"})
+
+	lastRunLeadTimeSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "deployment_last_run_lead_time_seconds",
+		Help:      "Lead time of the last successful deployment per release and environment, from the revision commit until the releases are healthy.",
+	}, []string{"release", "environment"})
+
+	leadTimeSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "deployment_lead_time_seconds",
+		Help:      "Lead time of the successful deployments, from the revision commit until the releases are healthy.",
+		Buckets:   []float64{300, 900, 1800, 3600, 7200, 14400, 43200, 86400, 259200, 604800},
+	}, []string{"release", "environment"})
+)
+
+func init() {
+	Registry.MustRegister(
+		deploymentsTotal,
+		deploymentDurationSeconds,
+		stageDurationSeconds,
+		lastSuccessTimestampSeconds,
+		lastRunTimestampSeconds,
+		lastRunSucceeded,
+		lastRunLeadTimeSeconds,
+		leadTimeSeconds,
+	)
+}
+
+// Observe records the metrics of a finished deployment result
+func Observe(res *result.Result) {
+	type releaseKey struct{ release, environment string }
+	seen := make(map[releaseKey]bool)
+
+	for _, rel := range res.Releases {
+		// the partial deployment is counted per cluster by the release result
+		status := res.Status
+		if status == result.StatusPartial {
+			status = result.StatusFailed
+			if types.IsSucceededResult(rel.Result) {
+				status = result.StatusSucceeded
+			}
+		}
+
+		deploymentsTotal.WithLabelValues(rel.Release, rel.Environment, rel.Cluster, status).Inc()
+		lastRunTimestampSeconds.WithLabelValues(rel.Release, rel.Environment, rel.Cluster).Set(float64(res.FinishedAt.Unix()))
+		lastRunSucceeded.WithLabelValues(rel.Release, rel.Environment, rel.Cluster).Set(0)
+
+		if status == result.StatusSucceeded {
+			lastSuccessTimestampSeconds.WithLabelValues(rel.Release, rel.Environment, rel.Cluster).Set(float64(res.FinishedAt.Unix()))
+			lastRunSucceeded.WithLabelValues(rel.Release, rel.Environment, rel.Cluster).Set(1)
+		}
+
+		key := releaseKey{rel.Release, rel.Environment}
+		if !seen[key] {
+			seen[key] = true
+			deploymentDurationSeconds.WithLabelValues(rel.Release, rel.Environment, res.Status).Observe(res.DurationSeconds)
+
+			// the lead time is only known when the commit time of the deployed revision is
+			if res.Status == result.StatusSucceeded && res.RevisionTime != nil {
+				leadTime := res.FinishedAt.Sub(*res.RevisionTime).Seconds()
+				leadTimeSeconds.WithLabelValues(rel.Release, rel.Environment).Observe(leadTime)
+				lastRunLeadTimeSeconds.WithLabelValues(rel.Release, rel.Environment).Set(leadTime)
+			}
+		}
+	}
+
+	for _, stage := range res.Stages {
+		stageDurationSeconds.WithLabelValues(stage.Name, res.Status).Observe(stage.DurationSeconds)
+	}
+}
+
+// Push adds the metrics to the Pushgateway, grouped by the given labels,
+// so runs of different groups don't overwrite each other.
+func Push(ctx context.Context, url string, grouping map[string]string) error {
+	pusher := push.New(url, jobName).Gatherer(Registry)
+	for k, v := range grouping {
+		pusher = pusher.Grouping(k, v)
+	}
+
+	return pusher.AddContext(ctx)
+}
+
+// WriteTextfile writes the metrics in the format of node exporter textfile collector, the file is written atomically
+func WriteTextfile(filename string) error {
+	return prometheus.WriteToTextfile(filename, Registry)
+}
+
+// Handler serves the metrics for scraping
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
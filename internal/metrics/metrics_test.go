@@ -0,0 +1,68 @@
+package metrics_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/metrics"
+	"github.com/ardikabs/dpl/internal/result"
+	"github.com/ardikabs/dpl/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserve(t *testing.T) {
+	res := result.New("b6d7153")
+	revisionTime := res.StartedAt.Add(-time.Hour)
+	res.RevisionTime = &revisionTime
+	res.Track("sync")()
+	res.SetReleases(types.ListReleases{
+		{ID: "myapp-dev-1", Name: "myapp", Environment: "dev", Cluster: "dev-1"},
+		{ID: "myapp-dev-2", Name: "myapp", Environment: "dev", Cluster: "dev-2"},
+	})
+	res.Finish(nil)
+
+	metrics.Observe(res)
+
+	t.Run("textfile", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "dpl.prom")
+		require.NoError(t, metrics.WriteTextfile(file))
+
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.Contains(t, string(content), `dpl_deployments_total{cluster="dev-1",environment="dev",release="myapp",result="succeeded"} 1`)
+		require.Contains(t, string(content), `dpl_deployments_total{cluster="dev-2",environment="dev",release="myapp",result="succeeded"} 1`)
+		require.Contains(t, string(content), `dpl_deployment_duration_seconds_count{environment="dev",release="myapp",result="succeeded"} 1`)
+		require.Contains(t, string(content), `dpl_deployment_stage_duration_seconds_count{result="succeeded",stage="sync"} 1`)
+		require.Contains(t, string(content), `dpl_deployment_last_success_timestamp_seconds{cluster="dev-1",environment="dev",release="myapp"}`)
+		require.Contains(t, string(content), `dpl_deployment_last_run_timestamp_seconds{cluster="dev-2",environment="dev",release="myapp"}`)
+		require.Contains(t, string(content), `dpl_deployment_last_run_succeeded{cluster="dev-2",environment="dev",release="myapp"} 1`)
+		require.Contains(t, string(content), `dpl_deployment_lead_time_seconds_count{environment="dev",release="myapp"} 1`)
+		require.Contains(t, string(content), `dpl_deployment_lead_time_seconds_bucket{environment="dev",release="myapp",le="3600"} 0`)
+		require.Contains(t, string(content), `dpl_deployment_lead_time_seconds_bucket{environment="dev",release="myapp",le="7200"} 1`)
+	})
+
+	t.Run("pushgateway", func(t *testing.T) {
+		var method, path string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method, path = r.Method, r.URL.Path
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		require.NoError(t, metrics.Push(context.Background(), srv.URL, map[string]string{"deployment": "myapp"}))
+		require.Equal(t, http.MethodPost, method)
+		require.Equal(t, "/metrics/job/dpl/deployment/myapp", path)
+	})
+
+	t.Run("handler", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), "dpl_deployments_total")
+	})
+}
@@ -0,0 +1,285 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/errs"
+	"github.com/ardikabs/dpl/internal/tools/ioutils"
+	goyaml "gopkg.in/yaml.v3"
+)
+
+var (
+	DefaultTimeoutSec uint = 10
+
+	ErrNotificationFailed    = errors.New("notification failed")
+	ErrNotifierInvalidConfig = errors.New("invalid notifier configuration")
+)
+
+// EventType is the deployment lifecycle event a notification is sent for
+type EventType string
+
+const (
+	EventStarted   EventType = "started"
+	EventSucceeded EventType = "succeeded"
+	EventFailed    EventType = "failed"
+)
+
+func (e EventType) isValid() bool {
+	switch e {
+	case EventStarted, EventSucceeded, EventFailed:
+		return true
+	}
+
+	return false
+}
+
+// Event is the deployment lifecycle event, it is the data of the message template
+// and the payload of the generic webhook.
+type Event struct {
+	Type        EventType       `json:"type"`
+	RequestID   string          `json:"requestID"`
+	Release     string          `json:"release,omitempty"`
+	Environment string          `json:"environment,omitempty"`
+	Image       string          `json:"image,omitempty"`
+	CommitSHA   string          `json:"commitSHA,omitempty"`
+	CommitURL   string          `json:"commitURL,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Releases    []ReleaseStatus `json:"releases,omitempty"`
+	Timestamp   time.Time       `json:"timestamp"`
+}
+
+type ReleaseStatus struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Environment  string `json:"environment"`
+	Cluster      string `json:"cluster"`
+	Image        string `json:"image"`
+	SyncStatus   string `json:"syncStatus,omitempty"`
+	HealthStatus string `json:"healthStatus,omitempty"`
+	Result       string `json:"result,omitempty"`
+	Message      string `json:"message,omitempty"`
+}
+
+// environments returns the distinct environments of the event
+func (e Event) environments() []string {
+	if e.Environment != "" {
+		return []string{e.Environment}
+	}
+
+	var out []string
+	for _, rel := range e.Releases {
+		if !slices.Contains(out, rel.Environment) {
+			out = append(out, rel.Environment)
+		}
+	}
+
+	return out
+}
+
+// SinkType is the kind of system the notification is delivered to
+type SinkType string
+
+const (
+	SinkSlack   SinkType = "slack"
+	SinkTeams   SinkType = "teams"
+	SinkWebhook SinkType = "webhook"
+)
+
+// Sink delivers the event to an external system
+type Sink interface {
+	Send(ctx context.Context, ev Event) error
+}
+
+type SinkConfig struct {
+	Name string   `yaml:"name"`
+	Type SinkType `yaml:"type"`
+	// URL is the incoming webhook URL, environment variables are expanded, so it doesn't need to be stored in the file.
+	URL string `yaml:"url"`
+	// Secret signs the generic webhook payload with HMAC-SHA256, environment variables are expanded.
+	Secret string `yaml:"secret"`
+	// Events filters the events to be sent, all events are sent when it is empty.
+	Events []EventType `yaml:"events"`
+	// Environments filters the environments to be notified, all environments are notified when it is empty.
+	Environments []string `yaml:"environments"`
+	// Template is the Go template of the message, rendered with the Event.
+	Template   string `yaml:"template"`
+	TimeoutSec uint   `yaml:"timeoutSec"`
+}
+
+func (c SinkConfig) matches(ev Event) bool {
+	if len(c.Events) > 0 && !slices.Contains(c.Events, ev.Type) {
+		return false
+	}
+
+	if len(c.Environments) == 0 {
+		return true
+	}
+
+	for _, env := range ev.environments() {
+		if slices.Contains(c.Environments, env) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type config struct {
+	Notifiers []SinkConfig `yaml:"notifiers"`
+}
+
+type entry struct {
+	SinkConfig
+	sink Sink
+}
+
+type Notifier struct {
+	entries []entry
+}
+
+// Load reads the notifiers configuration file, for example:
+//
+//	notifiers:
+//	  - name: production
+//	    type: slack
+//	    url: ${SLACK_WEBHOOK_URL}
+//	    events: [started, succeeded, failed]
+//	    environments: [production]
+func Load(filename string) (*Notifier, error) {
+	content, err := ioutils.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg config
+	if err := goyaml.Unmarshal(content, &cfg); err != nil {
+		return nil, err
+	}
+
+	return New(cfg.Notifiers...)
+}
+
+func New(cfgs ...SinkConfig) (*Notifier, error) {
+	n := &Notifier{entries: make([]entry, 0, len(cfgs))}
+
+	for idx, cfg := range cfgs {
+		if cfg.Name == "" {
+			cfg.Name = fmt.Sprintf("notifier-%d", idx)
+		}
+
+		for _, ev := range cfg.Events {
+			if !ev.isValid() {
+				return nil, errs.Wrapf(ErrNotifierInvalidConfig, "notifier %s has unknown event '%s'", cfg.Name, ev)
+			}
+		}
+
+		cfg.URL = os.ExpandEnv(cfg.URL)
+		cfg.Secret = os.ExpandEnv(cfg.Secret)
+		if cfg.URL == "" {
+			return nil, errs.Wrapf(ErrNotifierInvalidConfig, "notifier %s has no url", cfg.Name)
+		}
+
+		if cfg.TimeoutSec == 0 {
+			cfg.TimeoutSec = DefaultTimeoutSec
+		}
+
+		text := cfg.Template
+		if text == "" {
+			text = DefaultTemplate
+		}
+
+		tmpl, err := template.New(cfg.Name).Parse(text)
+		if err != nil {
+			return nil, errs.Wrapf(ErrNotifierInvalidConfig, "notifier %s has invalid template: %s", cfg.Name, err)
+		}
+
+		httpClient := &http.Client{Timeout: time.Duration(cfg.TimeoutSec) * time.Second}
+
+		var sink Sink
+		switch cfg.Type {
+		case SinkSlack:
+			sink = &slackSink{url: cfg.URL, tmpl: tmpl, client: httpClient}
+		case SinkTeams:
+			sink = &teamsSink{url: cfg.URL, tmpl: tmpl, client: httpClient}
+		case SinkWebhook:
+			sink = &webhookSink{url: cfg.URL, secret: cfg.Secret, tmpl: tmpl, client: httpClient}
+		default:
+			return nil, errs.Wrapf(ErrNotifierInvalidConfig, "notifier %s has unknown type '%s'", cfg.Name, cfg.Type)
+		}
+
+		n.entries = append(n.entries, entry{SinkConfig: cfg, sink: sink})
+	}
+
+	return n, nil
+}
+
+// With returns a copy of the notifier along with the given sinks, which receive every event.
+func (n *Notifier) With(sinks ...Sink) *Notifier {
+	out := new(Notifier)
+	if n != nil {
+		out.entries = slices.Clone(n.entries)
+	}
+
+	for idx, sink := range sinks {
+		out.entries = append(out.entries, entry{SinkConfig: SinkConfig{Name: fmt.Sprintf("sink-%d", idx)}, sink: sink})
+	}
+
+	return out
+}
+
+// Notify sends the event concurrently to every sink subscribed to it.
+// A nil notifier is valid and does nothing, so callers don't need to check whether notifiers are configured.
+func (n *Notifier) Notify(ctx context.Context, ev Event, opts ...Option) error {
+	if n == nil {
+		return nil
+	}
+
+	o := newOptions(opts...)
+	log := o.Logger.WithName("notifier.Notify").WithValues("event", ev.Type)
+
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now().UTC()
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures []error
+	)
+
+	for _, e := range n.entries {
+		if !e.matches(ev) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(e entry) {
+			defer wg.Done()
+
+			if err := e.sink.Send(ctx, ev); err != nil {
+				mu.Lock()
+				failures = append(failures, errs.Wrapf(err, "notifier: %s", e.Name))
+				mu.Unlock()
+				return
+			}
+
+			log.V(1).Info("notification sent", "notifier", e.Name, "type", e.Type)
+		}(e)
+	}
+
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return errs.Wrap(errors.Join(failures...), ErrNotificationFailed)
+	}
+
+	return nil
+}
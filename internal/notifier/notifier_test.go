@@ -0,0 +1,149 @@
+package notifier_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ardikabs/dpl/internal/notifier"
+	"github.com/stretchr/testify/require"
+)
+
+type capture struct {
+	mu       sync.Mutex
+	requests []capturedRequest
+}
+
+type capturedRequest struct {
+	header http.Header
+	body   map[string]any
+	raw    []byte
+}
+
+func (c *capture) server(t *testing.T, status int) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(raw, &body))
+
+		c.mu.Lock()
+		c.requests = append(c.requests, capturedRequest{header: r.Header.Clone(), body: body, raw: raw})
+		c.mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func newEvent(typ notifier.EventType) notifier.Event {
+	return notifier.Event{
+		Type:        typ,
+		RequestID:   "b6d7153",
+		Release:     "myapp",
+		Environment: "production",
+		Image:       "ghcr.io/ardikabs/app/myapp:v1.0.0",
+		CommitSHA:   "2a8e4d7",
+		CommitURL:   notifier.CommitURL("git@github.com:ardikabs/manifests.git", "2a8e4d7"),
+		Releases: []notifier.ReleaseStatus{
+			{ID: "myapp-prod-1", Name: "myapp", Environment: "production", Cluster: "prod-1", SyncStatus: "Synced", HealthStatus: "Healthy"},
+		},
+	}
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	var slack, teams, webhook capture
+
+	n, err := notifier.New(
+		notifier.SinkConfig{Type: notifier.SinkSlack, URL: slack.server(t, http.StatusOK).URL},
+		notifier.SinkConfig{Type: notifier.SinkTeams, URL: teams.server(t, http.StatusOK).URL, Events: []notifier.EventType{notifier.EventFailed}},
+		notifier.SinkConfig{
+			Type:     notifier.SinkWebhook,
+			URL:      webhook.server(t, http.StatusOK).URL,
+			Secret:   "s3cr3t",
+			Template: "{{ .Release }} is {{ .Type }}",
+		},
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, n.Notify(context.Background(), newEvent(notifier.EventSucceeded)))
+
+	t.Run("slack", func(t *testing.T) {
+		require.Len(t, slack.requests, 1)
+		text := slack.requests[0].body["text"]
+		require.Contains(t, text, "Deployment succeeded myapp on production (b6d7153)")
+		require.Contains(t, text, "Image: ghcr.io/ardikabs/app/myapp:v1.0.0")
+		require.Contains(t, text, "Commit: https://github.com/ardikabs/manifests/commit/2a8e4d7")
+		require.Contains(t, text, "- myapp on prod-1: Synced/Healthy")
+	})
+
+	t.Run("teams is filtered by event", func(t *testing.T) {
+		require.Empty(t, teams.requests)
+
+		require.NoError(t, n.Notify(context.Background(), newEvent(notifier.EventFailed)))
+		require.Len(t, teams.requests, 1)
+		require.Equal(t, "MessageCard", teams.requests[0].body["@type"])
+		require.Equal(t, "D00000", teams.requests[0].body["themeColor"])
+	})
+
+	t.Run("webhook is signed", func(t *testing.T) {
+		req := webhook.requests[0]
+		require.Equal(t, "succeeded", req.header.Get(notifier.EventHeader))
+		require.Equal(t, notifier.Sign("s3cr3t", req.raw), req.header.Get(notifier.SignatureHeader))
+		require.Equal(t, "myapp is succeeded", req.body["message"])
+		require.Equal(t, "b6d7153", req.body["requestID"])
+	})
+}
+
+func TestNotifier_Filters(t *testing.T) {
+	var sink capture
+
+	n, err := notifier.New(notifier.SinkConfig{
+		Type:         notifier.SinkSlack,
+		URL:          sink.server(t, http.StatusOK).URL,
+		Environments: []string{"staging"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, n.Notify(context.Background(), newEvent(notifier.EventStarted)))
+	require.Empty(t, sink.requests)
+}
+
+func TestNotifier_Failure(t *testing.T) {
+	var sink capture
+
+	n, err := notifier.New(notifier.SinkConfig{Name: "broken", Type: notifier.SinkSlack, URL: sink.server(t, http.StatusInternalServerError).URL})
+	require.NoError(t, err)
+
+	err = n.Notify(context.Background(), newEvent(notifier.EventStarted))
+	require.ErrorIs(t, err, notifier.ErrNotificationFailed)
+	require.ErrorContains(t, err, "notifier: broken")
+
+	t.Run("nil notifier does nothing", func(t *testing.T) {
+		var n *notifier.Notifier
+		require.NoError(t, n.Notify(context.Background(), newEvent(notifier.EventStarted)))
+	})
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	t.Setenv("DPL_TEST_WEBHOOK_URL", "")
+
+	for name, cfg := range map[string]notifier.SinkConfig{
+		"unknown type":     {Type: "pager", URL: "http://localhost"},
+		"unknown event":    {Type: notifier.SinkSlack, URL: "http://localhost", Events: []notifier.EventType{"deleted"}},
+		"empty url":        {Type: notifier.SinkSlack, URL: "${DPL_TEST_WEBHOOK_URL}"},
+		"invalid template": {Type: notifier.SinkSlack, URL: "http://localhost", Template: "{{ .Release"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := notifier.New(cfg)
+			require.ErrorIs(t, err, notifier.ErrNotifierInvalidConfig)
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package notifier
+
+import "github.com/go-logr/logr"
+
+type Options struct {
+	Logger logr.Logger
+}
+
+type Option func(*Options)
+
+func newOptions(opts ...Option) *Options {
+	o := &Options{
+		Logger: logr.Discard(),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+func WithLogger(logger logr.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
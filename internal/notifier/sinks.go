@@ -0,0 +1,186 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+const (
+	// SignatureHeader holds the HMAC-SHA256 signature of the generic webhook payload,
+	// in the format of 'sha256=<hex digest>'.
+	SignatureHeader = "X-Dpl-Signature-256"
+	EventHeader     = "X-Dpl-Event"
+)
+
+// DefaultTemplate is the message used when the notifier has no template
+const DefaultTemplate = `Deployment {{ .Type }}{{ with .Release }} {{ . }}{{ end }}{{ with .Environment }} on {{ . }}{{ end }} ({{ .RequestID }})
+{{- with .Image }}
+Image: {{ . }}{{ end }}
+{{- if .CommitURL }}
+Commit: {{ .CommitURL }}{{ else if .CommitSHA }}
+Commit: {{ .CommitSHA }}{{ end }}
+{{- range .Releases }}
+- {{ .Name }} on {{ .Cluster }}{{ if .SyncStatus }}: {{ .SyncStatus }}/{{ .HealthStatus }}{{ end }}{{ end }}
+{{- with .Error }}
+Error: {{ . }}{{ end }}`
+
+var themeColors = map[EventType]string{
+	EventStarted:   "0076D7",
+	EventSucceeded: "2EB886",
+	EventFailed:    "D00000",
+}
+
+// slackSink posts the message to the Slack incoming webhook
+type slackSink struct {
+	url    string
+	tmpl   *template.Template
+	client *http.Client
+}
+
+func (s *slackSink) Send(ctx context.Context, ev Event) error {
+	msg, err := render(s.tmpl, ev)
+	if err != nil {
+		return err
+	}
+
+	return post(ctx, s.client, s.url, map[string]any{
+		"text": msg,
+		"attachments": []map[string]any{
+			{"color": "#" + themeColors[ev.Type], "fallback": msg},
+		},
+	}, nil)
+}
+
+// teamsSink posts the message as a MessageCard to the Microsoft Teams incoming webhook
+type teamsSink struct {
+	url    string
+	tmpl   *template.Template
+	client *http.Client
+}
+
+func (s *teamsSink) Send(ctx context.Context, ev Event) error {
+	msg, err := render(s.tmpl, ev)
+	if err != nil {
+		return err
+	}
+
+	summary, _, _ := strings.Cut(msg, "\n")
+
+	return post(ctx, s.client, s.url, map[string]any{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    summary,
+		"themeColor": themeColors[ev.Type],
+		// Teams collapses single line breaks, hence every line is turned into a paragraph
+		"text": strings.ReplaceAll(msg, "\n", "\n\n"),
+	}, nil)
+}
+
+// webhookSink posts the event as JSON along with the rendered message,
+// the payload is signed when the secret is set.
+type webhookSink struct {
+	url    string
+	secret string
+	tmpl   *template.Template
+	client *http.Client
+}
+
+type webhookPayload struct {
+	Event
+	Message string `json:"message"`
+}
+
+func (s *webhookSink) Send(ctx context.Context, ev Event) error {
+	msg, err := render(s.tmpl, ev)
+	if err != nil {
+		return err
+	}
+
+	return post(ctx, s.client, s.url, webhookPayload{Event: ev, Message: msg}, func(req *http.Request, body []byte) {
+		req.Header.Set(EventHeader, string(ev.Type))
+		if s.secret != "" {
+			req.Header.Set(SignatureHeader, Sign(s.secret, body))
+		}
+	})
+}
+
+// Sign returns the HMAC-SHA256 signature of the payload, receivers verify it the same way.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func render(tmpl *template.Template, ev Event) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, ev); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+func post(ctx context.Context, client *http.Client, url string, payload any, decorate func(*http.Request, []byte)) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if decorate != nil {
+		decorate(req, body)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	return nil
+}
+
+// CommitURL returns the web URL of the commit for the common git hosting, such as GitHub and GitLab,
+// it returns empty string when the repository URL is unrecognized.
+func CommitURL(gitURL, sha string) string {
+	if gitURL == "" || sha == "" {
+		return ""
+	}
+
+	repo := strings.TrimSuffix(gitURL, ".git")
+
+	switch {
+	case strings.HasPrefix(repo, "https://"), strings.HasPrefix(repo, "http://"):
+	case strings.HasPrefix(repo, "git@"):
+		host, path, ok := strings.Cut(strings.TrimPrefix(repo, "git@"), ":")
+		if !ok {
+			return ""
+		}
+		repo = "https://" + host + "/" + path
+	case strings.HasPrefix(repo, "ssh://"):
+		repo = "https://" + strings.TrimPrefix(strings.TrimPrefix(repo, "ssh://"), "git@")
+	default:
+		return ""
+	}
+
+	return repo + "/commit/" + sha
+}
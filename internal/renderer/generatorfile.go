@@ -0,0 +1,499 @@
+package renderer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/ardikabs/dpl/internal/errs"
+	"github.com/ardikabs/dpl/internal/tools/ioutils"
+	"github.com/ardikabs/dpl/internal/types"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/valyala/fasttemplate"
+	goyaml "gopkg.in/yaml.v3"
+)
+
+var _ Interface = &GeneratorFile{}
+
+var (
+	ErrGeneratorFileInvalidParams = errors.New("invalid params type, expecting *GeneratorFileParams")
+	ErrGeneratorFileInvalid       = errors.New("generator file must be a single object")
+	ErrGeneratorFileNotFound      = errors.New("generator file not found")
+	ErrGeneratorImageNotFound     = errors.New("image key not found in generator file")
+)
+
+// GeneratorFileParams edits the image of the git files generator file,
+// the image key is the dot-separated key of either a mapping of name, tag, and digest, or an image reference string.
+type GeneratorFileParams struct {
+	// File is the generator file relative to the working directory
+	File string
+
+	ImageKey    string
+	ImageName   string
+	ImageTag    string
+	ImageDigest string
+}
+
+// GeneratorFile renders the image to the file consumed by the git files generator of an ApplicationSet,
+// instead of the kustomization file of the generated Application.
+type GeneratorFile struct{}
+
+func (g *GeneratorFile) Render(workdir string, releaseName string, params interface{}, opts ...RenderOption) error {
+	generatorParams, ok := params.(*GeneratorFileParams)
+	if !ok {
+		return ErrGeneratorFileInvalidParams
+	}
+
+	o := &RenderOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	log := o.Logger.WithValues(
+		"renderer", "generator-file",
+		"release", releaseName,
+		"file", generatorParams.File,
+		"imageKey", generatorParams.ImageKey,
+	)
+
+	if len(o.ExternalAnnotations) > 0 || o.Namespace != "" {
+		log.Info("generator file has no annotations or namespace to be rendered, hence they are left out")
+	}
+
+	filename := filepath.Join(workdir, generatorParams.File)
+	content, err := ioutils.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	// the file is only rewritten once the image is rendered, so a bad file or image key leaves it as it is
+	doc, err := parseGeneratorFile(content)
+	if err != nil {
+		return errs.Wrapf(err, "file: %s", generatorParams.File)
+	}
+
+	node := lookupNode(doc, generatorParams.ImageKey, true)
+	if node == nil {
+		return errs.Wrapf(ErrGeneratorImageNotFound, "key: %s is within a non-mapping value", generatorParams.ImageKey)
+	}
+
+	image := types.ImageDefinition{
+		Name:   generatorParams.ImageName,
+		Tag:    generatorParams.ImageTag,
+		Digest: generatorParams.ImageDigest,
+	}
+
+	setImageNode(node, image)
+
+	var out bytes.Buffer
+	if filepath.Ext(filename) == ".json" {
+		if err := encodeJSON(&out, doc); err != nil {
+			return err
+		}
+	} else {
+		enc := goyaml.NewEncoder(&out)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+	}
+
+	// With the custom writer in place, the original file is left as it is
+	if o.CustomWriter != nil {
+		if _, err := o.CustomWriter.Write(out.Bytes()); err != nil {
+			return err
+		}
+	} else if err := os.WriteFile(filename, out.Bytes(), 0666); err != nil {
+		return err
+	}
+
+	log.Info("rendering generator file is done", "image", image.String())
+	return nil
+}
+
+// Inspect reads the image currently rendered in the generator file for the image key
+func (g *GeneratorFile) Inspect(workdir string, releaseName string, params interface{}, opts ...RenderOption) (types.ImageDefinition, error) {
+	generatorParams, ok := params.(*GeneratorFileParams)
+	if !ok {
+		return types.ImageDefinition{}, ErrGeneratorFileInvalidParams
+	}
+
+	content, err := ioutils.ReadFile(filepath.Join(workdir, generatorParams.File))
+	if err != nil {
+		return types.ImageDefinition{}, err
+	}
+
+	doc, err := parseGeneratorFile(content)
+	if err != nil {
+		return types.ImageDefinition{}, errs.Wrapf(err, "file: %s", generatorParams.File)
+	}
+
+	node := lookupNode(doc, generatorParams.ImageKey, false)
+	if node == nil {
+		return types.ImageDefinition{}, errs.Wrapf(ErrGeneratorImageNotFound, "key: %s", generatorParams.ImageKey)
+	}
+
+	if node.Kind == goyaml.ScalarNode {
+		return parseImageReference(node.Value), nil
+	}
+
+	image := types.ImageDefinition{
+		Name:   mappingValue(node, "name"),
+		Tag:    mappingValue(node, "tag"),
+		Digest: mappingValue(node, "digest"),
+	}
+
+	if image.Name == "" {
+		return types.ImageDefinition{}, errs.Wrapf(ErrGeneratorImageNotFound, "key: %s has no name", generatorParams.ImageKey)
+	}
+
+	return image, nil
+}
+
+// ResolveGeneratorFile returns the generator file generating the Application, relative to the root of the repository.
+// Every generator file is rendered to the Application name through the name template of the generator, as Argo CD does,
+// so the file whose rendered name is the Application name is the one generating it.
+func ResolveGeneratorFile(root string, generator *types.GitFilesGenerator, appName string) (string, error) {
+	for _, pattern := range generator.Files {
+		files, err := doublestar.Glob(os.DirFS(root), pattern)
+		if err != nil {
+			return "", err
+		}
+
+		for _, file := range files {
+			content, err := os.ReadFile(filepath.Join(root, file))
+			if err != nil {
+				return "", err
+			}
+
+			var values map[string]any
+			// the generator file of many objects generates many Applications, which is left out
+			if err := goyaml.Unmarshal(content, &values); err != nil {
+				continue
+			}
+
+			name, err := renderName(generator, file, values)
+			if err != nil {
+				return "", err
+			}
+
+			if name == appName {
+				return file, nil
+			}
+		}
+	}
+
+	return "", errs.Wrapf(ErrGeneratorFileNotFound, "application %s is not generated by any of %s", appName, strings.Join(generator.Files, ", "))
+}
+
+// renderName renders the Application name template with the parameters of the generator file, as the git files generator does
+func renderName(generator *types.GitFilesGenerator, file string, values map[string]any) (string, error) {
+	dir := path.Dir(file)
+	params := make(map[string]any)
+
+	if generator.GoTemplate {
+		for k, v := range values {
+			params[k] = v
+		}
+
+		pathParams := map[string]any{
+			"path":               dir,
+			"basename":           path.Base(dir),
+			"filename":           path.Base(file),
+			"basenameNormalized": sanitizeName(path.Base(dir)),
+			"filenameNormalized": sanitizeName(path.Base(file)),
+			"segments":           strings.Split(dir, "/"),
+		}
+
+		if generator.PathParamPrefix != "" {
+			params[generator.PathParamPrefix] = map[string]any{"path": pathParams}
+		} else {
+			params["path"] = pathParams
+		}
+
+		return renderGoTemplate(generator, params)
+	}
+
+	flattenValues(params, "", values)
+
+	pathParam := "path"
+	if generator.PathParamPrefix != "" {
+		pathParam = generator.PathParamPrefix + ".path"
+	}
+
+	params[pathParam] = dir
+	params[pathParam+".basename"] = path.Base(dir)
+	params[pathParam+".filename"] = path.Base(file)
+	params[pathParam+".basenameNormalized"] = sanitizeName(path.Base(dir))
+	params[pathParam+".filenameNormalized"] = sanitizeName(path.Base(file))
+	for idx, segment := range strings.Split(dir, "/") {
+		if segment != "" {
+			params[pathParam+"["+strconv.Itoa(idx)+"]"] = segment
+		}
+	}
+
+	tmpl, err := fasttemplate.NewTemplate(generator.NameTemplate, "{{", "}}")
+	if err != nil {
+		return "", fmt.Errorf("invalid application name template: %w", err)
+	}
+
+	// the unknown parameter is left as it is
+	return tmpl.ExecuteFuncString(func(w io.Writer, tag string) (int, error) {
+		if value, ok := params[strings.TrimSpace(tag)].(string); ok {
+			return w.Write([]byte(value))
+		}
+
+		return w.Write([]byte("{{" + tag + "}}"))
+	}), nil
+}
+
+// templateFuncs are the sprig functions along with the functions Argo CD adds for the ApplicationSet templates
+var templateFuncs = func() template.FuncMap {
+	funcs := sprig.TxtFuncMap()
+	delete(funcs, "env")
+	delete(funcs, "expandenv")
+	delete(funcs, "getHostByName")
+	funcs["normalize"] = sanitizeName
+
+	return funcs
+}()
+
+func renderGoTemplate(generator *types.GitFilesGenerator, params map[string]any) (string, error) {
+	tmpl, err := template.New("").Funcs(templateFuncs).Parse(generator.NameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse application name template: %w", err)
+	}
+
+	for _, option := range generator.GoTemplateOptions {
+		tmpl = tmpl.Option(option)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, params); err != nil {
+		return "", fmt.Errorf("failed to render application name template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// flattenValues flattens the nested values into the dot-separated keys
+func flattenValues(params map[string]any, prefix string, value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, nested := range v {
+			flattenValues(params, joinKey(prefix, key), nested)
+		}
+	case []any:
+		for idx, nested := range v {
+			flattenValues(params, joinKey(prefix, strconv.Itoa(idx)), nested)
+		}
+	default:
+		params[prefix] = fmt.Sprintf("%v", v)
+	}
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "." + key
+}
+
+var invalidDNSNameChars = regexp.MustCompile("[^-a-z0-9.]")
+
+// sanitizeName normalizes the name to be a valid DNS subdomain name, as the 'Normalized' path parameters are
+func sanitizeName(name string) string {
+	name = invalidDNSNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	if len(name) > 253 {
+		name = name[:253]
+	}
+
+	return strings.Trim(name, "-.")
+}
+
+func parseGeneratorFile(content []byte) (*goyaml.Node, error) {
+	doc := new(goyaml.Node)
+	if err := goyaml.Unmarshal(content, doc); err != nil {
+		return nil, err
+	}
+
+	if doc.Kind != goyaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != goyaml.MappingNode {
+		return nil, ErrGeneratorFileInvalid
+	}
+
+	return doc, nil
+}
+
+// lookupNode returns the value node of the dot-separated key, the missing mappings are created when it is told to
+func lookupNode(doc *goyaml.Node, key string, create bool) *goyaml.Node {
+	node := doc.Content[0]
+	for _, name := range strings.Split(key, ".") {
+		if node.Kind != goyaml.MappingNode {
+			return nil
+		}
+
+		next := mappingNode(node, name)
+		if next == nil {
+			if !create {
+				return nil
+			}
+
+			next = &goyaml.Node{Kind: goyaml.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content, stringNode(name), next)
+		}
+
+		node = next
+	}
+
+	return node
+}
+
+// setImageNode sets the image to the node, the image reference string is kept as a string
+func setImageNode(node *goyaml.Node, image types.ImageDefinition) {
+	if node.Kind == goyaml.ScalarNode {
+		node.Tag, node.Value = "!!str", image.String()
+		return
+	}
+
+	setMappingValue(node, "name", image.Name)
+	setMappingValue(node, "tag", image.Tag)
+
+	if image.Digest != "" {
+		setMappingValue(node, "digest", image.Digest)
+		return
+	}
+
+	// the previous digest would pin the previous image
+	for idx := 0; idx < len(node.Content); idx += 2 {
+		if node.Content[idx].Value == "digest" {
+			node.Content = append(node.Content[:idx], node.Content[idx+2:]...)
+			break
+		}
+	}
+}
+
+func mappingNode(node *goyaml.Node, key string) *goyaml.Node {
+	for idx := 0; idx+1 < len(node.Content); idx += 2 {
+		if node.Content[idx].Value == key {
+			return node.Content[idx+1]
+		}
+	}
+
+	return nil
+}
+
+func mappingValue(node *goyaml.Node, key string) string {
+	if value := mappingNode(node, key); value != nil {
+		return value.Value
+	}
+
+	return ""
+}
+
+func setMappingValue(node *goyaml.Node, key, value string) {
+	if existing := mappingNode(node, key); existing != nil {
+		existing.Kind, existing.Tag, existing.Value = goyaml.ScalarNode, "!!str", value
+		return
+	}
+
+	node.Content = append(node.Content, stringNode(key), stringNode(value))
+}
+
+func stringNode(value string) *goyaml.Node {
+	return &goyaml.Node{Kind: goyaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// encodeJSON encodes the node as an indented JSON document, the order of the keys is kept
+func encodeJSON(out *bytes.Buffer, doc *goyaml.Node) error {
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, doc); err != nil {
+		return err
+	}
+
+	if err := json.Indent(out, buf.Bytes(), "", "  "); err != nil {
+		return err
+	}
+
+	out.WriteByte('\n')
+	return nil
+}
+
+func writeJSON(buf *bytes.Buffer, node *goyaml.Node) error {
+	switch node.Kind {
+	case goyaml.DocumentNode:
+		return writeJSON(buf, node.Content[0])
+	case goyaml.AliasNode:
+		return writeJSON(buf, node.Alias)
+	case goyaml.MappingNode:
+		buf.WriteByte('{')
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			if idx > 0 {
+				buf.WriteByte(',')
+			}
+
+			key, err := json.Marshal(node.Content[idx].Value)
+			if err != nil {
+				return err
+			}
+
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeJSON(buf, node.Content[idx+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case goyaml.SequenceNode:
+		buf.WriteByte('[')
+		for idx, item := range node.Content {
+			if idx > 0 {
+				buf.WriteByte(',')
+			}
+
+			if err := writeJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		switch node.Tag {
+		case "!!int", "!!float", "!!bool":
+			buf.WriteString(node.Value)
+		case "!!null":
+			buf.WriteString("null")
+		default:
+			value, err := json.Marshal(node.Value)
+			if err != nil {
+				return err
+			}
+
+			buf.Write(value)
+		}
+	}
+
+	return nil
+}
+
+// parseImageReference parses the image reference in 'NAME[:TAG][@DIGEST]' format
+func parseImageReference(ref string) types.ImageDefinition {
+	var image types.ImageDefinition
+	ref, image.Digest, _ = strings.Cut(ref, "@")
+
+	image.Name = ref
+	if idx := strings.LastIndex(ref, ":"); idx > strings.LastIndex(ref, "/") {
+		image.Name, image.Tag = ref[:idx], ref[idx+1:]
+	}
+
+	return image
+}
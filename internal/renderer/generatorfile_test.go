@@ -0,0 +1,183 @@
+package renderer_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ardikabs/dpl/internal/renderer"
+	"github.com/ardikabs/dpl/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratorFile_Render(t *testing.T) {
+	inputFiles, err := filepath.Glob(filepath.Join("testdata/generatorfile/render", "*.in.*"))
+	require.NoError(t, err)
+
+	for _, inputFile := range inputFiles {
+		name := strings.Split(filepath.Base(inputFile), ".")[0]
+		t.Run(name, func(t *testing.T) {
+			generatorFile := &renderer.GeneratorFile{}
+
+			bytes := &bytes.Buffer{}
+			err := generatorFile.Render(filepath.Dir(inputFile), name, &renderer.GeneratorFileParams{
+				File:      filepath.Base(inputFile),
+				ImageKey:  "image",
+				ImageName: "ghcr.io/ardikabs/etc/mockserver",
+				ImageTag:  "v1.0.0",
+			}, renderer.WithCustomWriter(bytes))
+			require.NoError(t, err)
+
+			outputFile := strings.Replace(inputFile, ".in.", ".out.", 1)
+
+			if *overrideTestData {
+				require.NoError(t, os.WriteFile(outputFile, bytes.Bytes(), 0644))
+			}
+
+			out, err := os.ReadFile(outputFile)
+			require.NoError(t, err)
+
+			require.Equal(t, string(out), bytes.String())
+		})
+	}
+}
+
+func TestGeneratorFile_Render_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		content  string
+		imageKey string
+		wantErr  error
+	}{
+		"image key within a scalar": {
+			content:  "image: ghcr.io/ardikabs/etc/mockserver:v0.9.0\n",
+			imageKey: "image.ref",
+			wantErr:  renderer.ErrGeneratorImageNotFound,
+		},
+		"invalid yaml": {
+			content:  "image: [ghcr.io/ardikabs/etc/mockserver\n",
+			imageKey: "image",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			workdir := t.TempDir()
+			filename := filepath.Join(workdir, "config.yaml")
+			require.NoError(t, os.WriteFile(filename, []byte(tt.content), 0644))
+
+			generatorFile := &renderer.GeneratorFile{}
+			err := generatorFile.Render(workdir, "myapp", &renderer.GeneratorFileParams{
+				File:      "config.yaml",
+				ImageKey:  tt.imageKey,
+				ImageName: "ghcr.io/ardikabs/etc/mockserver",
+				ImageTag:  "v1.0.0",
+			})
+			require.Error(t, err)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			}
+
+			// the generator file is left as it is
+			content, err := os.ReadFile(filename)
+			require.NoError(t, err)
+			require.Equal(t, tt.content, string(content))
+		})
+	}
+}
+
+func TestGeneratorFile_Inspect(t *testing.T) {
+	generatorFile := &renderer.GeneratorFile{}
+	workdir := "testdata/generatorfile"
+
+	t.Run("image mapping", func(t *testing.T) {
+		image, err := generatorFile.Inspect(workdir, "myapp", &renderer.GeneratorFileParams{
+			File:     "clusters/prod-2/myapp.json",
+			ImageKey: "image",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "ghcr.io/ardikabs/etc/mockserver:v0.8.0@sha256:7d2c1e9f", image.String())
+	})
+
+	t.Run("image reference", func(t *testing.T) {
+		image, err := generatorFile.Inspect(workdir, "myapp", &renderer.GeneratorFileParams{
+			File:     "render/image-reference.in.yaml",
+			ImageKey: "image",
+		})
+		require.NoError(t, err)
+		require.Equal(t, types.ImageDefinition{Name: "ghcr.io/ardikabs/etc/mockserver", Tag: "v0.9.0"}, image)
+	})
+
+	t.Run("image key not exists", func(t *testing.T) {
+		_, err := generatorFile.Inspect(workdir, "myapp", &renderer.GeneratorFileParams{
+			File:     "clusters/prod-1/myapp.json",
+			ImageKey: "values.image",
+		})
+		require.ErrorIs(t, err, renderer.ErrGeneratorImageNotFound)
+	})
+}
+
+func TestResolveGeneratorFile(t *testing.T) {
+	root := "testdata/generatorfile"
+
+	tests := []struct {
+		name      string
+		generator *types.GitFilesGenerator
+		appName   string
+		want      string
+		wantErr   error
+	}{
+		{
+			name: "flattened parameters",
+			generator: &types.GitFilesGenerator{
+				Files:        []string{"clusters/*/myapp.json"},
+				NameTemplate: "myapp-{{cluster.name}}",
+			},
+			appName: "myapp-prod-1",
+			want:    "clusters/prod-1/myapp.json",
+		},
+		{
+			name: "path parameters with prefix",
+			generator: &types.GitFilesGenerator{
+				Files:           []string{"clusters/**/*.json"},
+				NameTemplate:    "{{ path.basename }}-{{generator.path.basenameNormalized}}-{{generator.path[1]}}",
+				PathParamPrefix: "generator",
+			},
+			appName: "{{ path.basename }}-prod-2-prod-2",
+			want:    "clusters/prod-2/myapp.json",
+		},
+		{
+			name: "go template",
+			generator: &types.GitFilesGenerator{
+				Files:        []string{"clusters/*/myapp.json"},
+				NameTemplate: "myapp-{{ .cluster.name | lower }}-{{ index .path.segments 1 }}",
+				GoTemplate:   true,
+			},
+			appName: "myapp-prod_2-prod-2",
+			want:    "clusters/prod-2/myapp.json",
+		},
+		{
+			name: "application is not generated",
+			generator: &types.GitFilesGenerator{
+				Files:        []string{"clusters/*/myapp.json"},
+				NameTemplate: "myapp-{{cluster.name}}",
+			},
+			appName: "myapp-prod-3",
+			wantErr: renderer.ErrGeneratorFileNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := renderer.ResolveGeneratorFile(root, tt.generator, tt.appName)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, file)
+		})
+	}
+}
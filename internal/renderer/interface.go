@@ -1,5 +1,8 @@
 package renderer
 
+import "github.com/ardikabs/dpl/internal/types"
+
 type Interface interface {
 	Render(workdir string, releaseName string, params interface{}, opts ...RenderOption) error
+	Inspect(workdir string, releaseName string, params interface{}, opts ...RenderOption) (types.ImageDefinition, error)
 }
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	"github.com/ardikabs/dpl/internal/tools/ioutils"
+	"github.com/ardikabs/dpl/internal/types"
 	goyaml "gopkg.in/yaml.v3"
 	kusttypes "sigs.k8s.io/kustomize/api/types"
 )
@@ -15,6 +16,7 @@ var _ Interface = &Kustomize{}
 var (
 	ErrKustomizeInvalidParams        = errors.New("invalid params type, expecting *KustomizeParams")
 	ErrKustomizeFailedAutoGenComment = errors.New("failed to inject auto-generated comment")
+	ErrKustomizeImageNotFound        = errors.New("image reference not found in kustomization file")
 )
 
 type KustomizeParams struct {
@@ -23,6 +25,7 @@ type KustomizeParams struct {
 	ImageReferenceName string
 	ImageName          string
 	ImageTag           string
+	ImageDigest        string
 }
 
 type Kustomize struct{}
@@ -75,6 +78,7 @@ func (k *Kustomize) Render(workdir string, releaseName string, params interface{
 		if img.Name == kustomizeParams.ImageReferenceName {
 			kust.Images[idx].NewName = kustomizeParams.ImageName
 			kust.Images[idx].NewTag = kustomizeParams.ImageTag
+			kust.Images[idx].Digest = kustomizeParams.ImageDigest
 
 			foundImageSelector = true
 			log.Info("found image reference")
@@ -89,11 +93,17 @@ func (k *Kustomize) Render(workdir string, releaseName string, params interface{
 			Name:    kustomizeParams.ImageReferenceName,
 			NewName: kustomizeParams.ImageName,
 			NewTag:  kustomizeParams.ImageTag,
+			Digest:  kustomizeParams.ImageDigest,
 		})
 	}
 
 	kust.CommonAnnotations = mergeMaps(kust.CommonAnnotations, o.ExternalAnnotations)
 
+	if o.Namespace != "" {
+		log.Info("overriding namespace", "namespace", o.Namespace)
+		kust.Namespace = o.Namespace
+	}
+
 	node := new(goyaml.Node)
 	if err := node.Encode(kust); err != nil {
 		return err
@@ -125,6 +135,61 @@ func (k *Kustomize) Render(workdir string, releaseName string, params interface{
 	return nil
 }
 
+// Inspect reads the image currently rendered in the kustomization file for the image reference name
+func (k *Kustomize) Inspect(workdir string, releaseName string, params interface{}, opts ...RenderOption) (types.ImageDefinition, error) {
+	kustomizeParams, ok := params.(*KustomizeParams)
+	if !ok {
+		return types.ImageDefinition{}, ErrKustomizeInvalidParams
+	}
+
+	if kustomizeParams.KustomizationRef == "" {
+		kustomizeParams.KustomizationRef = "kustomization.yaml"
+	}
+
+	o := &RenderOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	log := o.Logger.WithValues(
+		"renderer", "kustomize",
+		"release", releaseName,
+		"ref", kustomizeParams.ImageReferenceName,
+		"kustomizeRef", kustomizeParams.KustomizationRef,
+	)
+
+	content, err := ioutils.ReadFile(filepath.Join(workdir, kustomizeParams.KustomizationRef))
+	if err != nil {
+		return types.ImageDefinition{}, err
+	}
+
+	var kust kusttypes.Kustomization
+	if err := kust.Unmarshal(content); err != nil {
+		return types.ImageDefinition{}, err
+	}
+
+	for _, img := range kust.Images {
+		if img.Name != kustomizeParams.ImageReferenceName {
+			continue
+		}
+
+		image := types.ImageDefinition{
+			Name:   img.NewName,
+			Tag:    img.NewTag,
+			Digest: img.Digest,
+		}
+
+		if image.Name == "" {
+			image.Name = img.Name
+		}
+
+		log.V(1).Info("found image reference", "image", image.String())
+		return image, nil
+	}
+
+	return types.ImageDefinition{}, ErrKustomizeImageNotFound
+}
+
 func mergeMaps(original, adds map[string]string) map[string]string {
 	if original == nil {
 		return adds
@@ -51,3 +51,25 @@ func TestKustomize_Render(t *testing.T) {
 		})
 	}
 }
+
+func TestKustomize_Inspect(t *testing.T) {
+	kustomize := &renderer.Kustomize{}
+	workdir := "testdata/kustomize/multi-image-refs"
+
+	t.Run("image reference exists", func(t *testing.T) {
+		image, err := kustomize.Inspect(workdir, "multi-image-refs", &renderer.KustomizeParams{
+			KustomizationRef:   "kustomization.in.yaml",
+			ImageReferenceName: "sidecar",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "ghcr.io/ardikabs/etc/sidecar:latest", image.String())
+	})
+
+	t.Run("image reference not exists", func(t *testing.T) {
+		_, err := kustomize.Inspect(workdir, "multi-image-refs", &renderer.KustomizeParams{
+			KustomizationRef:   "kustomization.in.yaml",
+			ImageReferenceName: "unknown",
+		})
+		require.ErrorIs(t, err, renderer.ErrKustomizeImageNotFound)
+	})
+}
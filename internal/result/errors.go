@@ -0,0 +1,81 @@
+package result
+
+import (
+	"errors"
+)
+
+// Category classifies the failure of a deployment, each category has a distinct exit code
+type Category string
+
+const (
+	CategoryUnknown     Category = "unknown"
+	CategoryValidation  Category = "validation"
+	CategoryGit         Category = "git"
+	CategoryRender      Category = "render"
+	CategorySync        Category = "sync"
+	CategorySyncTimeout Category = "sync-timeout"
+	CategoryDegraded    Category = "degraded"
+	CategorySmokeCheck  Category = "smoke-check"
+	CategoryHook        Category = "hook"
+	CategoryCancelled   Category = "cancelled"
+)
+
+var exitCodes = map[Category]int{
+	CategoryUnknown:     1,
+	CategoryValidation:  2,
+	CategoryGit:         3,
+	CategoryRender:      4,
+	CategorySync:        5,
+	CategorySyncTimeout: 6,
+	CategoryDegraded:    7,
+	CategorySmokeCheck:  8,
+	CategoryHook:        9,
+	CategoryCancelled:   10,
+}
+
+// Error is an error classified with a category
+type Error struct {
+	Category Category
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithCategory classifies the error with the category, unless the error is nil or already classified
+func WithCategory(category Category, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+
+	return &Error{Category: category, Err: err}
+}
+
+// CategoryOf returns the category of the error, it returns CategoryUnknown when the error is not classified
+func CategoryOf(err error) Category {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Category
+	}
+
+	return CategoryUnknown
+}
+
+// ExitCode returns the process exit code for the error, zero when the error is nil
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	return exitCodes[CategoryOf(err)]
+}
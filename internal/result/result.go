@@ -0,0 +1,297 @@
+package result
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/types"
+)
+
+// SchemaVersion is the version of the result document schema,
+// it must be bumped on any backward incompatible change of the document.
+const SchemaVersion = "v1"
+
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+
+	// StatusPartial is the deployment which is accepted while some of its releases failed to sync
+	StatusPartial = "partial"
+)
+
+// Result is the machine-readable result of a deployment
+type Result struct {
+	SchemaVersion   string          `json:"schemaVersion"`
+	RequestID       string          `json:"requestID"`
+	Status          string          `json:"status"`
+	Error           *ErrorResult    `json:"error,omitempty"`
+	CommitSHA       string          `json:"commitSHA,omitempty"`
+	GitURL          string          `json:"gitURL,omitempty"`
+	GitRevision     string          `json:"gitRevision,omitempty"`
+	StartedAt       time.Time       `json:"startedAt"`
+	FinishedAt      time.Time       `json:"finishedAt"`
+	DurationSeconds float64         `json:"durationSeconds"`
+	RevisionTime    *time.Time      `json:"revisionTime,omitempty"`
+	Stages          []StageResult   `json:"stages"`
+	Releases        []ReleaseResult `json:"releases"`
+
+	mu sync.Mutex
+}
+
+type ErrorResult struct {
+	Category Category `json:"category"`
+	Message  string   `json:"message"`
+}
+
+type StageResult struct {
+	Name            string  `json:"name"`
+	DurationSeconds float64 `json:"durationSeconds"`
+}
+
+type ReleaseResult struct {
+	Application  string `json:"application"`
+	Release      string `json:"release"`
+	Environment  string `json:"environment"`
+	Cluster      string `json:"cluster"`
+	Instance     string `json:"instance,omitempty"`
+	Image        string `json:"image"`
+	SyncStatus   string `json:"syncStatus"`
+	HealthStatus string `json:"healthStatus"`
+	Result       string `json:"result,omitempty"`
+	Message      string `json:"message,omitempty"`
+
+	Diagnostics []DiagnosticResult `json:"diagnostics,omitempty"`
+}
+
+// DiagnosticResult describes an unhealthy resource of the failed release
+type DiagnosticResult struct {
+	Kind       string   `json:"kind"`
+	Namespace  string   `json:"namespace,omitempty"`
+	Name       string   `json:"name"`
+	Health     string   `json:"health,omitempty"`
+	Message    string   `json:"message,omitempty"`
+	Containers []string `json:"containers,omitempty"`
+	Events     []string `json:"events,omitempty"`
+}
+
+func New(reqID string) *Result {
+	return &Result{
+		SchemaVersion: SchemaVersion,
+		RequestID:     reqID,
+		StartedAt:     time.Now().UTC(),
+		Stages:        []StageResult{},
+		Releases:      []ReleaseResult{},
+	}
+}
+
+// Track starts measuring the duration of the stage, the returned function must be called when the stage ends
+func (r *Result) Track(stage string) func() {
+	start := time.Now()
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.Stages = append(r.Stages, StageResult{
+			Name:            stage,
+			DurationSeconds: time.Since(start).Seconds(),
+		})
+	}
+}
+
+// SetReleases records the final state of the releases
+func (r *Result) SetReleases(releases types.ListReleases) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Releases = make([]ReleaseResult, 0, len(releases))
+	for _, rel := range releases {
+		r.Releases = append(r.Releases, ReleaseResult{
+			Application:  rel.ID,
+			Release:      rel.Name,
+			Environment:  rel.Environment,
+			Cluster:      rel.Cluster,
+			Instance:     rel.Instance,
+			Image:        rel.Image.String(),
+			SyncStatus:   rel.Status.Sync,
+			HealthStatus: rel.Status.Health,
+			Result:       rel.Status.Result,
+			Message:      rel.Status.Message,
+			Diagnostics:  newDiagnosticResults(rel.Status.Diagnostics),
+		})
+	}
+}
+
+func newDiagnosticResults(diagnostics []types.ResourceDiagnostic) []DiagnosticResult {
+	if len(diagnostics) == 0 {
+		return nil
+	}
+
+	results := make([]DiagnosticResult, 0, len(diagnostics))
+	for _, diag := range diagnostics {
+		results = append(results, DiagnosticResult(diag))
+	}
+
+	return results
+}
+
+// Finish marks the result as finished, it is failed when the error is not nil
+func (r *Result) Finish(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.FinishedAt = time.Now().UTC()
+	r.DurationSeconds = r.FinishedAt.Sub(r.StartedAt).Seconds()
+	r.Status = StatusSucceeded
+
+	for _, rel := range r.Releases {
+		if rel.Result != "" && !types.IsSucceededResult(rel.Result) {
+			r.Status = StatusPartial
+			break
+		}
+	}
+
+	if err != nil {
+		r.Status = StatusFailed
+		r.Error = &ErrorResult{
+			Category: CategoryOf(err),
+			Message:  err.Error(),
+		}
+	}
+}
+
+func (r *Result) Write(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r *Result) WriteFile(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return r.Write(f)
+}
+
+// WriteSummary writes the releases as a table, along with their sync result
+func (r *Result) WriteSummary(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "APPLICATION\tCLUSTER\tIMAGE\tSYNC\tHEALTH\tRESULT\tMESSAGE")
+	for _, rel := range r.Releases {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			rel.Application,
+			rel.Cluster,
+			rel.Image,
+			valueOrNone(rel.SyncStatus),
+			valueOrNone(rel.HealthStatus),
+			valueOrNone(rel.Result),
+			rel.Message,
+		)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, rel := range r.Releases {
+		if len(rel.Diagnostics) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "\n%s on %s:\n", rel.Application, rel.Cluster)
+		for _, diag := range rel.Diagnostics {
+			line := fmt.Sprintf("  %s/%s (%s)", diag.Kind, diag.Name, diag.Health)
+			if diag.Message != "" {
+				line += " " + diag.Message
+			}
+			fmt.Fprintln(w, line)
+			for _, state := range diag.Containers {
+				fmt.Fprintf(w, "    container %s\n", state)
+			}
+
+			for _, event := range diag.Events {
+				fmt.Fprintf(w, "    event %s\n", event)
+			}
+		}
+	}
+
+	return nil
+}
+
+// HasDiagnostics returns true when any of the releases is diagnosed
+func (r *Result) HasDiagnostics() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rel := range r.Releases {
+		if len(rel.Diagnostics) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func valueOrNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+// WriteGitHubOutput appends the result as GitHub Actions step outputs to the file,
+// which is the file referred by GITHUB_OUTPUT environment variable.
+func (r *Result) WriteGitHubOutput(filename string) error {
+	var doc strings.Builder
+	if err := r.Write(&doc); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	applications := make([]string, 0, len(r.Releases))
+	for _, rel := range r.Releases {
+		applications = append(applications, rel.Application)
+	}
+
+	var errorCategory Category
+	if r.Error != nil {
+		errorCategory = r.Error.Category
+	}
+
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	delimiter := "DPL_RESULT_EOF"
+	_, err = fmt.Fprintf(f, "request-id=%s\nstatus=%s\ncommit-sha=%s\napplications=%s\nerror-category=%s\nresult<<%s\n%s%s\n",
+		r.RequestID,
+		r.Status,
+		r.CommitSHA,
+		strings.Join(applications, ","),
+		errorCategory,
+		delimiter,
+		doc.String(),
+		delimiter,
+	)
+	return err
+}
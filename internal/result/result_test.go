@@ -0,0 +1,108 @@
+package result_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ardikabs/dpl/internal/result"
+	"github.com/ardikabs/dpl/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitCode(t *testing.T) {
+	require.Equal(t, 0, result.ExitCode(nil))
+	require.Equal(t, 1, result.ExitCode(errors.New("unclassified")))
+
+	err := result.WithCategory(result.CategoryGit, errors.New("push failed"))
+	require.Equal(t, 3, result.ExitCode(err))
+
+	t.Run("category is kept when the error is wrapped", func(t *testing.T) {
+		wrapped := fmt.Errorf("deployment failed: %w", err)
+		require.Equal(t, result.CategoryGit, result.CategoryOf(wrapped))
+	})
+
+	t.Run("first category wins", func(t *testing.T) {
+		reclassified := result.WithCategory(result.CategorySync, err)
+		require.Equal(t, result.CategoryGit, result.CategoryOf(reclassified))
+	})
+}
+
+func TestResult_Write(t *testing.T) {
+	res := result.New("b6d7153")
+	res.CommitSHA = "2a8e4d7"
+	res.Track("clone")()
+	res.SetReleases(types.ListReleases{
+		{
+			ID:          "myapp-dev-1",
+			Name:        "myapp",
+			Environment: "dev",
+			Cluster:     "dev-1",
+			Image:       types.ImageDefinition{Name: "ghcr.io/ardikabs/app/myapp", Tag: "v1.0.0"},
+			Status:      types.ReleaseStatus{Sync: "Synced", Health: "Degraded"},
+		},
+	})
+	res.Finish(result.WithCategory(result.CategoryDegraded, errors.New("health status degraded")))
+
+	t.Run("result file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "result.json")
+		require.NoError(t, res.WriteFile(file))
+
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+
+		var doc map[string]any
+		require.NoError(t, json.Unmarshal(content, &doc))
+		require.Equal(t, result.SchemaVersion, doc["schemaVersion"])
+		require.Equal(t, result.StatusFailed, doc["status"])
+		require.Equal(t, "degraded", doc["error"].(map[string]any)["category"])
+		require.Len(t, doc["stages"], 1)
+		require.Equal(t, "Degraded", doc["releases"].([]any)[0].(map[string]any)["healthStatus"])
+	})
+
+	t.Run("github output", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "github_output")
+		require.NoError(t, res.WriteGitHubOutput(file))
+
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.Contains(t, string(content), "request-id=b6d7153\n")
+		require.Contains(t, string(content), "status=failed\n")
+		require.Contains(t, string(content), "commit-sha=2a8e4d7\n")
+		require.Contains(t, string(content), "applications=myapp-dev-1\n")
+		require.Contains(t, string(content), "error-category=degraded\n")
+		require.Contains(t, string(content), "result<<DPL_RESULT_EOF\n{")
+	})
+}
+
+func TestResult_Partial(t *testing.T) {
+	res := result.New("b6d7153")
+	res.SetReleases(types.ListReleases{
+		{
+			ID:      "myapp-dev-1",
+			Cluster: "dev-1",
+			Image:   types.ImageDefinition{Name: "ghcr.io/ardikabs/app/myapp", Tag: "v1.0.0"},
+			Status:  types.ReleaseStatus{Sync: "Synced", Health: "Healthy", Result: types.ReleaseResultSucceeded},
+		},
+		{
+			ID:      "myapp-dev-2",
+			Cluster: "dev-2",
+			Image:   types.ImageDefinition{Name: "ghcr.io/ardikabs/app/myapp", Tag: "v1.0.0"},
+			Status:  types.ReleaseStatus{Sync: "OutOfSync", Health: "Progressing", Result: types.ReleaseResultTimeout, Message: "watch operation timeout is exceeded"},
+		},
+	})
+	res.Finish(nil)
+	require.Equal(t, result.StatusPartial, res.Status)
+
+	var summary strings.Builder
+	require.NoError(t, res.WriteSummary(&summary))
+
+	lines := strings.Split(strings.TrimSpace(summary.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Contains(t, lines[2], "timeout")
+	require.Contains(t, lines[2], "watch operation timeout is exceeded")
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/notifier"
+	"github.com/ardikabs/dpl/internal/result"
+	"github.com/go-logr/logr"
+)
+
+// Status is the state of the deployment within the server
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+func (s Status) isFinished() bool {
+	return s == StatusSucceeded || s == StatusFailed
+}
+
+// EventType is the kind of the progress event streamed to the clients
+type EventType string
+
+const (
+	// EventStatus is sent when the deployment status changes, the data is the Status
+	EventStatus EventType = "status"
+	// EventLog is sent for every log line of the deployment, the data is the line
+	EventLog EventType = "log"
+	// EventLifecycle is sent for the deployment lifecycle events, the data is the notifier.Event
+	EventLifecycle EventType = "lifecycle"
+)
+
+type Event struct {
+	ID   int       `json:"id"`
+	Type EventType `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+// Request is the deployment request, it accepts the same parameters as the exec command,
+// either a single release or many deployments at once as in the batch file.
+type Request struct {
+	Release                string          `json:"release,omitempty"`
+	Environment            string          `json:"environment,omitempty"`
+	Cluster                string          `json:"cluster,omitempty"`
+	Image                  string          `json:"image,omitempty"`
+	Deployments            []RequestTarget `json:"deployments,omitempty"`
+	Profile                string          `json:"profile,omitempty"`
+	KustomizationFileRef   string          `json:"kustomizeFileRef,omitempty"`
+	KustomizationImageRef  string          `json:"kustomizeImageRef,omitempty"`
+	RollbackOnSmokeFailure bool            `json:"rollbackOnSmokeFailure,omitempty"`
+	Restart                bool            `json:"restart,omitempty"`
+	Actor                  string          `json:"actor,omitempty"`
+	Reason                 string          `json:"reason,omitempty"`
+}
+
+type RequestTarget struct {
+	Release     string `json:"release"`
+	Environment string `json:"environment,omitempty"`
+	Cluster     string `json:"cluster,omitempty"`
+	Image       string `json:"image,omitempty"`
+}
+
+// Deployment is a deployment submitted to the server, its ID is the request ID of the execution.
+type Deployment struct {
+	ID         string         `json:"id"`
+	Status     Status         `json:"status"`
+	Request    Request        `json:"request"`
+	Error      string         `json:"error,omitempty"`
+	Result     *result.Result `json:"result,omitempty"`
+	Logs       []string       `json:"logs"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	StartedAt  *time.Time     `json:"startedAt,omitempty"`
+	FinishedAt *time.Time     `json:"finishedAt,omitempty"`
+
+	mu      sync.Mutex
+	events  []Event
+	changed chan struct{}
+	partial []byte
+	logger  logr.Logger
+}
+
+func newDeployment(id string, req Request) *Deployment {
+	d := &Deployment{
+		ID:        id,
+		Status:    StatusQueued,
+		Request:   req,
+		Logs:      []string{},
+		CreatedAt: time.Now().UTC(),
+		changed:   make(chan struct{}),
+	}
+
+	d.publish(EventStatus, StatusQueued)
+	return d
+}
+
+// Logger returns the logger capturing the logs of the deployment
+func (d *Deployment) Logger() logr.Logger {
+	return d.logger
+}
+
+// SetResult records the machine-readable result of the deployment
+func (d *Deployment) SetResult(res *result.Result) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.Result = res
+}
+
+// Send implements notifier.Sink, so the lifecycle events are streamed as the deployment progress
+func (d *Deployment) Send(_ context.Context, ev notifier.Event) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.publish(EventLifecycle, ev)
+	return nil
+}
+
+// Write implements io.Writer, so the deployment logs are captured line by line
+func (d *Deployment) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.partial = append(d.partial, p...)
+	for {
+		idx := bytes.IndexByte(d.partial, '\n')
+		if idx < 0 {
+			break
+		}
+
+		line := string(d.partial[:idx])
+		d.partial = d.partial[idx+1:]
+
+		d.Logs = append(d.Logs, line)
+		d.publish(EventLog, line)
+	}
+
+	return len(p), nil
+}
+
+func (d *Deployment) start() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now().UTC()
+	d.Status, d.StartedAt = StatusRunning, &now
+	d.publish(EventStatus, StatusRunning)
+}
+
+func (d *Deployment) finish(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now().UTC()
+	d.Status, d.FinishedAt = StatusSucceeded, &now
+	if err != nil {
+		d.Status, d.Error = StatusFailed, err.Error()
+	}
+
+	d.publish(EventStatus, d.Status)
+}
+
+// publish appends the event and wakes up every subscriber, the caller must hold the lock.
+func (d *Deployment) publish(typ EventType, data any) {
+	d.events = append(d.events, Event{
+		ID:   len(d.events) + 1,
+		Type: typ,
+		Time: time.Now().UTC(),
+		Data: data,
+	})
+
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
+
+// eventsSince returns the events after the given event ID, the channel that is closed on the next event,
+// and whether the deployment has finished.
+func (d *Deployment) eventsSince(id int) ([]Event, <-chan struct{}, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if id < 0 || id > len(d.events) {
+		id = len(d.events)
+	}
+
+	return d.events[id:], d.changed, d.Status.isFinished()
+}
+
+func (d *Deployment) isFinished() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.Status.isFinished()
+}
+
+// snapshot returns a copy of the deployment that is safe to be encoded
+func (d *Deployment) snapshot() *Deployment {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return &Deployment{
+		ID:         d.ID,
+		Status:     d.Status,
+		Request:    d.Request,
+		Error:      d.Error,
+		Result:     d.Result,
+		Logs:       append([]string{}, d.Logs...),
+		CreatedAt:  d.CreatedAt,
+		StartedAt:  d.StartedAt,
+		FinishedAt: d.FinishedAt,
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"github.com/ardikabs/dpl/internal/history"
+	"github.com/go-logr/logr"
+)
+
+type Options struct {
+	Logger logr.Logger
+	// Port is the port the server listens on
+	Port uint
+	// Token is the bearer token required on the API requests
+	Token string
+	// Workers is the maximum number of deployments executed concurrently
+	Workers uint
+	// QueueSize is the maximum number of deployments waiting for a worker, the server rejects deployments beyond it
+	QueueSize uint
+	// MaxRetained is the maximum number of finished deployments kept in memory, the oldest are evicted first
+	MaxRetained uint
+	// History is the deployment ledger served through the API, it is disabled when it is nil
+	History history.Store
+}
+
+type Option func(*Options)
+
+func newOptions(opts ...Option) *Options {
+	o := &Options{
+		Logger:      logr.Discard(),
+		Port:        10080,
+		Workers:     4,
+		QueueSize:   100,
+		MaxRetained: 1000,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+func WithLogger(logger logr.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+func WithPort(port uint) Option {
+	return func(o *Options) {
+		o.Port = port
+	}
+}
+
+func WithToken(token string) Option {
+	return func(o *Options) {
+		o.Token = token
+	}
+}
+
+func WithWorkers(workers uint) Option {
+	return func(o *Options) {
+		if workers > 0 {
+			o.Workers = workers
+		}
+	}
+}
+
+func WithQueueSize(size uint) Option {
+	return func(o *Options) {
+		if size > 0 {
+			o.QueueSize = size
+		}
+	}
+}
+
+func WithMaxRetained(n uint) Option {
+	return func(o *Options) {
+		if n > 0 {
+			o.MaxRetained = n
+		}
+	}
+}
+
+func WithHistory(store history.Store) Option {
+	return func(o *Options) {
+		o.History = store
+	}
+}
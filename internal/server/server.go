@@ -0,0 +1,363 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/errs"
+	"github.com/ardikabs/dpl/internal/history"
+	"github.com/ardikabs/dpl/internal/log"
+	"github.com/ardikabs/dpl/internal/metrics"
+	"github.com/google/uuid"
+	"golang.org/x/sync/errgroup"
+)
+
+const shutdownTimeout = 10 * time.Second
+
+var (
+	ErrServerTokenRequired = errors.New("server token is required")
+	ErrQueueFull           = errors.New("deployment queue is full")
+	ErrDeploymentNotFound  = errors.New("deployment not found")
+	ErrInvalidRequest      = errors.New("invalid deployment request")
+)
+
+// Runner executes the deployments submitted to the server
+type Runner interface {
+	// Validate checks the request before it is queued
+	Validate(req Request) error
+	// Run executes the deployment, it is given the logger, the lifecycle sink, and the result through the Deployment.
+	Run(ctx context.Context, d *Deployment) error
+}
+
+type Server struct {
+	opts   *Options
+	runner Runner
+	queue  chan *Deployment
+
+	mu          sync.RWMutex
+	deployments map[string]*Deployment
+	order       []string
+
+	handlers map[string]http.Handler
+}
+
+func New(runner Runner, opts ...Option) (*Server, error) {
+	o := newOptions(opts...)
+	if o.Token == "" {
+		return nil, ErrServerTokenRequired
+	}
+
+	return &Server{
+		opts:        o,
+		runner:      runner,
+		queue:       make(chan *Deployment, o.QueueSize),
+		deployments: make(map[string]*Deployment),
+		handlers:    make(map[string]http.Handler),
+	}, nil
+}
+
+// Handle registers an additional handler on the API, such as the webhook receivers,
+// the handler is responsible for its own authentication.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.handlers[pattern] = handler
+}
+
+// Submit validates and queues the deployment request, the deployment is executed once a worker is available.
+func (s *Server) Submit(req Request) (*Deployment, error) {
+	if err := s.runner.Validate(req); err != nil {
+		return nil, errs.Wrap(err, ErrInvalidRequest)
+	}
+
+	d := newDeployment(uuid.New().String(), req)
+	d.logger = log.New(d).WithName("server").WithValues("deploymentID", d.ID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case s.queue <- d:
+	default:
+		return nil, ErrQueueFull
+	}
+
+	s.deployments[d.ID] = d
+	s.order = append(s.order, d.ID)
+	s.evict()
+
+	return d, nil
+}
+
+// Get returns the deployment by its ID
+func (s *Server) Get(id string) (*Deployment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d, ok := s.deployments[id]
+	if !ok {
+		return nil, ErrDeploymentNotFound
+	}
+
+	return d, nil
+}
+
+// evict removes the oldest finished deployments beyond the retention, the caller must hold the lock.
+func (s *Server) evict() {
+	excess := len(s.order) - int(s.opts.MaxRetained)
+	if excess <= 0 {
+		return
+	}
+
+	kept := s.order[:0]
+	for _, id := range s.order {
+		if excess > 0 && s.deployments[id].isFinished() {
+			delete(s.deployments, id)
+			excess--
+			continue
+		}
+
+		kept = append(kept, id)
+	}
+
+	s.order = kept
+}
+
+// Run listens on the configured port, then serves until the context is done
+func (s *Server) Run(ctx context.Context) error {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", s.opts.Port))
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(ctx, l)
+}
+
+// Serve starts the workers and serves the API on the listener until the context is done
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	log := s.opts.Logger.WithName("server.Serve")
+
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	for i := uint(0); i < s.opts.Workers; i++ {
+		g.Go(func() error {
+			s.work(ctx)
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		log.Info("server is listening", "addr", l.Addr().String(), "workers", s.opts.Workers, "queueSize", s.opts.QueueSize)
+		if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+
+		return nil
+	})
+
+	g.Go(func() error {
+		<-ctx.Done()
+		log.Info("server is shutting down")
+
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+		defer cancel()
+
+		return srv.Shutdown(shutdownCtx)
+	})
+
+	return g.Wait()
+}
+
+func (s *Server) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-s.queue:
+			d.start()
+			d.finish(s.runner.Run(ctx, d))
+		}
+	}
+}
+
+// Handler returns the HTTP handler of the API, the deployment endpoints require the bearer token,
+// while the health and metrics endpoints don't.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.Handle("GET /metrics", metrics.Handler())
+
+	mux.Handle("POST /v1/deployments", s.authenticate(http.HandlerFunc(s.createDeployment)))
+	mux.Handle("GET /v1/deployments/{id}", s.authenticate(http.HandlerFunc(s.getDeployment)))
+	mux.Handle("GET /v1/deployments/{id}/events", s.authenticate(http.HandlerFunc(s.streamDeploymentEvents)))
+
+	if s.opts.History != nil {
+		mux.Handle("GET /v1/history", s.authenticate(http.HandlerFunc(s.queryHistory)))
+	}
+
+	for pattern, handler := range s.handlers {
+		mux.Handle(pattern, handler)
+	}
+
+	return mux
+}
+
+// authenticate rejects the requests without the valid bearer token
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) createDeployment(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errs.Wrap(err, ErrInvalidRequest))
+		return
+	}
+
+	d, err := s.Submit(req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrInvalidRequest):
+			status = http.StatusBadRequest
+		case errors.Is(err, ErrQueueFull):
+			status = http.StatusServiceUnavailable
+		}
+
+		writeError(w, status, err)
+		return
+	}
+
+	w.Header().Set("Location", "/v1/deployments/"+d.ID)
+	writeJSON(w, http.StatusAccepted, d.snapshot())
+}
+
+func (s *Server) getDeployment(w http.ResponseWriter, r *http.Request) {
+	d, err := s.Get(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, d.snapshot())
+}
+
+// streamDeploymentEvents streams the deployment progress as Server-Sent Events until the deployment finishes,
+// clients could resume from the last received event through the Last-Event-ID header.
+func (s *Server) streamDeploymentEvents(w http.ResponseWriter, r *http.Request) {
+	d, err := s.Get(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	lastID, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for {
+		events, changed, finished := d.eventsSince(lastID)
+		for _, ev := range events {
+			data, err := json.Marshal(ev.Data)
+			if err != nil {
+				return
+			}
+
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+			lastID = ev.ID
+		}
+		flusher.Flush()
+
+		if finished {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changed:
+		}
+	}
+}
+
+// queryHistory returns the deployment ledger entries, filtered by the release, environment, cluster, result, since, and limit query parameters.
+func (s *Server) queryHistory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	f := history.Filter{
+		Release:     q.Get("release"),
+		Environment: q.Get("environment"),
+		Cluster:     q.Get("cluster"),
+		Result:      q.Get("result"),
+	}
+
+	if since := q.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errs.Wrapf(err, "invalid since parameter"))
+			return
+		}
+		f.Since = t
+	}
+
+	if limit := q.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errs.Wrapf(err, "invalid limit parameter"))
+			return
+		}
+		f.Limit = n
+	}
+
+	entries, err := s.opts.History.Query(f)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
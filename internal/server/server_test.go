@@ -0,0 +1,157 @@
+package server_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/notifier"
+	"github.com/ardikabs/dpl/internal/result"
+	"github.com/ardikabs/dpl/internal/server"
+	"github.com/stretchr/testify/require"
+)
+
+const token = "s3cr3t"
+
+type fakeRunner struct {
+	release chan struct{}
+}
+
+func (r *fakeRunner) Validate(req server.Request) error {
+	if req.Release == "" {
+		return errors.New("release is required")
+	}
+
+	return nil
+}
+
+func (r *fakeRunner) Run(ctx context.Context, d *server.Deployment) error {
+	<-r.release
+
+	d.Logger().Info("deploying release", "release", d.Request.Release)
+	_ = d.Send(ctx, notifier.Event{Type: notifier.EventSucceeded, RequestID: d.ID})
+	d.SetResult(result.New(d.ID))
+
+	return nil
+}
+
+func startServer(t *testing.T, runner server.Runner, opts ...server.Option) string {
+	srv, err := server.New(runner, append([]server.Option{server.WithToken(token)}, opts...)...)
+	require.NoError(t, err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- srv.Serve(ctx, l) }()
+
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	return "http://" + l.Addr().String()
+}
+
+func do(t *testing.T, method, url string, body any) *http.Response {
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+
+	req, err := http.NewRequest(method, url, &buf)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return resp
+}
+
+func decode(t *testing.T, resp *http.Response) map[string]any {
+	var out map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	return out
+}
+
+func TestServer_Deployment(t *testing.T) {
+	runner := &fakeRunner{release: make(chan struct{})}
+	url := startServer(t, runner)
+
+	resp := do(t, http.MethodPost, url+"/v1/deployments", server.Request{Release: "myapp", Environment: "staging", Image: "myapp:v1"})
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	created := decode(t, resp)
+	id := created["id"].(string)
+	require.Equal(t, "queued", created["status"])
+	require.Equal(t, "/v1/deployments/"+id, resp.Header.Get("Location"))
+
+	events := do(t, http.MethodGet, url+"/v1/deployments/"+id+"/events", nil)
+	require.Equal(t, "text/event-stream", events.Header.Get("Content-Type"))
+
+	close(runner.release)
+
+	var types []string
+	scanner := bufio.NewScanner(events.Body)
+	for scanner.Scan() {
+		if typ, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			types = append(types, typ)
+		}
+	}
+	require.Equal(t, []string{"status", "status", "log", "lifecycle", "status"}, types)
+
+	got := decode(t, do(t, http.MethodGet, url+"/v1/deployments/"+id, nil))
+	require.Equal(t, "succeeded", got["status"])
+	require.Len(t, got["logs"], 1)
+	require.Equal(t, id, got["result"].(map[string]any)["requestID"])
+}
+
+func TestServer_Rejections(t *testing.T) {
+	runner := &fakeRunner{release: make(chan struct{})}
+	url := startServer(t, runner, server.WithWorkers(1), server.WithQueueSize(1))
+
+	// cleanups run in reverse, so the blocked worker is released before the server is stopped
+	t.Cleanup(func() { close(runner.release) })
+
+	t.Run("unauthorized", func(t *testing.T) {
+		resp, err := http.Post(url+"/v1/deployments", "application/json", strings.NewReader(`{"release":"myapp"}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		resp := do(t, http.MethodPost, url+"/v1/deployments", server.Request{})
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		resp := do(t, http.MethodGet, url+"/v1/deployments/unknown", nil)
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("queue is full", func(t *testing.T) {
+		// the first is picked up by the only worker, the second fills up the queue
+		require.Equal(t, http.StatusAccepted, do(t, http.MethodPost, url+"/v1/deployments", server.Request{Release: "myapp"}).StatusCode)
+		time.Sleep(100 * time.Millisecond)
+		require.Equal(t, http.StatusAccepted, do(t, http.MethodPost, url+"/v1/deployments", server.Request{Release: "myapp"}).StatusCode)
+
+		resp := do(t, http.MethodPost, url+"/v1/deployments", server.Request{Release: "myapp"})
+		require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	})
+}
+
+func TestNew_TokenRequired(t *testing.T) {
+	_, err := server.New(&fakeRunner{})
+	require.ErrorIs(t, err, server.ErrServerTokenRequired)
+}
@@ -0,0 +1,15 @@
+package smoke
+
+import "github.com/go-logr/logr"
+
+type Options struct {
+	Logger logr.Logger
+}
+
+type Option func(*Options)
+
+func WithLogger(logger logr.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
@@ -0,0 +1,314 @@
+package smoke
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"text/template"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/errs"
+	"github.com/ardikabs/dpl/internal/tools/cmdutils"
+	"github.com/ardikabs/dpl/internal/tools/ioutils"
+	"github.com/ardikabs/dpl/internal/tools/retry"
+	goyaml "gopkg.in/yaml.v3"
+)
+
+var (
+	DefaultTimeoutSec       uint = 300 // 5 minutes
+	DefaultRetryIntervalSec uint = 5
+
+	ErrSmokeCheckFailed        = errors.New("smoke check failed")
+	ErrSmokeCheckInvalidConfig = errors.New("invalid smoke check configuration")
+)
+
+// Config is the smoke checks configuration, for example:
+//
+//	timeoutSec: 300
+//	retryIntervalSec: 5
+//	checks:
+//	  - name: healthz
+//	    http:
+//	      url: https://{{ .Release }}.{{ .Cluster }}.example.com/healthz
+//	      expectedStatus: 200
+//	      expectedBody: '"status":\s*"ok"'
+//	  - name: database
+//	    tcp:
+//	      address: {{ .Release }}-db.{{ .Namespace }}.svc:5432
+//	  - name: e2e
+//	    command: ./scripts/smoke.sh
+type Config struct {
+	TimeoutSec       uint    `yaml:"timeoutSec"`
+	RetryIntervalSec uint    `yaml:"retryIntervalSec"`
+	Checks           []Check `yaml:"checks"`
+}
+
+// Check is a single smoke check, exactly one of HTTP, TCP, or Command must be set.
+// URL, address and command are Go templates rendered with the Target.
+type Check struct {
+	Name    string     `yaml:"name"`
+	HTTP    *HTTPCheck `yaml:"http"`
+	TCP     *TCPCheck  `yaml:"tcp"`
+	Command string     `yaml:"command"`
+}
+
+type HTTPCheck struct {
+	URL            string            `yaml:"url"`
+	Method         string            `yaml:"method"`
+	Headers        map[string]string `yaml:"headers"`
+	ExpectedStatus int               `yaml:"expectedStatus"`
+	ExpectedBody   string            `yaml:"expectedBody"`
+
+	expectedBody *regexp.Regexp
+}
+
+type TCPCheck struct {
+	Address string `yaml:"address"`
+}
+
+// Target is the deployed release, as in a single cluster, the smoke checks run against
+type Target struct {
+	Release     string
+	Environment string
+	Cluster     string
+	Namespace   string
+	Image       string
+}
+
+func (t Target) environ() []string {
+	return []string{
+		"DPL_RELEASE=" + t.Release,
+		"DPL_ENVIRONMENT=" + t.Environment,
+		"DPL_CLUSTER=" + t.Cluster,
+		"DPL_NAMESPACE=" + t.Namespace,
+		"DPL_IMAGE=" + t.Image,
+	}
+}
+
+type Checker struct {
+	cfg        Config
+	httpClient *http.Client
+}
+
+func Load(filename string) (*Checker, error) {
+	content, err := ioutils.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := goyaml.Unmarshal(content, &cfg); err != nil {
+		return nil, err
+	}
+
+	return New(cfg)
+}
+
+func New(cfg Config) (*Checker, error) {
+	if cfg.TimeoutSec == 0 {
+		cfg.TimeoutSec = DefaultTimeoutSec
+	}
+
+	if cfg.RetryIntervalSec == 0 {
+		cfg.RetryIntervalSec = DefaultRetryIntervalSec
+	}
+
+	for idx := range cfg.Checks {
+		c := &cfg.Checks[idx]
+
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("check-%d", idx)
+		}
+
+		kinds := 0
+		if c.HTTP != nil {
+			kinds++
+		}
+		if c.TCP != nil {
+			kinds++
+		}
+		if c.Command != "" {
+			kinds++
+		}
+
+		if kinds != 1 {
+			return nil, errs.Wrapf(ErrSmokeCheckInvalidConfig, "check %s must have exactly one of http, tcp, or command", c.Name)
+		}
+
+		// Ensure the templates are valid upfront, so it doesn't end up retrying a misconfigured check
+		for _, text := range []string{c.Command, ptrField(c.HTTP, func(h *HTTPCheck) string { return h.URL }), ptrField(c.TCP, func(t *TCPCheck) string { return t.Address })} {
+			if _, err := renderTemplate(text, Target{}); err != nil {
+				return nil, errs.Wrapf(ErrSmokeCheckInvalidConfig, "check %s has invalid template: %s", c.Name, err)
+			}
+		}
+
+		if c.HTTP != nil {
+			if c.HTTP.Method == "" {
+				c.HTTP.Method = http.MethodGet
+			}
+
+			if c.HTTP.ExpectedStatus == 0 {
+				c.HTTP.ExpectedStatus = http.StatusOK
+			}
+
+			if c.HTTP.ExpectedBody != "" {
+				re, err := regexp.Compile(c.HTTP.ExpectedBody)
+				if err != nil {
+					return nil, errs.Wrapf(ErrSmokeCheckInvalidConfig, "check %s has invalid expectedBody: %s", c.Name, err)
+				}
+
+				c.HTTP.expectedBody = re
+			}
+		}
+	}
+
+	return &Checker{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Run executes every smoke check against the target, each check is retried until it passes or the deadline is exceeded.
+// A nil checker is valid and does nothing, so callers don't need to check whether smoke checks are configured.
+func (c *Checker) Run(ctx context.Context, target Target, opts ...Option) error {
+	if c == nil {
+		return nil
+	}
+
+	o := &Options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	log := o.Logger.WithName("smoke.Run").WithValues("cluster", target.Cluster)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(c.cfg.TimeoutSec)*time.Second)
+	defer cancel()
+
+	for _, check := range c.cfg.Checks {
+		log := log.WithValues("check", check.Name)
+
+		if err := retry.OnError(ctx, func(err error) bool {
+			return true
+		}, func(ctx context.Context) error {
+			return c.run(ctx, check, target, o)
+		},
+			retry.WithRetryIntervalSec(int(c.cfg.RetryIntervalSec)),
+			retry.WithRetryTimoutSec(int(c.cfg.TimeoutSec)),
+			retry.WithLogger(log),
+		); err != nil {
+			return errs.Wrap(err, errs.Wrapf(ErrSmokeCheckFailed, "check %s on cluster %s", check.Name, target.Cluster))
+		}
+
+		log.Info("smoke check passed")
+	}
+
+	return nil
+}
+
+func (c *Checker) run(ctx context.Context, check Check, target Target, o *Options) error {
+	switch {
+	case check.HTTP != nil:
+		return c.runHTTP(ctx, check.HTTP, target)
+	case check.TCP != nil:
+		return runTCP(ctx, check.TCP, target)
+	default:
+		return runCommand(ctx, check.Command, target, o)
+	}
+}
+
+func (c *Checker) runHTTP(ctx context.Context, check *HTTPCheck, target Target) error {
+	url, err := renderTemplate(check.URL, target)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, check.Method, url, nil)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range check.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != check.ExpectedStatus {
+		return fmt.Errorf("unexpected status code %d, expecting %d", resp.StatusCode, check.ExpectedStatus)
+	}
+
+	if check.expectedBody == nil {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if !check.expectedBody.Match(body) {
+		return fmt.Errorf("response body does not match '%s'", check.ExpectedBody)
+	}
+
+	return nil
+}
+
+func runTCP(ctx context.Context, check *TCPCheck, target Target) error {
+	address, err := renderTemplate(check.Address, target)
+	if err != nil {
+		return err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+func runCommand(ctx context.Context, command string, target Target, o *Options) error {
+	command, err := renderTemplate(command, target)
+	if err != nil {
+		return err
+	}
+
+	return cmdutils.Exec(ctx, command,
+		cmdutils.WithShellMode("/bin/sh"),
+		cmdutils.WithEnv(target.environ()...),
+		cmdutils.WithLogger(o.Logger),
+	)
+}
+
+func renderTemplate(text string, target Target) (string, error) {
+	tmpl, err := template.New("smoke").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, target); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func ptrField[T any](v *T, fn func(*T) string) string {
+	if v == nil {
+		return ""
+	}
+
+	return fn(v)
+}
@@ -0,0 +1,96 @@
+package smoke_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ardikabs/dpl/internal/smoke"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("check without kind", func(t *testing.T) {
+		_, err := smoke.New(smoke.Config{Checks: []smoke.Check{{Name: "empty"}}})
+		require.ErrorIs(t, err, smoke.ErrSmokeCheckInvalidConfig)
+	})
+
+	t.Run("check with multiple kinds", func(t *testing.T) {
+		_, err := smoke.New(smoke.Config{Checks: []smoke.Check{{
+			TCP:     &smoke.TCPCheck{Address: "localhost:80"},
+			Command: "true",
+		}}})
+		require.ErrorIs(t, err, smoke.ErrSmokeCheckInvalidConfig)
+	})
+
+	t.Run("check with unknown template field", func(t *testing.T) {
+		_, err := smoke.New(smoke.Config{Checks: []smoke.Check{{
+			TCP: &smoke.TCPCheck{Address: "{{ .Unknown }}:80"},
+		}}})
+		require.ErrorIs(t, err, smoke.ErrSmokeCheckInvalidConfig)
+	})
+}
+
+func TestChecker_Run(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/dev-1/healthz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Write([]byte(`{"status": "ok"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	target := smoke.Target{Release: "myapp", Cluster: "dev-1"}
+
+	t.Run("nil checker does nothing", func(t *testing.T) {
+		var c *smoke.Checker
+		require.NoError(t, c.Run(context.TODO(), target))
+	})
+
+	t.Run("http check passed", func(t *testing.T) {
+		c, err := smoke.New(smoke.Config{RetryIntervalSec: 1, Checks: []smoke.Check{{
+			HTTP: &smoke.HTTPCheck{
+				URL:          srv.URL + "/{{ .Cluster }}/healthz",
+				ExpectedBody: `"status":\s*"ok"`,
+			},
+		}}})
+		require.NoError(t, err)
+		require.NoError(t, c.Run(context.TODO(), target))
+	})
+
+	t.Run("http check failed", func(t *testing.T) {
+		c, err := smoke.New(smoke.Config{
+			TimeoutSec:       1,
+			RetryIntervalSec: 1,
+			Checks: []smoke.Check{{
+				HTTP: &smoke.HTTPCheck{URL: srv.URL + "/unknown"},
+			}},
+		})
+		require.NoError(t, err)
+		require.ErrorIs(t, c.Run(context.TODO(), target), smoke.ErrSmokeCheckFailed)
+	})
+
+	t.Run("tcp check passed", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { l.Close() })
+
+		c, err := smoke.New(smoke.Config{RetryIntervalSec: 1, Checks: []smoke.Check{{
+			TCP: &smoke.TCPCheck{Address: l.Addr().String()},
+		}}})
+		require.NoError(t, err)
+		require.NoError(t, c.Run(context.TODO(), target))
+	})
+
+	t.Run("command check passed", func(t *testing.T) {
+		c, err := smoke.New(smoke.Config{RetryIntervalSec: 1, Checks: []smoke.Check{{
+			Command: `test "$DPL_CLUSTER" = "{{ .Cluster }}"`,
+		}}})
+		require.NoError(t, err)
+		require.NoError(t, c.Run(context.TODO(), target))
+	})
+}
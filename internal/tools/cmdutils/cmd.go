@@ -20,15 +20,26 @@ func Exec(ctx context.Context, cmd string, opts ...Option) error {
 
 	log := o.logger.WithName("cmd.Exec")
 
-	command := o.executor(ctx, cmd, o.args...)
-	command.Dir = o.workdir
-
+	var command *exec.Cmd
 	if o.shell != nil {
+		// In shell mode, the cmd is passed as a script to the shell,
+		// with the cmd itself set as $0 and followed by the args as positional parameters.
 		shellExec := ptr.Deref(o.shell, "/bin/sh")
-		command.Path = shellExec
-		command.Args = append([]string{"-c", cmd}, command.Args...)
+		command = o.executor(ctx, shellExec, append([]string{"-c", cmd, cmd}, o.args...)...)
 
 		log = log.WithValues("shell", shellExec)
+	} else {
+		command = o.executor(ctx, cmd, o.args...)
+	}
+
+	command.Dir = o.workdir
+
+	if len(o.env) > 0 {
+		command.Env = append(command.Environ(), o.env...)
+	}
+
+	if o.stdin != nil {
+		command.Stdin = o.stdin
 	}
 
 	log = log.WithValues(
@@ -1,9 +1,12 @@
 package cmdutils_test
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"os"
 	"os/exec"
+	"strings"
 	"testing"
 
 	"github.com/ardikabs/dpl/internal/tools/cmdutils"
@@ -28,4 +31,17 @@ func TestExec(t *testing.T) {
 		err := cmdutils.Exec(context.TODO(), "ping", cmdutils.WithExecutor(fakeExecutor))
 		require.NoError(t, err)
 	})
+
+	t.Run("shell mode with env and stdin", func(t *testing.T) {
+		stdout := &bytes.Buffer{}
+		err := cmdutils.Exec(context.TODO(), `printf "%s-%s" "$FOO" "$(cat)"`,
+			cmdutils.WithShellMode("/bin/sh"),
+			cmdutils.WithEnv("FOO=bar"),
+			cmdutils.WithStdin(strings.NewReader("baz")),
+			cmdutils.WithStdout(stdout),
+			cmdutils.WithStderr(io.Discard),
+		)
+		require.NoError(t, err)
+		require.Equal(t, "bar-baz", stdout.String())
+	})
 }
@@ -15,7 +15,9 @@ type Options struct {
 	workdir string
 	shell   *string
 	args    []string
+	env     []string
 
+	stdin  io.Reader
 	stderr io.Writer
 	stdout io.Writer
 }
@@ -75,3 +77,16 @@ func WithShellMode(shell string) Option {
 		o.shell = ptr.To(shell)
 	}
 }
+
+// WithEnv appends environment variables in the form of "key=value" to the command environment
+func WithEnv(env ...string) Option {
+	return func(o *Options) {
+		o.env = append(o.env, env...)
+	}
+}
+
+func WithStdin(r io.Reader) Option {
+	return func(o *Options) {
+		o.stdin = r
+	}
+}
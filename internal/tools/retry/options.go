@@ -20,6 +20,12 @@ func WithRetryIntervalSec(intervalSec int) RetryOption {
 	}
 }
 
+func WithRetryInterval(interval time.Duration) RetryOption {
+	return func(o *RetryOptions) {
+		o.Interval = interval
+	}
+}
+
 func WithRetryTimoutSec(timeoutSec int) RetryOption {
 	return func(o *RetryOptions) {
 		o.Timeout = time.Duration(timeoutSec) * time.Second
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/ardikabs/dpl/internal/errs"
+	"github.com/ardikabs/dpl/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
@@ -25,7 +27,10 @@ func OnError(ctx context.Context, retriable RetriableFn, fn func(ctx context.Con
 		opt(options)
 	}
 
-	var lastErr error
+	var (
+		lastErr error
+		attempt int
+	)
 
 	log := options.Logger.WithName("retry.OnError")
 
@@ -38,6 +43,12 @@ func OnError(ctx context.Context, retriable RetriableFn, fn func(ctx context.Con
 		case retriable(err):
 			log.V(2).Info("error caught, retrying ...", "err", err)
 
+			attempt++
+			tracing.AddEvent(ctx, "retry",
+				attribute.Int("retry.attempt", attempt),
+				attribute.String("retry.error", err.Error()),
+			)
+
 			lastErr = err
 			return false, nil
 		default:
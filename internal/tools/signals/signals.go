@@ -0,0 +1,45 @@
+package signals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ardikabs/dpl/internal/log"
+)
+
+// ExitCodeForced is the exit code when the process is forced to exit by the second signal
+const ExitCodeForced = 130
+
+var ErrInterrupted = errors.New("interrupted by signal")
+
+// NotifyContext returns a context cancelled on the first SIGINT or SIGTERM, with ErrInterrupted as its cause,
+// so the running operations could be cleaned up. The second signal forces the process to exit.
+func NotifyContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-sigCh:
+			log.Info("signal received, cancelling, send it again to force exit", "signal", sig.String())
+			cancel(fmt.Errorf("%w: %s", ErrInterrupted, sig))
+		}
+
+		sig := <-sigCh
+		log.Info("signal received again, forcing exit", "signal", sig.String())
+		os.Exit(ExitCodeForced)
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigCh)
+		cancel(context.Canceled)
+	}
+}
@@ -0,0 +1,26 @@
+package signals_test
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/tools/signals"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyContext(t *testing.T) {
+	ctx, stop := signals.NotifyContext(context.Background())
+	defer stop()
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("context is not cancelled by the signal")
+	}
+
+	require.ErrorIs(t, context.Cause(ctx), signals.ErrInterrupted)
+}
@@ -0,0 +1,145 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TracerName  = "github.com/ardikabs/dpl"
+	ServiceName = "dpl"
+
+	ExporterNone    = "none"
+	ExporterOTLP    = "otlp"
+	ExporterConsole = "console"
+
+	AttributeRequestID = attribute.Key("dpl.request_id")
+)
+
+type requestIDKey struct{}
+
+// Setup configures the global tracer provider from the environment variables:
+//
+//   - OTEL_TRACES_EXPORTER, either "otlp", "console", or "none" (default).
+//     It defaults to "otlp" when OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set.
+//   - OTEL_EXPORTER_OTLP_PROTOCOL, either "grpc" (default) or "http/protobuf",
+//     the rest of OTEL_EXPORTER_OTLP_* variables are handled by the OTLP exporter itself.
+//   - DPL_TRACES_FILE, the file to write the spans to for offline use, it implies the "console" exporter.
+//
+// The returned shutdown function flushes the remaining spans, it must be called before the process exits.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	exporter, err := newExporter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	exporter := os.Getenv("OTEL_TRACES_EXPORTER")
+	file := os.Getenv("DPL_TRACES_FILE")
+
+	if exporter == "" {
+		switch {
+		case file != "":
+			exporter = ExporterConsole
+		case os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "":
+			exporter = ExporterOTLP
+		default:
+			exporter = ExporterNone
+		}
+	}
+
+	switch exporter {
+	case ExporterNone:
+		return nil, nil
+	case ExporterConsole:
+		var w io.Writer = os.Stderr
+		if file != "" {
+			f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return nil, err
+			}
+			w = f
+		}
+
+		return stdouttrace.New(stdouttrace.WithWriter(w))
+	case ExporterOTLP:
+		var client otlptrace.Client
+		switch protocol := os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL"); protocol {
+		case "", "grpc":
+			client = otlptracegrpc.NewClient()
+		case "http/protobuf":
+			client = otlptracehttp.NewClient()
+		default:
+			return nil, fmt.Errorf("unsupported OTLP protocol '%s'", protocol)
+		}
+
+		return otlptrace.New(ctx, client)
+	default:
+		return nil, fmt.Errorf("unsupported traces exporter '%s', it should be either %s, %s, or %s", exporter, ExporterOTLP, ExporterConsole, ExporterNone)
+	}
+}
+
+// WithRequestID stores the request ID in the context, so every span started from the context is attributed with it
+func WithRequestID(ctx context.Context, reqID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, reqID)
+}
+
+// Start starts a span with the dpl tracer, the span is attributed with the request ID when it is stored in the context
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if reqID, ok := ctx.Value(requestIDKey{}).(string); ok {
+		attrs = append(attrs, AttributeRequestID.String(reqID))
+	}
+
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records the error, if any, to the span then ends the span.
+// It is meant to be deferred with a pointer to the named error result.
+func End(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+
+	span.End()
+}
+
+// AddEvent adds an event to the span stored in the context, if any
+func AddEvent(ctx context.Context, name string, attrs ...attribute.KeyValue) {
+	trace.SpanFromContext(ctx).AddEvent(name, trace.WithAttributes(attrs...))
+}
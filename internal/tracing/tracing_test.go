@@ -0,0 +1,48 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ardikabs/dpl/internal/tracing"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStart(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	original := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(original) })
+
+	ctx := tracing.WithRequestID(context.TODO(), "b6d7153")
+
+	func() (err error) {
+		ctx, span := tracing.Start(ctx, "Exec")
+		defer tracing.End(span, &err)
+
+		tracing.AddEvent(ctx, "retry")
+		return errors.New("sync failed")
+	}()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, "Exec", spans[0].Name)
+	require.Contains(t, spans[0].Attributes, tracing.AttributeRequestID.String("b6d7153"))
+	require.Equal(t, codes.Error, spans[0].Status.Code)
+	require.Equal(t, "sync failed", spans[0].Status.Description)
+	require.Len(t, spans[0].Events, 2) // the retry event and the recorded error
+}
+
+func TestSetup(t *testing.T) {
+	t.Setenv("OTEL_TRACES_EXPORTER", "zipkin")
+
+	_, err := tracing.Setup(context.TODO())
+	require.Error(t, err)
+}
@@ -1,14 +1,49 @@
 package types
 
 type ArgoConfig struct {
+	// Name is the name of the Argo CD instance, recorded to the releases it owns
+	Name string
+
 	Host      string
 	Insecure  bool
 	PlainText bool
 	GRPCWeb   bool
 
+	// CACertFile is the CA bundle to verify the server certificate with, instead of the system roots
+	CACertFile string
+
+	// ClientCertFile and ClientKeyFile are the client certificate to authenticate the connection with
+	ClientCertFile string
+	ClientKeyFile  string
+
 	Secret ArgoSecret
 }
 
+// ArgoSecret is the credentials of Argo CD, either the token, the token file, or the username and password
 type ArgoSecret struct {
 	Token string
+
+	// TokenFile is read on every connection, so the rotated token is picked up
+	TokenFile string
+
+	// Username and Password log in a session on every connection
+	Username string
+	Password string
+}
+
+// GitFilesGenerator is the git files generator of an ApplicationSet, every generator file generates an Application
+type GitFilesGenerator struct {
+	RepoURL  string
+	Revision string
+
+	// Files are the glob patterns of the generator files within the repository
+	Files []string
+
+	// NameTemplate is the template of the Application name, it tells which generator file generates which Application
+	NameTemplate      string
+	GoTemplate        bool
+	GoTemplateOptions []string
+
+	// PathParamPrefix prefixes the path parameters of the generator file
+	PathParamPrefix string
 }
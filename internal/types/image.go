@@ -1,10 +1,15 @@
 package types
 
 type ImageDefinition struct {
-	Name string
-	Tag  string
+	Name   string
+	Tag    string
+	Digest string
 }
 
 func (i ImageDefinition) String() string {
+	if i.Digest != "" {
+		return i.Name + ":" + i.Tag + "@" + i.Digest
+	}
+
 	return i.Name + ":" + i.Tag
 }
@@ -1,5 +1,83 @@
 package types
 
+import "fmt"
+
+const (
+	SyncStatusSynced    = "Synced"
+	HealthStatusHealthy = "Healthy"
+)
+
+// the outcome of the release sync
+const (
+	ReleaseResultSucceeded  = "succeeded"
+	ReleaseResultDegraded   = "degraded"
+	ReleaseResultTimeout    = "timeout"
+	ReleaseResultSyncFailed = "sync-failed"
+	ReleaseResultCancelled  = "cancelled"
+
+	// ReleaseResultPaused is the release left with a paused rollout, which is accepted as succeeded
+	ReleaseResultPaused = "paused"
+
+	// ReleaseResultAborted is the release whose paused rollout is aborted
+	ReleaseResultAborted = "aborted"
+)
+
+// IsSucceededResult returns true when the sync result counts as succeeded
+func IsSucceededResult(result string) bool {
+	return result == ReleaseResultSucceeded || result == ReleaseResultPaused
+}
+
+type ReleaseStatus struct {
+	Sync   string
+	Health string
+
+	// Result is the outcome of the last sync, along with the message of the failure
+	Result  string
+	Message string
+
+	// Diagnostics are the unhealthy resources of the failed sync
+	Diagnostics []ResourceDiagnostic
+}
+
+// ResourceDiagnostic describes an unhealthy resource of the release, to tell why the release is failed
+type ResourceDiagnostic struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Health    string
+	Message   string
+
+	// Containers are the waiting or terminated states of the pod containers, e.g. "app: CrashLoopBackOff"
+	Containers []string
+
+	// Events are the recent warning events of the resource
+	Events []string
+}
+
+// String returns the resource along with the most telling reason it is unhealthy
+func (d ResourceDiagnostic) String() string {
+	reason := d.Message
+	if len(d.Containers) > 0 {
+		reason = d.Containers[0]
+	}
+
+	if reason == "" {
+		reason = d.Health
+	}
+
+	return fmt.Sprintf("%s/%s: %s", d.Kind, d.Name, reason)
+}
+
+// IsReady returns true when the release is both synced and healthy
+func (s ReleaseStatus) IsReady() bool {
+	return s.Sync == SyncStatusSynced && s.Health == HealthStatusHealthy
+}
+
+// IsFailed returns true when the last sync of the release is failed
+func (s ReleaseStatus) IsFailed() bool {
+	return s.Result != "" && !IsSucceededResult(s.Result)
+}
+
 type Release struct {
 	ID string
 
@@ -7,10 +85,31 @@ type Release struct {
 	Cluster     string
 	Environment string
 	Image       ImageDefinition
+	Labels      map[string]string
+	Annotations map[string]string
+	Status      ReleaseStatus
+
+	Project           string
+	Namespace         string
+	DestinationServer string
+	DestinationName   string
 
 	GitURL      string
 	GitPath     string
 	GitRevision string
+
+	// Instance is the name of the Argo CD instance owning the Application, empty when there is a single instance
+	Instance string
+
+	// ApplicationSet is the name of the ApplicationSet generating the Application, empty when it isn't generated
+	ApplicationSet string
+
+	// Generator is the git files generator of the ApplicationSet, when the Application is generated by one
+	Generator *GitFilesGenerator
+
+	// GeneratorFile is the generator file generating the Application, relative to the repository root,
+	// it is resolved from the Generator once the repository is cloned
+	GeneratorFile string
 }
 
 type ListReleases []*Release
@@ -30,3 +129,15 @@ func (l ListReleases) GetGitRevision() string {
 
 	return l[0].GitRevision
 }
+
+// Succeeded returns the releases whose last sync is succeeded
+func (l ListReleases) Succeeded() ListReleases {
+	var succeeded ListReleases
+	for _, rel := range l {
+		if IsSucceededResult(rel.Status.Result) {
+			succeeded = append(succeeded, rel)
+		}
+	}
+
+	return succeeded
+}
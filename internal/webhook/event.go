@@ -0,0 +1,237 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ardikabs/dpl/internal/errs"
+)
+
+var ErrInvalidPayload = errors.New("invalid webhook payload")
+
+// Source is the registry the webhook is received from
+type Source string
+
+const (
+	SourceDockerHub   Source = "dockerhub"
+	SourceGitHub      Source = "github"
+	SourceHarbor      Source = "harbor"
+	SourceCloudEvents Source = "cloudevents"
+)
+
+// PushEvent is an image pushed to the registry
+type PushEvent struct {
+	Source     Source
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// Image returns the image reference in the format of the exec command, as in 'IMAGE_NAME[:TAG][@DIGEST]'
+func (e PushEvent) Image() string {
+	image := e.Repository + ":" + e.Tag
+	if e.Digest != "" {
+		image += "@" + e.Digest
+	}
+
+	return image
+}
+
+// key identifies the pushed image for de-duplication, the digest is preferred as repeated events could come with different delivery IDs.
+func (e PushEvent) key() string {
+	if e.Digest != "" {
+		return e.Repository + ":" + e.Tag + "@" + e.Digest
+	}
+
+	return e.Repository + ":" + e.Tag
+}
+
+// parse extracts the pushed images from the webhook payload, events unrelated to the image push are left out.
+func parse(source Source, header http.Header, body []byte) ([]PushEvent, error) {
+	var (
+		events []PushEvent
+		err    error
+	)
+
+	switch source {
+	case SourceDockerHub:
+		events, err = parseDockerHub(body)
+	case SourceGitHub:
+		events, err = parseGitHub(header, body)
+	case SourceHarbor:
+		events, err = parseHarbor(body)
+	case SourceCloudEvents:
+		events, err = parseCloudEvents(header, body)
+	}
+
+	if err != nil {
+		return nil, errs.Wrap(err, ErrInvalidPayload)
+	}
+
+	return events, nil
+}
+
+type dockerHubPayload struct {
+	PushData struct {
+		Tag string `json:"tag"`
+	} `json:"push_data"`
+	Repository struct {
+		RepoName string `json:"repo_name"`
+	} `json:"repository"`
+}
+
+func parseDockerHub(body []byte) ([]PushEvent, error) {
+	var p dockerHubPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, err
+	}
+
+	if p.Repository.RepoName == "" || p.PushData.Tag == "" {
+		return nil, nil
+	}
+
+	return []PushEvent{{Source: SourceDockerHub, Repository: p.Repository.RepoName, Tag: p.PushData.Tag}}, nil
+}
+
+type gitHubPackage struct {
+	Name           string `json:"name"`
+	Namespace      string `json:"namespace"`
+	PackageType    string `json:"package_type"`
+	PackageVersion struct {
+		PackageURL        string `json:"package_url"`
+		ContainerMetadata struct {
+			Tag struct {
+				Name   string `json:"name"`
+				Digest string `json:"digest"`
+			} `json:"tag"`
+		} `json:"container_metadata"`
+	} `json:"package_version"`
+}
+
+type gitHubPayload struct {
+	Action          string         `json:"action"`
+	Package         *gitHubPackage `json:"package"`
+	RegistryPackage *gitHubPackage `json:"registry_package"`
+}
+
+// parseGitHub handles both 'package' and 'registry_package' events of the GitHub Container Registry
+func parseGitHub(header http.Header, body []byte) ([]PushEvent, error) {
+	switch header.Get("X-GitHub-Event") {
+	case "package", "registry_package":
+	default:
+		return nil, nil
+	}
+
+	var p gitHubPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, err
+	}
+
+	pkg := p.Package
+	if pkg == nil {
+		pkg = p.RegistryPackage
+	}
+
+	if p.Action != "published" || pkg == nil || !strings.EqualFold(pkg.PackageType, "container") {
+		return nil, nil
+	}
+
+	tag := pkg.PackageVersion.ContainerMetadata.Tag
+	if tag.Name == "" {
+		return nil, nil
+	}
+
+	repository := strings.ToLower("ghcr.io/" + pkg.Namespace + "/" + pkg.Name)
+	if url := pkg.PackageVersion.PackageURL; url != "" {
+		repository = splitImage(url)
+	}
+
+	return []PushEvent{{Source: SourceGitHub, Repository: repository, Tag: tag.Name, Digest: tag.Digest}}, nil
+}
+
+type harborPayload struct {
+	Type      string `json:"type"`
+	EventData struct {
+		Resources []struct {
+			Digest      string `json:"digest"`
+			Tag         string `json:"tag"`
+			ResourceURL string `json:"resource_url"`
+		} `json:"resources"`
+	} `json:"event_data"`
+}
+
+func parseHarbor(body []byte) ([]PushEvent, error) {
+	var p harborPayload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, err
+	}
+
+	if p.Type != "PUSH_ARTIFACT" {
+		return nil, nil
+	}
+
+	var events []PushEvent
+	for _, res := range p.EventData.Resources {
+		if res.Tag == "" || res.ResourceURL == "" {
+			continue
+		}
+
+		events = append(events, PushEvent{
+			Source:     SourceHarbor,
+			Repository: splitImage(res.ResourceURL),
+			Tag:        res.Tag,
+			Digest:     res.Digest,
+		})
+	}
+
+	return events, nil
+}
+
+type cloudEventData struct {
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+	Digest     string `json:"digest"`
+}
+
+type cloudEvent struct {
+	ID   string          `json:"id"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// parseCloudEvents handles both the structured and the binary content mode,
+// the event data must hold the repository, the tag, and optionally the digest of the pushed image.
+func parseCloudEvents(header http.Header, body []byte) ([]PushEvent, error) {
+	data := body
+	if header.Get("Ce-Specversion") == "" {
+		var ce cloudEvent
+		if err := json.Unmarshal(body, &ce); err != nil {
+			return nil, err
+		}
+		data = ce.Data
+	}
+
+	var d cloudEventData
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, err
+	}
+
+	if d.Repository == "" || d.Tag == "" {
+		return nil, errors.New("cloudevents data must have repository and tag")
+	}
+
+	return []PushEvent{{Source: SourceCloudEvents, Repository: d.Repository, Tag: d.Tag, Digest: d.Digest}}, nil
+}
+
+// splitImage returns the repository of the image reference, that is without the tag and the digest.
+func splitImage(ref string) string {
+	ref, _, _ = strings.Cut(ref, "@")
+
+	if idx := strings.LastIndex(ref, ":"); idx > strings.LastIndex(ref, "/") {
+		ref = ref[:idx]
+	}
+
+	return ref
+}
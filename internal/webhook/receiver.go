@@ -0,0 +1,317 @@
+package webhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ardikabs/dpl/internal/errs"
+	"github.com/ardikabs/dpl/internal/notifier"
+	"github.com/ardikabs/dpl/internal/server"
+	"github.com/ardikabs/dpl/internal/tools/ioutils"
+	"github.com/go-logr/logr"
+	goyaml "gopkg.in/yaml.v3"
+)
+
+const maxPayloadBytes = 1 << 20
+
+var (
+	DefaultDedupWindowSec uint = 600 // 10 minutes
+
+	ErrInvalidSignature       = errors.New("invalid webhook signature")
+	ErrSourceNotConfigured    = errors.New("webhook source is not configured")
+	ErrWebhookInvalidConfig   = errors.New("invalid webhook configuration")
+	ErrUnsupportedSource      = errors.New("unsupported webhook source")
+	ErrDeploymentNotSubmitted = errors.New("deployment is not submitted")
+)
+
+// Rule maps the pushed images to the release deployment, the repository and the tag are glob patterns.
+type Rule struct {
+	Repository  string `yaml:"repository"`
+	Tag         string `yaml:"tag"`
+	Release     string `yaml:"release"`
+	Environment string `yaml:"environment"`
+	Cluster     string `yaml:"cluster"`
+}
+
+func (r Rule) matches(ev PushEvent) bool {
+	if ok, _ := path.Match(r.Repository, ev.Repository); !ok {
+		return false
+	}
+
+	tag := r.Tag
+	if tag == "" {
+		tag = "*"
+	}
+
+	ok, _ := path.Match(tag, ev.Tag)
+	return ok
+}
+
+// key identifies the deployment of the pushed image by the rule, to be de-duplicated.
+func (r Rule) key(ev PushEvent) string {
+	return ev.key() + " " + r.Release + "/" + r.Environment + "/" + r.Cluster
+}
+
+// Secrets are the per source secrets to validate the webhook, a source without secret is rejected.
+//   - dockerhub: the token query parameter, as Docker Hub doesn't sign the webhook.
+//   - github: the HMAC-SHA256 secret of the X-Hub-Signature-256 header.
+//   - harbor: the Authorization header value.
+//   - cloudevents: the HMAC-SHA256 secret of the X-Dpl-Signature-256 header.
+type Secrets struct {
+	DockerHub   string `yaml:"dockerhub"`
+	GitHub      string `yaml:"github"`
+	Harbor      string `yaml:"harbor"`
+	CloudEvents string `yaml:"cloudevents"`
+}
+
+type Config struct {
+	Secrets        Secrets `yaml:"secrets"`
+	DedupWindowSec uint    `yaml:"dedupWindowSec"`
+	Rules          []Rule  `yaml:"rules"`
+}
+
+// Submitter queues the deployment, it is implemented by the server.
+type Submitter interface {
+	Submit(req server.Request) (*server.Deployment, error)
+}
+
+// Receiver accepts the registry webhooks on 'POST /v1/webhooks/{source}',
+// then submits a deployment for every rule matching the pushed image.
+type Receiver struct {
+	cfg       Config
+	submitter Submitter
+	logger    logr.Logger
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// Load reads the webhook configuration file, for example:
+//
+//	secrets:
+//	  github: ${GITHUB_WEBHOOK_SECRET}
+//	rules:
+//	  - repository: ghcr.io/ardikabs/app/myapp
+//	    tag: main-*
+//	    release: myapp
+//	    environment: dev
+func Load(filename string, submitter Submitter, logger logr.Logger) (*Receiver, error) {
+	content, err := ioutils.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := goyaml.Unmarshal(content, &cfg); err != nil {
+		return nil, err
+	}
+
+	return New(cfg, submitter, logger)
+}
+
+func New(cfg Config, submitter Submitter, logger logr.Logger) (*Receiver, error) {
+	for idx, rule := range cfg.Rules {
+		if rule.Repository == "" || rule.Release == "" || rule.Environment == "" {
+			return nil, errs.Wrapf(ErrWebhookInvalidConfig, "rules[%d]: repository, release, and environment are required", idx)
+		}
+
+		if _, err := path.Match(rule.Repository, ""); err != nil {
+			return nil, errs.Wrapf(ErrWebhookInvalidConfig, "rules[%d]: invalid repository pattern: %s", idx, err)
+		}
+
+		if _, err := path.Match(rule.Tag, ""); err != nil {
+			return nil, errs.Wrapf(ErrWebhookInvalidConfig, "rules[%d]: invalid tag pattern: %s", idx, err)
+		}
+	}
+
+	cfg.Secrets.DockerHub = os.ExpandEnv(cfg.Secrets.DockerHub)
+	cfg.Secrets.GitHub = os.ExpandEnv(cfg.Secrets.GitHub)
+	cfg.Secrets.Harbor = os.ExpandEnv(cfg.Secrets.Harbor)
+	cfg.Secrets.CloudEvents = os.ExpandEnv(cfg.Secrets.CloudEvents)
+
+	if cfg.DedupWindowSec == 0 {
+		cfg.DedupWindowSec = DefaultDedupWindowSec
+	}
+
+	return &Receiver{
+		cfg:       cfg,
+		submitter: submitter,
+		logger:    logger,
+		seen:      make(map[string]time.Time),
+	}, nil
+}
+
+type response struct {
+	Deployments []string `json:"deployments"`
+	Duplicates  []string `json:"duplicates,omitempty"`
+	Failures    []string `json:"failures,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+func (rc *Receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	source := Source(r.PathValue("source"))
+	log := rc.logger.WithName("webhook.Receiver").WithValues("source", source)
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPayloadBytes))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, response{Error: err.Error()})
+		return
+	}
+
+	if err := rc.verify(source, r, body); err != nil {
+		status := http.StatusUnauthorized
+		if errors.Is(err, ErrUnsupportedSource) {
+			status = http.StatusNotFound
+		}
+
+		log.Info("webhook is rejected", "err", err)
+		writeJSON(w, status, response{Error: err.Error()})
+		return
+	}
+
+	events, err := parse(source, r.Header, body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, response{Error: err.Error()})
+		return
+	}
+
+	res := response{Deployments: []string{}}
+	var failures []error
+
+	for _, ev := range events {
+		log := log.WithValues("image", ev.Image())
+		duplicate := false
+
+		for _, rule := range rc.cfg.Rules {
+			if !rule.matches(ev) {
+				continue
+			}
+
+			key := rule.key(ev)
+			if rc.isDuplicate(key) {
+				log.V(1).Info("duplicate push event, skipping", "release", rule.Release, "environment", rule.Environment)
+				duplicate = true
+				continue
+			}
+
+			d, err := rc.submitter.Submit(server.Request{
+				Release:     rule.Release,
+				Environment: rule.Environment,
+				Cluster:     rule.Cluster,
+				Image:       ev.Image(),
+				Actor:       "webhook/" + string(ev.Source),
+				Reason:      "image pushed to " + ev.Repository,
+			})
+			if err != nil {
+				// only the failed rule is forgotten, so the redelivered event doesn't resubmit the queued ones
+				rc.forget(key)
+				failures = append(failures, fmt.Errorf("%s to %s: %w", rule.Release, rule.Environment, err))
+				continue
+			}
+
+			log.Info("deployment is submitted", "release", rule.Release, "environment", rule.Environment, "deploymentID", d.ID)
+			res.Deployments = append(res.Deployments, d.ID)
+		}
+
+		if duplicate {
+			res.Duplicates = append(res.Duplicates, ev.Image())
+		}
+	}
+
+	if len(failures) > 0 {
+		err := errs.Wrap(errors.Join(failures...), ErrDeploymentNotSubmitted)
+		log.Error(err, "failed to submit deployments")
+
+		res.Error = ErrDeploymentNotSubmitted.Error()
+		for _, failure := range failures {
+			res.Failures = append(res.Failures, failure.Error())
+		}
+
+		// nothing is queued, the registry could redeliver the event as a whole
+		status := http.StatusServiceUnavailable
+		if len(res.Deployments) > 0 {
+			status = http.StatusMultiStatus
+		}
+
+		writeJSON(w, status, res)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, res)
+}
+
+func (rc *Receiver) verify(source Source, r *http.Request, body []byte) error {
+	var secret, got, want string
+
+	switch source {
+	case SourceDockerHub:
+		secret = rc.cfg.Secrets.DockerHub
+		got, want = r.URL.Query().Get("token"), secret
+	case SourceGitHub:
+		secret = rc.cfg.Secrets.GitHub
+		got, want = r.Header.Get("X-Hub-Signature-256"), notifier.Sign(secret, body)
+	case SourceHarbor:
+		secret = rc.cfg.Secrets.Harbor
+		got, want = r.Header.Get("Authorization"), secret
+	case SourceCloudEvents:
+		secret = rc.cfg.Secrets.CloudEvents
+		got, want = r.Header.Get(notifier.SignatureHeader), notifier.Sign(secret, body)
+	default:
+		return errs.Wrapf(ErrUnsupportedSource, "source: %s", source)
+	}
+
+	if secret == "" {
+		return ErrSourceNotConfigured
+	}
+
+	if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(got)), []byte(want)) != 1 {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// isDuplicate reports whether the deployment of the pushed image is already submitted within the de-duplication window,
+// registries redeliver the webhooks on failure, and some of them send an event per pushed manifest.
+func (rc *Receiver) isDuplicate(key string) bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	now := time.Now()
+	window := time.Duration(rc.cfg.DedupWindowSec) * time.Second
+
+	for key, at := range rc.seen {
+		if now.Sub(at) > window {
+			delete(rc.seen, key)
+		}
+	}
+
+	if _, ok := rc.seen[key]; ok {
+		return true
+	}
+
+	rc.seen[key] = now
+	return false
+}
+
+func (rc *Receiver) forget(key string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	delete(rc.seen, key)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
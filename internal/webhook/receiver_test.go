@@ -0,0 +1,190 @@
+package webhook_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ardikabs/dpl/internal/notifier"
+	"github.com/ardikabs/dpl/internal/server"
+	"github.com/ardikabs/dpl/internal/webhook"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	secret = "s3cr3t"
+	digest = "sha256:3b1c8a0e5c2f0d4a9e7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e1f0a9b8c7d6e5f"
+)
+
+type fakeSubmitter struct {
+	requests []server.Request
+	err      error
+	// failures fail the submission per environment
+	failures map[string]error
+}
+
+func (s *fakeSubmitter) Submit(req server.Request) (*server.Deployment, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	if err := s.failures[req.Environment]; err != nil {
+		return nil, err
+	}
+
+	s.requests = append(s.requests, req)
+	return &server.Deployment{ID: "b6d7153"}, nil
+}
+
+func newReceiver(t *testing.T, submitter webhook.Submitter, rules ...webhook.Rule) http.Handler {
+	if len(rules) == 0 {
+		rules = []webhook.Rule{
+			{Repository: "ardikabs/myapp", Tag: "main-*", Release: "myapp", Environment: "dev"},
+			{Repository: "ghcr.io/ardikabs/*", Tag: "main-*", Release: "myapp", Environment: "dev", Cluster: "k8s-dev-1"},
+			{Repository: "harbor.ardikabs.com/library/myapp", Release: "myapp", Environment: "dev"},
+			{Repository: "registry.ardikabs.com/myapp", Tag: "v*", Release: "myapp", Environment: "staging"},
+			{Repository: "registry.ardikabs.com/myapp", Tag: "main-*", Release: "myapp", Environment: "dev"},
+		}
+	}
+
+	rc, err := webhook.New(webhook.Config{
+		Secrets: webhook.Secrets{DockerHub: secret, GitHub: secret, Harbor: secret, CloudEvents: secret},
+		Rules:   rules,
+	}, submitter, logr.Discard())
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /v1/webhooks/{source}", rc)
+	return mux
+}
+
+func newRequest(t *testing.T, source, fixture string) *http.Request {
+	body, err := os.ReadFile(filepath.Join("testdata", fixture))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/webhooks/"+source, bytes.NewReader(body))
+
+	switch source {
+	case "dockerhub":
+		req.URL.RawQuery = "token=" + secret
+	case "github":
+		req.Header.Set("X-GitHub-Event", "package")
+		req.Header.Set("X-Hub-Signature-256", notifier.Sign(secret, body))
+	case "harbor":
+		req.Header.Set("Authorization", secret)
+	case "cloudevents":
+		req.Header.Set(notifier.SignatureHeader, notifier.Sign(secret, body))
+	}
+
+	return req
+}
+
+func TestReceiver(t *testing.T) {
+	tests := []struct {
+		source string
+		want   server.Request
+	}{
+		{
+			source: "dockerhub",
+			want:   server.Request{Release: "myapp", Environment: "dev", Image: "ardikabs/myapp:main-b6d7153", Actor: "webhook/dockerhub", Reason: "image pushed to ardikabs/myapp"},
+		},
+		{
+			source: "github",
+			want:   server.Request{Release: "myapp", Environment: "dev", Cluster: "k8s-dev-1", Image: "ghcr.io/ardikabs/myapp:main-b6d7153@" + digest, Actor: "webhook/github", Reason: "image pushed to ghcr.io/ardikabs/myapp"},
+		},
+		{
+			source: "harbor",
+			want:   server.Request{Release: "myapp", Environment: "dev", Image: "harbor.ardikabs.com/library/myapp:main-b6d7153@" + digest, Actor: "webhook/harbor", Reason: "image pushed to harbor.ardikabs.com/library/myapp"},
+		},
+		{
+			source: "cloudevents",
+			want:   server.Request{Release: "myapp", Environment: "dev", Image: "registry.ardikabs.com/myapp:main-b6d7153@" + digest, Actor: "webhook/cloudevents", Reason: "image pushed to registry.ardikabs.com/myapp"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			submitter := new(fakeSubmitter)
+			handler := newReceiver(t, submitter)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, newRequest(t, tt.source, tt.source+".json"))
+			require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+			require.Equal(t, []server.Request{tt.want}, submitter.requests)
+
+			t.Run("repeated event is de-duplicated", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, newRequest(t, tt.source, tt.source+".json"))
+				require.Equal(t, http.StatusAccepted, rec.Code)
+				require.Contains(t, rec.Body.String(), "duplicates")
+				require.Len(t, submitter.requests, 1)
+			})
+		})
+	}
+}
+
+func TestReceiver_Rejections(t *testing.T) {
+	handler := newReceiver(t, new(fakeSubmitter))
+
+	t.Run("invalid signature", func(t *testing.T) {
+		req := newRequest(t, "github", "github.json")
+		req.Header.Set("X-Hub-Signature-256", notifier.Sign("wrong", []byte("{}")))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("unsupported source", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newRequest(t, "quay", "dockerhub.json"))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("submission failure is retriable", func(t *testing.T) {
+		submitter := &fakeSubmitter{err: errors.New("deployment queue is full")}
+		handler := newReceiver(t, submitter)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newRequest(t, "harbor", "harbor.json"))
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+		submitter.err = nil
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, newRequest(t, "harbor", "harbor.json"))
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		require.Len(t, submitter.requests, 1)
+	})
+
+	t.Run("only the failed rule is retried", func(t *testing.T) {
+		submitter := &fakeSubmitter{failures: map[string]error{"staging": errors.New("deployment queue is full")}}
+		handler := newReceiver(t, submitter,
+			webhook.Rule{Repository: "harbor.ardikabs.com/library/myapp", Release: "myapp", Environment: "dev"},
+			webhook.Rule{Repository: "harbor.ardikabs.com/library/myapp", Release: "myapp", Environment: "staging"},
+		)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newRequest(t, "harbor", "harbor.json"))
+		require.Equal(t, http.StatusMultiStatus, rec.Code)
+		require.Contains(t, rec.Body.String(), `"failures":["myapp to staging: deployment queue is full"]`)
+		require.Len(t, submitter.requests, 1)
+		require.Equal(t, "dev", submitter.requests[0].Environment)
+
+		submitter.failures = nil
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, newRequest(t, "harbor", "harbor.json"))
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+		require.Len(t, submitter.requests, 2)
+		require.Equal(t, "staging", submitter.requests[1].Environment)
+	})
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	_, err := webhook.New(webhook.Config{Rules: []webhook.Rule{{Repository: "ardikabs/myapp"}}}, new(fakeSubmitter), logr.Discard())
+	require.ErrorIs(t, err, webhook.ErrWebhookInvalidConfig)
+}
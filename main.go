@@ -1,16 +1,36 @@
 package main
 
 import (
+	"context"
 	"os"
 
 	"github.com/ardikabs/dpl/internal/cli"
 	"github.com/ardikabs/dpl/internal/log"
+	"github.com/ardikabs/dpl/internal/result"
+	"github.com/ardikabs/dpl/internal/tools/signals"
+	"github.com/ardikabs/dpl/internal/tracing"
 )
 
 func main() {
+	ctx, stop := signals.NotifyContext(context.Background())
+	defer stop()
+
+	shutdown, err := tracing.Setup(ctx)
+	if err != nil {
+		log.Error(err, "failed to setup tracing")
+		os.Exit(1)
+	}
+
 	cli := cli.New()
-	if err := cli.Execute(); err != nil {
+	err = cli.ExecuteContext(ctx)
+
+	// the traces are flushed even when the command is interrupted
+	if shutdownErr := shutdown(context.WithoutCancel(ctx)); shutdownErr != nil {
+		log.Error(shutdownErr, "failed to flush traces")
+	}
+
+	if err != nil {
 		log.Error(err, "failed to execute command")
-		os.Exit(1)
+		os.Exit(result.ExitCode(err))
 	}
 }